@@ -1,19 +1,26 @@
 package awssecretsmanager
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
 	"github.com/shogo82148/op-sync/internal/backends"
 	"github.com/shogo82148/op-sync/internal/maputils"
 	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/awsconfig"
 )
 
 func isNotFoundError(err error) bool {
@@ -21,6 +28,12 @@ func isNotFoundError(err error) bool {
 	return errors.As(err, &awsErr)
 }
 
+// managedPrefix is the prefix of the descriptions of the secrets that op-sync creates.
+const managedPrefix = "managed by op-sync"
+
+var _ backends.Backend = (*Backend)(nil)
+var _ backends.Pruner = (*Backend)(nil)
+
 type Backend struct {
 	opts *Options
 }
@@ -31,42 +44,436 @@ type Options struct {
 	services.SecretsManagerSecretCreator
 	services.SecretsManagerSecretGetter
 	services.SecretsManagerSecretUpdater
+	services.SecretsManagerSecretDescriber
+	services.SecretsManagerResourcePolicyGetter
+	services.SecretsManagerResourcePolicyPutter
+	services.SecretsManagerResourceTagger
+	services.SecretsManagerSecretReplicator
+	services.SecretsManagerSecretValuePutter
+	services.SecretsManagerVersionStageUpdater
+	services.SecretsManagerSecretRotator
+	services.SecretsManagerRotationCanceler
+	services.SecretsManagerSecretsLister
+	services.SecretsManagerSecretDeleter
+
+	// Rotate enables staging the new values as AWSPENDING instead of overwriting AWSCURRENT.
+	// The staged values are promoted to AWSCURRENT by the next plan.
+	Rotate bool
+
+	// EndpointURL is the default endpoint of the AWS services.
+	// It can be overridden by the endpoint_url parameter of each entry.
+	EndpointURL string
+
+	// Regions are the allowed regions that region: "*" of the entries expands to.
+	Regions []string
 }
 
 func New(opts *Options) *Backend {
 	return &Backend{opts: opts}
 }
 
+// attributes are the optional attributes of the secret.
+// Only the declared attributes are compared with the secret.
+type attributes struct {
+	KeyID string            `json:"kms_key_id,omitempty"`
+	Tags  map[string]string `json:"tags,omitempty"`
+
+	// ResourcePolicy is the resource policy in the canonical JSON form.
+	ResourcePolicy string `json:"resource_policy,omitempty"`
+
+	// ReplicaRegions are the sorted regions to replicate the secret to.
+	ReplicaRegions []string `json:"replica_regions,omitempty"`
+
+	// Rotation is nil if the rotation is not declared.
+	Rotation *rotation `json:"rotation,omitempty"`
+}
+
+// rotation is the configuration of the rotation.
+type rotation struct {
+	Enabled            bool   `json:"enabled"`
+	LambdaARN          string `json:"lambda_arn,omitempty"`
+	AfterDays          int64  `json:"automatically_after_days,omitempty"`
+	ScheduleExpression string `json:"schedule_expression,omitempty"`
+	Duration           string `json:"duration,omitempty"`
+}
+
+// parseRotation parses the rotation of the entry.
+func parseRotation(c *maputils.Context, params map[string]any) (*rotation, error) {
+	enabled, hasEnabled := maputils.Get[bool](c, params, "rotation_enabled")
+	lambdaARN, hasLambdaARN := maputils.Get[string](c, params, "rotation_lambda_arn")
+	rules, hasRules := maputils.Get[map[string]any](c, params, "rotation_rules")
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	if !hasEnabled && !hasLambdaARN && !hasRules {
+		return nil, nil
+	}
+	if hasEnabled && !enabled {
+		if hasLambdaARN || hasRules {
+			return nil, errors.New("rotation_lambda_arn and rotation_rules are declared, but rotation_enabled is false")
+		}
+		return &rotation{Enabled: false}, nil
+	}
+
+	r := &rotation{
+		Enabled:   true,
+		LambdaARN: lambdaARN,
+	}
+	if days, ok := rules["automatically_after_days"]; ok {
+		n, ok := toInt64(days)
+		if !ok || n <= 0 {
+			return nil, fmt.Errorf("invalid automatically_after_days %v", days)
+		}
+		r.AfterDays = n
+	}
+	r.ScheduleExpression, _ = maputils.Get[string](c, rules, "schedule_expression")
+	r.Duration, _ = maputils.Get[string](c, rules, "duration")
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	if r.AfterDays == 0 && r.ScheduleExpression == "" {
+		return nil, errors.New("rotation_rules requires automatically_after_days or schedule_expression")
+	}
+	if r.AfterDays != 0 && r.ScheduleExpression != "" {
+		return nil, errors.New("automatically_after_days and schedule_expression are mutually exclusive")
+	}
+	return r, nil
+}
+
+// toInt64 converts the number in the configuration to int64.
+func toInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case float64:
+		return int64(v), v == math.Trunc(v)
+	}
+	return 0, false
+}
+
+// matches reports whether the rotation of the secret satisfies the declared configuration.
+func (r *rotation) matches(secret *secretsmanager.DescribeSecretOutput) bool {
+	if r.Enabled != aws.ToBool(secret.RotationEnabled) {
+		return false
+	}
+	if !r.Enabled {
+		return true
+	}
+	if r.LambdaARN != "" && aws.ToString(secret.RotationLambdaARN) != r.LambdaARN {
+		return false
+	}
+	rules := secret.RotationRules
+	if rules == nil {
+		return false
+	}
+	if r.AfterDays != 0 && aws.ToInt64(rules.AutomaticallyAfterDays) != r.AfterDays {
+		return false
+	}
+	if r.ScheduleExpression != "" && aws.ToString(rules.ScheduleExpression) != r.ScheduleExpression {
+		return false
+	}
+	if r.Duration != "" && aws.ToString(rules.Duration) != r.Duration {
+		return false
+	}
+	return true
+}
+
+// rotate configures the rotation of the secret.
+func (r *rotation) rotate(ctx context.Context, b *Backend, region, secretID string) error {
+	if !r.Enabled {
+		_, err := b.opts.SecretsManagerCancelRotateSecret(ctx, region, &secretsmanager.CancelRotateSecretInput{
+			SecretId: aws.String(secretID),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to cancel rotation: %w", err)
+		}
+		return nil
+	}
+
+	in := &secretsmanager.RotateSecretInput{
+		SecretId:      aws.String(secretID),
+		RotationRules: &types.RotationRulesType{},
+		// the value is synced from 1Password, so don't rotate it now.
+		RotateImmediately: aws.Bool(false),
+	}
+	if r.LambdaARN != "" {
+		in.RotationLambdaARN = aws.String(r.LambdaARN)
+	}
+	if r.AfterDays != 0 {
+		in.RotationRules.AutomaticallyAfterDays = aws.Int64(r.AfterDays)
+	}
+	if r.ScheduleExpression != "" {
+		in.RotationRules.ScheduleExpression = aws.String(r.ScheduleExpression)
+	}
+	if r.Duration != "" {
+		in.RotationRules.Duration = aws.String(r.Duration)
+	}
+	if _, err := b.opts.SecretsManagerRotateSecret(ctx, region, in); err != nil {
+		return fmt.Errorf("failed to configure rotation: %w", err)
+	}
+	return nil
+}
+
+// defaultKeyID is the AWS managed key of Secrets Manager.
+// DescribeSecret omits the key of the secrets encrypted with it.
+const defaultKeyID = "alias/aws/secretsmanager"
+
+// validKeyID reports whether id is the ARN of a KMS key or [defaultKeyID].
+// The aliases and the bare key IDs are rejected because DescribeSecret may return them in another form,
+// and they would drift on every run.
+func validKeyID(id string) bool {
+	if id == defaultKeyID {
+		return true
+	}
+	a, err := arn.Parse(id)
+	return err == nil && a.Service == "kms" && strings.HasPrefix(a.Resource, "key/")
+}
+
+// parseAttributes parses the optional attributes of the entry.
+func parseAttributes(c *maputils.Context, params map[string]any) (attributes, error) {
+	keyID, _ := maputils.Get[string](c, params, "kms_key_id")
+	tags, _ := maputils.Get[map[string]any](c, params, "tags")
+	policy, hasPolicy := params["resource_policy"]
+	regions, _ := maputils.Get[[]any](c, params, "replica_regions")
+	if err := c.Err(); err != nil {
+		return attributes{}, err
+	}
+
+	if keyID != "" && !validKeyID(keyID) {
+		return attributes{}, fmt.Errorf("kms_key_id must be the ARN of the key or %s, but got %q", defaultKeyID, keyID)
+	}
+	attrs := attributes{
+		KeyID: keyID,
+	}
+	if len(tags) > 0 {
+		attrs.Tags = make(map[string]string, len(tags))
+		for k, v := range tags {
+			s, ok := v.(string)
+			if !ok {
+				return attributes{}, fmt.Errorf("invalid value %v of tag %q, want string", v, k)
+			}
+			attrs.Tags[k] = s
+		}
+	}
+	if hasPolicy {
+		// the policy is a JSON string or a YAML mapping.
+		var data []byte
+		switch policy := policy.(type) {
+		case string:
+			data = []byte(policy)
+		case map[string]any:
+			var err error
+			data, err = json.Marshal(policy)
+			if err != nil {
+				return attributes{}, fmt.Errorf("invalid resource_policy: %w", err)
+			}
+		default:
+			return attributes{}, fmt.Errorf("invalid resource_policy %v", policy)
+		}
+		canonical, err := canonicalJSON(string(data))
+		if err != nil {
+			return attributes{}, fmt.Errorf("invalid resource_policy: %w", err)
+		}
+		attrs.ResourcePolicy = canonical
+	}
+	for _, region := range regions {
+		s, ok := region.(string)
+		if !ok {
+			return attributes{}, fmt.Errorf("invalid value %v in replica_regions", region)
+		}
+		attrs.ReplicaRegions = append(attrs.ReplicaRegions, s)
+	}
+	slices.Sort(attrs.ReplicaRegions)
+	attrs.ReplicaRegions = slices.Compact(attrs.ReplicaRegions)
+
+	r, err := parseRotation(c, params)
+	if err != nil {
+		return attributes{}, err
+	}
+	attrs.Rotation = r
+	return attrs, nil
+}
+
+// canonicalJSON normalizes the JSON document to compare.
+func canonicalJSON(doc string) (string, error) {
+	var v any
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// diff compares the declared attributes with the secret.
+// It returns the names of the attributes that differ, and the replica regions to add.
+// The tags and the replica regions that are not declared are kept.
+func (b *Backend) diff(ctx context.Context, region, arn string, attrs attributes) (changes, addRegions []string, err error) {
+	if attrs.KeyID != "" || len(attrs.Tags) > 0 || len(attrs.ReplicaRegions) > 0 || attrs.Rotation != nil {
+		secret, err := b.opts.SecretsManagerDescribeSecret(ctx, region, &secretsmanager.DescribeSecretInput{
+			SecretId: aws.String(arn),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to describe secret: %w", err)
+		}
+		if attrs.KeyID != "" && cmp.Or(aws.ToString(secret.KmsKeyId), defaultKeyID) != attrs.KeyID {
+			changes = append(changes, "kms_key_id")
+		}
+		tags := make(map[string]string, len(secret.Tags))
+		for _, tag := range secret.Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		for k, v := range attrs.Tags {
+			if got, ok := tags[k]; !ok || got != v {
+				changes = append(changes, "tags")
+				break
+			}
+		}
+		for _, r := range attrs.ReplicaRegions {
+			if !slices.ContainsFunc(secret.ReplicationStatus, func(s types.ReplicationStatusType) bool {
+				return aws.ToString(s.Region) == r
+			}) {
+				addRegions = append(addRegions, r)
+			}
+		}
+		if len(addRegions) > 0 {
+			changes = append(changes, "replica_regions")
+		}
+		if attrs.Rotation != nil && !attrs.Rotation.matches(secret) {
+			changes = append(changes, "rotation")
+		}
+	}
+
+	if attrs.ResourcePolicy != "" {
+		out, err := b.opts.SecretsManagerGetResourcePolicy(ctx, region, &secretsmanager.GetResourcePolicyInput{
+			SecretId: aws.String(arn),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get resource policy: %w", err)
+		}
+		var current string
+		if out.ResourcePolicy != nil {
+			current, err = canonicalJSON(aws.ToString(out.ResourcePolicy))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse resource policy: %w", err)
+			}
+		}
+		if current != attrs.ResourcePolicy {
+			changes = append(changes, "resource_policy")
+		}
+	}
+	return changes, addRegions, nil
+}
+
+// Plan plans the secret of the entry in each region.
 func (b *Backend) Plan(ctx context.Context, params map[string]any) ([]backends.Plan, error) {
+	c := new(maputils.Context)
+	regions, err := awsconfig.ParseRegions(c, params, b.opts.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("awssecretsmanager: validation failed: %w", err)
+	}
+	replicas, _ := maputils.Get[[]any](c, params, "replica_regions")
+	for _, region := range replicas {
+		if s, ok := region.(string); ok && slices.Contains(regions, s) {
+			return nil, fmt.Errorf("awssecretsmanager: %s is both a region and a replica region", s)
+		}
+	}
+
+	var plans []backends.Plan
+	for _, region := range regions {
+		p, err := b.planRegion(ctx, region, params)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p...)
+	}
+	return plans, nil
+}
+
+// planRegion plans the secret of the entry in the region.
+func (b *Backend) planRegion(ctx context.Context, region string, params map[string]any) ([]backends.Plan, error) {
 	c := new(maputils.Context)
 	account := maputils.Must[string](c, params, "account")
-	region := maputils.Must[string](c, params, "region")
 	name := maputils.Must[string](c, params, "name")
-	template := maputils.Must[map[string]any](c, params, "template")
+	template, hasTemplate := maputils.Get[map[string]any](c, params, "template")
+	source, hasSource := maputils.Get[string](c, params, "source")
+	binarySource, hasBinarySource := maputils.Get[string](c, params, "binary_source")
 	description, hasDescription := maputils.Get[string](c, params, "description")
-	if err := c.Err(); err != nil {
-		return nil, fmt.Errorf("awsssm: validation failed: %w", err)
+	awsConfig := awsconfig.ParseConfig(c, params, b.opts.EndpointURL)
+	attrs, err := parseAttributes(c, params)
+	if err != nil {
+		return nil, fmt.Errorf("awssecretsmanager: validation failed: %w", err)
+	}
+	switch {
+	case hasTemplate && !hasSource && !hasBinarySource:
+	case !hasTemplate && hasSource && !hasBinarySource:
+	case !hasTemplate && !hasSource && hasBinarySource:
+	default:
+		return nil, errors.New("awssecretsmanager: one of template, source, or binary_source is required")
+	}
+	if err := awsconfig.Validate(awsConfig); err != nil {
+		return nil, fmt.Errorf("awssecretsmanager: %w", err)
 	}
+	ctx = services.WithAWSConfig(ctx, awsConfig)
 	if !hasDescription {
-		data, err := json.MarshalIndent(template, "", "  ")
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal the template: %w", err)
+		switch {
+		case hasTemplate:
+			data, err := json.MarshalIndent(template, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal the template: %w", err)
+			}
+			description = fmt.Sprintf("%s:\n%s", managedPrefix, string(data))
+		case hasSource:
+			description = fmt.Sprintf("%s: %s", managedPrefix, source)
+		case hasBinarySource:
+			description = fmt.Sprintf("%s: %s", managedPrefix, binarySource)
 		}
-		description = fmt.Sprintf("managed by op-sync:\n%s", string(data))
 	}
 
 	id, err := b.opts.STSGetCallerIdentity(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get caller identity: %w", err)
 	}
-	if aws.ToString(id.Account) != account {
-		return []backends.Plan{}, nil
+	if got := aws.ToString(id.Account); got != account {
+		reason := fmt.Sprintf("credentials are for account %s", got)
+		return []backends.Plan{backends.NewSkipPlan(secretARN(region, account, name), reason)}, nil
 	}
 
-	// inject the template
-	injected, err := b.inject(ctx, template)
-	if err != nil {
-		return nil, err
+	// read the value of the secret
+	var secret string
+	var binary []byte
+	var injected any
+	switch {
+	case hasTemplate:
+		injected, err = b.inject(ctx, template)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(injected)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal the secret value: %w", err)
+		}
+		secret = string(data)
+	case hasSource:
+		data, err := b.opts.ReadOnePassword(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret: %w", err)
+		}
+		secret = string(data)
+	case hasBinarySource:
+		binary, err = b.opts.ReadOnePassword(ctx, binarySource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret: %w", err)
+		}
+		if len(binary) == 0 {
+			return nil, fmt.Errorf("awssecretsmanager: binary_source %s is empty", binarySource)
+		}
 	}
 
 	// check the secret exists
@@ -75,18 +482,17 @@ func (b *Backend) Plan(ctx context.Context, params map[string]any) ([]backends.P
 	})
 	if isNotFoundError(err) {
 		// the secret doesn't exist. create it.
-		data, err := json.Marshal(injected)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal the secret value: %w", err)
-		}
 		return []backends.Plan{
 			&PlanCreate{
 				backend:     b,
+				awsConfig:   awsConfig,
 				account:     account,
 				region:      region,
 				name:        name,
 				description: description,
-				secret:      string(data),
+				attrs:       attrs,
+				secret:      secret,
+				binary:      binary,
 			},
 		}, nil
 	}
@@ -95,29 +501,259 @@ func (b *Backend) Plan(ctx context.Context, params map[string]any) ([]backends.P
 	}
 
 	// check the secret value is up-to-date
-	var current any
-	err = json.Unmarshal([]byte(aws.ToString(value.SecretString)), &current)
+	var changes []string
+	if !isUpToDate(value, hasTemplate, injected, secret, binary) {
+		changes = append(changes, "value")
+	}
+	attrChanges, addRegions, err := b.diff(ctx, region, aws.ToString(value.ARN), attrs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal secret value: %w", err)
+		return nil, err
 	}
-	if reflect.DeepEqual(injected, current) {
-		return []backends.Plan{}, nil
+	changes = append(changes, attrChanges...)
+	if len(changes) == 0 {
+		return []backends.Plan{backends.NewNoopPlan(aws.ToString(value.ARN))}, nil
 	}
 
 	// update the secret
-	data, err := json.Marshal(injected)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal the secret value: %w", err)
+	plan := &PlanUpdate{
+		backend:     b,
+		awsConfig:   awsConfig,
+		region:      region,
+		arn:         aws.ToString(value.ARN),
+		secret:      secret,
+		binary:      binary,
+		description: description,
+		attrs:       attrs,
+		versionID:   aws.ToString(value.VersionId),
+		changes:     changes,
+		addRegions:  addRegions,
+	}
+	if b.opts.Rotate && plan.changed("value") {
+		// stage the new value, or promote it if it is already staged.
+		pending, err := b.opts.SecretsManagerGetSecretValue(ctx, region, &secretsmanager.GetSecretValueInput{
+			SecretId:     value.ARN,
+			VersionStage: aws.String(stagePending),
+		})
+		switch {
+		case isNotFoundError(err):
+			plan.staging = stagingPending
+		case err != nil:
+			return nil, fmt.Errorf("failed to get pending secret value: %w", err)
+		case aws.ToString(pending.VersionId) != plan.versionID && isUpToDate(pending, hasTemplate, injected, secret, binary):
+			plan.staging = stagingPromote
+			plan.pendingVersionID = aws.ToString(pending.VersionId)
+		default:
+			plan.staging = stagingPending
+		}
+	}
+	return []backends.Plan{plan}, nil
+}
+
+// the staging labels of the versions.
+const (
+	stageCurrent = "AWSCURRENT"
+	stagePending = "AWSPENDING"
+)
+
+// the ways to update the value in the rotate mode.
+const (
+	// stagingPending puts the new value as a new version labeled AWSPENDING.
+	stagingPending = "pending"
+
+	// stagingPromote moves AWSCURRENT to the version labeled AWSPENDING.
+	stagingPromote = "promote"
+)
+
+// isUpToDate reports whether the current value of the secret equals to the new one.
+// The JSON templates are compared as JSON values, the strings and the binaries are compared as they are.
+// A secret is never up-to-date if the representation differs, e.g. a binary secret with the new string.
+func isUpToDate(value *secretsmanager.GetSecretValueOutput, isJSON bool, injected any, secret string, binary []byte) bool {
+	if binary != nil {
+		return value.SecretString == nil && bytes.Equal(value.SecretBinary, binary)
+	}
+	if value.SecretString == nil {
+		return false
+	}
+	if !isJSON {
+		return aws.ToString(value.SecretString) == secret
+	}
+
+	// the current value may not be JSON.
+	var current any
+	if err := json.Unmarshal([]byte(aws.ToString(value.SecretString)), &current); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(injected, current)
+}
+
+// Prune plans to delete the secrets that op-sync created but none of cfgs refers to.
+// The secrets whose description starts with "managed by op-sync" are considered as created by op-sync.
+// Only the accounts and the regions that appear in cfgs are searched.
+func (b *Backend) Prune(ctx context.Context, cfgs []map[string]any) ([]backends.Plan, error) {
+	// the accounts of the credentials.
+	accounts := awsconfig.NewAccounts(b.opts)
+
+	// collect the secrets in the configuration for each account and region.
+	names := map[awsconfig.Location]map[string]struct{}{}
+	// the credentials to access each account.
+	creds := map[awsconfig.Location]services.AWSConfig{}
+	for _, params := range cfgs {
+		c := new(maputils.Context)
+		secretAccount := maputils.Must[string](c, params, "account")
+		name := maputils.Must[string](c, params, "name")
+		awsConfig := awsconfig.ParseConfig(c, params, b.opts.EndpointURL)
+		regions, err := awsconfig.ParseRegions(c, params, b.opts.Regions)
+		if err != nil {
+			return nil, fmt.Errorf("awssecretsmanager: validation failed: %w", err)
+		}
+
+		account, err := accounts.Get(ctx, awsConfig)
+		if err != nil {
+			return nil, err
+		}
+		if secretAccount != account {
+			continue
+		}
+
+		for _, region := range regions {
+			loc := awsconfig.Location{Account: account, Region: region}
+			if _, ok := names[loc]; !ok {
+				names[loc] = map[string]struct{}{}
+				creds[loc] = awsConfig
+			}
+			names[loc][name] = struct{}{}
+		}
 	}
-	return []backends.Plan{
-		&PlanUpdate{
+
+	plans := []backends.Plan{}
+	for _, loc := range slices.SortedFunc(maps.Keys(names), awsconfig.CompareLocation) {
+		awsConfig, region := creds[loc], loc.Region
+		ctx := services.WithAWSConfig(ctx, awsConfig)
+		in := &secretsmanager.ListSecretsInput{
+			Filters: []types.Filter{
+				{
+					Key:    types.FilterNameStringTypeDescription,
+					Values: []string{managedPrefix},
+				},
+			},
+		}
+		for {
+			out, err := b.opts.SecretsManagerListSecrets(ctx, region, in)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list secrets: %w", err)
+			}
+			for _, secret := range out.SecretList {
+				// the filter is case-insensitive, so check it again.
+				if !strings.HasPrefix(aws.ToString(secret.Description), managedPrefix) {
+					continue
+				}
+				// the replicas have the description of their primary secrets,
+				// and they are deleted together with the primary secrets.
+				if primary := aws.ToString(secret.PrimaryRegion); primary != "" && primary != region {
+					continue
+				}
+				name := aws.ToString(secret.Name)
+				if _, ok := names[loc][name]; ok {
+					continue
+				}
+				plans = append(plans, &PlanDelete{
+					backend:   b,
+					awsConfig: awsConfig,
+					region:    region,
+					arn:       aws.ToString(secret.ARN),
+					versionID: currentVersion(secret.SecretVersionsToStages),
+				})
+			}
+			if out.NextToken == nil {
+				break
+			}
+			in.NextToken = out.NextToken
+		}
+	}
+	return plans, nil
+}
+
+// currentVersion returns the version ID that has the AWSCURRENT staging label.
+func currentVersion(versions map[string][]string) string {
+	for id, stages := range versions {
+		if slices.Contains(stages, "AWSCURRENT") {
+			return id
+		}
+	}
+	return ""
+}
+
+const (
+	planKindCreate = "create"
+	planKindUpdate = "update"
+	planKindDelete = "delete"
+)
+
+// planJSON is the serialized form of the plans.
+type planJSON struct {
+	Kind        string             `json:"kind"`
+	AWS         services.AWSConfig `json:"aws,omitzero"`
+	Account     string             `json:"account,omitempty"`
+	Region      string             `json:"region"`
+	Name        string             `json:"name,omitempty"`
+	ARN         string             `json:"arn,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Secret      string             `json:"secret,omitempty"`
+	Binary      []byte             `json:"binary,omitempty"`
+	Attributes  attributes         `json:"attributes,omitzero"`
+	VersionID   string             `json:"version_id,omitempty"`
+	Changes     []string           `json:"changes,omitempty"`
+	AddRegions  []string           `json:"add_regions,omitempty"`
+
+	Staging          string `json:"staging,omitempty"`
+	PendingVersionID string `json:"pending_version_id,omitempty"`
+}
+
+func (b *Backend) UnmarshalPlan(data []byte) (backends.Plan, error) {
+	var v planJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("awssecretsmanager: failed to unmarshal the plan: %w", err)
+	}
+	switch v.Kind {
+	case planKindCreate:
+		return &PlanCreate{
+			backend:     b,
+			awsConfig:   v.AWS,
+			account:     v.Account,
+			region:      v.Region,
+			name:        v.Name,
+			description: v.Description,
+			attrs:       v.Attributes,
+			secret:      v.Secret,
+			binary:      v.Binary,
+		}, nil
+	case planKindUpdate:
+		return &PlanUpdate{
 			backend:     b,
-			region:      region,
-			arn:         aws.ToString(value.ARN),
-			secret:      string(data),
-			description: description,
-		},
-	}, nil
+			awsConfig:   v.AWS,
+			region:      v.Region,
+			arn:         v.ARN,
+			secret:      v.Secret,
+			binary:      v.Binary,
+			description: v.Description,
+			attrs:       v.Attributes,
+			versionID:   v.VersionID,
+			changes:     v.Changes,
+			addRegions:  v.AddRegions,
+
+			staging:          v.Staging,
+			pendingVersionID: v.PendingVersionID,
+		}, nil
+	case planKindDelete:
+		return &PlanDelete{
+			backend:   b,
+			awsConfig: v.AWS,
+			region:    v.Region,
+			arn:       v.ARN,
+			versionID: v.VersionID,
+		}, nil
+	}
+	return nil, fmt.Errorf("awssecretsmanager: unknown plan kind %q", v.Kind)
 }
 
 func (b *Backend) inject(ctx context.Context, template any) (any, error) {
@@ -157,49 +793,418 @@ func (b *Backend) inject(ctx context.Context, template any) (any, error) {
 	}
 }
 
+// secretARN returns the ARN of the secret without the random suffix.
+func secretARN(region, account, name string) string {
+	return fmt.Sprintf("arn:aws:secretsmanager:%s:%s:secret:%s", region, account, name)
+}
+
 var _ backends.Plan = (*PlanCreate)(nil)
 
 type PlanCreate struct {
 	backend     *Backend
+	awsConfig   services.AWSConfig
 	account     string
 	region      string
 	name        string
 	description string
-	secret      string
+	attrs       attributes
+
+	// secret is the value of the string secret.
+	secret string
+
+	// binary is the value of the binary secret.
+	// It is nil for the string secrets.
+	binary []byte
 }
 
 func (p *PlanCreate) Preview() string {
-	return fmt.Sprintf("create AWS Secrets Manager secret %s on account %s", p.name, p.account)
+	return fmt.Sprintf("create AWS Secrets Manager secret %s on account %s in %s", p.name, p.account, p.region)
 }
 
-func (p *PlanCreate) Apply(ctx context.Context) error {
-	_, err := p.backend.opts.SecretsManagerCreateSecret(ctx, p.region, &secretsmanager.CreateSecretInput{
-		Name:         aws.String(p.name),
-		Description:  aws.String(p.description),
-		SecretString: aws.String(p.secret),
+func (p *PlanCreate) Action() backends.Action {
+	return backends.ActionCreate
+}
+
+func (p *PlanCreate) Target() string {
+	return secretARN(p.region, p.account, p.name)
+}
+
+func (p *PlanCreate) Reason() string {
+	return "the secret does not exist"
+}
+
+func (p *PlanCreate) Verify(ctx context.Context) error {
+	ctx = services.WithAWSConfig(ctx, p.awsConfig)
+	_, err := p.backend.opts.SecretsManagerGetSecretValue(ctx, p.region, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(p.name),
 	})
-	return err
+	if isNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret value: %w", err)
+	}
+	return fmt.Errorf("secret %s was created: %w", p.name, backends.ErrStalePlan)
+}
+
+func (p *PlanCreate) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Kind:        planKindCreate,
+		AWS:         p.awsConfig,
+		Account:     p.account,
+		Region:      p.region,
+		Name:        p.name,
+		Description: p.description,
+		Secret:      p.secret,
+		Binary:      p.binary,
+		Attributes:  p.attrs,
+	})
+}
+
+func (p *PlanCreate) Apply(ctx context.Context) error {
+	ctx = services.WithAWSConfig(ctx, p.awsConfig)
+	in := &secretsmanager.CreateSecretInput{
+		Name:        aws.String(p.name),
+		Description: aws.String(p.description),
+		Tags:        tags(p.attrs.Tags),
+	}
+	if p.binary != nil {
+		in.SecretBinary = p.binary
+	} else {
+		in.SecretString = aws.String(p.secret)
+	}
+	if p.attrs.KeyID != "" {
+		in.KmsKeyId = aws.String(p.attrs.KeyID)
+	}
+	for _, region := range p.attrs.ReplicaRegions {
+		in.AddReplicaRegions = append(in.AddReplicaRegions, types.ReplicaRegionType{
+			Region: aws.String(region),
+		})
+	}
+	out, err := p.backend.opts.SecretsManagerCreateSecret(ctx, p.region, in)
+	if err != nil {
+		return err
+	}
+
+	if p.attrs.ResourcePolicy != "" {
+		_, err := p.backend.opts.SecretsManagerPutResourcePolicy(ctx, p.region, &secretsmanager.PutResourcePolicyInput{
+			SecretId:       out.ARN,
+			ResourcePolicy: aws.String(p.attrs.ResourcePolicy),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to put resource policy: %w", err)
+		}
+	}
+	if r := p.attrs.Rotation; r != nil && r.Enabled {
+		if err := r.rotate(ctx, p.backend, p.region, aws.ToString(out.ARN)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tags converts the declared tags sorted by the keys.
+func tags(m map[string]string) []types.Tag {
+	if len(m) == 0 {
+		return nil
+	}
+	ret := make([]types.Tag, 0, len(m))
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		ret = append(ret, types.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(m[k]),
+		})
+	}
+	return ret
 }
 
 var _ backends.Plan = (*PlanUpdate)(nil)
 
 type PlanUpdate struct {
 	backend     *Backend
+	awsConfig   services.AWSConfig
 	region      string
 	arn         string
-	secret      string
 	description string
+	attrs       attributes
+
+	// secret is the value of the string secret.
+	secret string
+
+	// binary is the value of the binary secret.
+	// It is nil for the string secrets.
+	binary []byte
+
+	// versionID is the version of the secret when the plan was made.
+	versionID string
+
+	// changes are the names of the differences, e.g. "value" and "tags".
+	// The plans saved by older versions have no changes, and update the value.
+	changes []string
+
+	// addRegions are the regions to replicate the secret to.
+	addRegions []string
+
+	// staging is how to update the value in the rotate mode.
+	// It is empty if the value is overwritten in place.
+	staging string
+
+	// pendingVersionID is the version to promote to AWSCURRENT.
+	pendingVersionID string
 }
 
 func (p *PlanUpdate) Preview() string {
+	switch p.staging {
+	case stagingPending:
+		return fmt.Sprintf("update AWS Secrets Manager secret %s, staging the new value as %s", p.arn, stagePending)
+	case stagingPromote:
+		return fmt.Sprintf("update AWS Secrets Manager secret %s, promoting %s to %s", p.arn, stagePending, stageCurrent)
+	}
 	return fmt.Sprintf("update AWS Secrets Manager secret %s", p.arn)
 }
 
+func (p *PlanUpdate) Action() backends.Action {
+	return backends.ActionUpdate
+}
+
+func (p *PlanUpdate) Target() string {
+	return p.arn
+}
+
+func (p *PlanUpdate) Reason() string {
+	reason := "the value of the secret differs"
+	if len(p.changes) != 0 && !slices.Equal(p.changes, []string{"value"}) {
+		reason = fmt.Sprintf("the %s of the secret differ", strings.Join(p.changes, ", "))
+	}
+	switch p.staging {
+	case stagingPending:
+		reason += ", and the new value will be staged"
+	case stagingPromote:
+		reason += ", and the staged value will be promoted"
+	}
+	return reason
+}
+
+// changed reports whether the attribute differs.
+func (p *PlanUpdate) changed(name string) bool {
+	if len(p.changes) == 0 {
+		return name == "value"
+	}
+	return slices.Contains(p.changes, name)
+}
+
+func (p *PlanUpdate) Verify(ctx context.Context) error {
+	ctx = services.WithAWSConfig(ctx, p.awsConfig)
+	value, err := p.backend.opts.SecretsManagerGetSecretValue(ctx, p.region, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(p.arn),
+	})
+	if isNotFoundError(err) {
+		return fmt.Errorf("secret %s was removed: %w", p.arn, backends.ErrStalePlan)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret value: %w", err)
+	}
+	if aws.ToString(value.VersionId) != p.versionID {
+		return fmt.Errorf("secret %s was updated: %w", p.arn, backends.ErrStalePlan)
+	}
+	if p.staging == stagingPromote {
+		pending, err := p.backend.opts.SecretsManagerGetSecretValue(ctx, p.region, &secretsmanager.GetSecretValueInput{
+			SecretId:     aws.String(p.arn),
+			VersionStage: aws.String(stagePending),
+		})
+		if isNotFoundError(err) {
+			return fmt.Errorf("pending value of secret %s was removed: %w", p.arn, backends.ErrStalePlan)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get pending secret value: %w", err)
+		}
+		if aws.ToString(pending.VersionId) != p.pendingVersionID {
+			return fmt.Errorf("pending value of secret %s was updated: %w", p.arn, backends.ErrStalePlan)
+		}
+	}
+	return nil
+}
+
+func (p *PlanUpdate) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Kind:        planKindUpdate,
+		AWS:         p.awsConfig,
+		Region:      p.region,
+		ARN:         p.arn,
+		Description: p.description,
+		Secret:      p.secret,
+		Binary:      p.binary,
+		Attributes:  p.attrs,
+		VersionID:   p.versionID,
+		Changes:     p.changes,
+		AddRegions:  p.addRegions,
+
+		Staging:          p.staging,
+		PendingVersionID: p.pendingVersionID,
+	})
+}
+
 func (p *PlanUpdate) Apply(ctx context.Context) error {
-	_, err := p.backend.opts.SecretsManagerUpdateSecret(ctx, p.region, &secretsmanager.UpdateSecretInput{
-		SecretId:     aws.String(p.arn),
-		SecretString: aws.String(p.secret),
-		Description:  aws.String(p.description),
+	ctx = services.WithAWSConfig(ctx, p.awsConfig)
+	inPlace := p.changed("value") && p.staging == ""
+	if inPlace || p.changed("kms_key_id") {
+		in := &secretsmanager.UpdateSecretInput{
+			SecretId:    aws.String(p.arn),
+			Description: aws.String(p.description),
+		}
+		if inPlace {
+			// the new version is encrypted by the new key.
+			if p.binary != nil {
+				in.SecretBinary = p.binary
+			} else {
+				in.SecretString = aws.String(p.secret)
+			}
+		}
+		if p.attrs.KeyID != "" {
+			in.KmsKeyId = aws.String(p.attrs.KeyID)
+		}
+		if _, err := p.backend.opts.SecretsManagerUpdateSecret(ctx, p.region, in); err != nil {
+			return err
+		}
+	}
+	if p.changed("value") {
+		switch p.staging {
+		case stagingPending:
+			in := &secretsmanager.PutSecretValueInput{
+				SecretId:      aws.String(p.arn),
+				VersionStages: []string{stagePending},
+			}
+			if p.binary != nil {
+				in.SecretBinary = p.binary
+			} else {
+				in.SecretString = aws.String(p.secret)
+			}
+			if _, err := p.backend.opts.SecretsManagerPutSecretValue(ctx, p.region, in); err != nil {
+				return fmt.Errorf("failed to stage secret value: %w", err)
+			}
+		case stagingPromote:
+			_, err := p.backend.opts.SecretsManagerUpdateSecretVersionStage(ctx, p.region, &secretsmanager.UpdateSecretVersionStageInput{
+				SecretId:            aws.String(p.arn),
+				VersionStage:        aws.String(stageCurrent),
+				MoveToVersionId:     aws.String(p.pendingVersionID),
+				RemoveFromVersionId: aws.String(p.versionID),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to promote secret value: %w", err)
+			}
+
+			// the promoted version is no longer pending.
+			// the rotation functions and the next staging expect that no version has AWSPENDING.
+			_, err = p.backend.opts.SecretsManagerUpdateSecretVersionStage(ctx, p.region, &secretsmanager.UpdateSecretVersionStageInput{
+				SecretId:            aws.String(p.arn),
+				VersionStage:        aws.String(stagePending),
+				RemoveFromVersionId: aws.String(p.pendingVersionID),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to remove %s from secret value: %w", stagePending, err)
+			}
+		}
+	}
+	if p.changed("tags") {
+		_, err := p.backend.opts.SecretsManagerTagResource(ctx, p.region, &secretsmanager.TagResourceInput{
+			SecretId: aws.String(p.arn),
+			Tags:     tags(p.attrs.Tags),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to tag secret: %w", err)
+		}
+	}
+	if p.changed("resource_policy") {
+		_, err := p.backend.opts.SecretsManagerPutResourcePolicy(ctx, p.region, &secretsmanager.PutResourcePolicyInput{
+			SecretId:       aws.String(p.arn),
+			ResourcePolicy: aws.String(p.attrs.ResourcePolicy),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to put resource policy: %w", err)
+		}
+	}
+	if p.changed("rotation") {
+		if err := p.attrs.Rotation.rotate(ctx, p.backend, p.region, p.arn); err != nil {
+			return err
+		}
+	}
+	if p.changed("replica_regions") {
+		in := &secretsmanager.ReplicateSecretToRegionsInput{
+			SecretId: aws.String(p.arn),
+		}
+		for _, region := range p.addRegions {
+			in.AddReplicaRegions = append(in.AddReplicaRegions, types.ReplicaRegionType{
+				Region: aws.String(region),
+			})
+		}
+		if _, err := p.backend.opts.SecretsManagerReplicateSecretToRegions(ctx, p.region, in); err != nil {
+			return fmt.Errorf("failed to replicate secret: %w", err)
+		}
+	}
+	return nil
+}
+
+var _ backends.Plan = (*PlanDelete)(nil)
+
+// PlanDelete is a plan to delete the secret that is no longer in the configuration.
+// The secret is deleted with the default recovery window, so it can be restored for a while.
+type PlanDelete struct {
+	backend   *Backend
+	awsConfig services.AWSConfig
+	region    string
+	arn       string
+
+	// versionID is the version of the secret when the plan was made.
+	versionID string
+}
+
+func (p *PlanDelete) Preview() string {
+	return fmt.Sprintf("delete AWS Secrets Manager secret %s", p.arn)
+}
+
+func (p *PlanDelete) Action() backends.Action {
+	return backends.ActionDelete
+}
+
+func (p *PlanDelete) Target() string {
+	return p.arn
+}
+
+func (p *PlanDelete) Reason() string {
+	return "the secret is no longer in the configuration"
+}
+
+func (p *PlanDelete) Verify(ctx context.Context) error {
+	ctx = services.WithAWSConfig(ctx, p.awsConfig)
+	value, err := p.backend.opts.SecretsManagerGetSecretValue(ctx, p.region, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(p.arn),
+	})
+	if isNotFoundError(err) {
+		return fmt.Errorf("secret %s was removed: %w", p.arn, backends.ErrStalePlan)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret value: %w", err)
+	}
+	if aws.ToString(value.VersionId) != p.versionID {
+		return fmt.Errorf("secret %s was updated: %w", p.arn, backends.ErrStalePlan)
+	}
+	return nil
+}
+
+func (p *PlanDelete) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Kind:      planKindDelete,
+		AWS:       p.awsConfig,
+		Region:    p.region,
+		ARN:       p.arn,
+		VersionID: p.versionID,
+	})
+}
+
+func (p *PlanDelete) Apply(ctx context.Context) error {
+	ctx = services.WithAWSConfig(ctx, p.awsConfig)
+	_, err := p.backend.opts.SecretsManagerDeleteSecret(ctx, p.region, &secretsmanager.DeleteSecretInput{
+		SecretId: aws.String(p.arn),
 	})
 	return err
 }
@@ -2,6 +2,7 @@ package awssecretsmanager
 
 import (
 	"context"
+	"maps"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -10,6 +11,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/shogo82148/op-sync/internal/backends"
+	"github.com/shogo82148/op-sync/internal/services"
 	"github.com/shogo82148/op-sync/internal/services/mock"
 )
 
@@ -173,7 +176,931 @@ func TestPlan_UpToDate(t *testing.T) {
 	}
 
 	// verify the plan
-	if len(plans) != 0 {
-		t.Fatalf("unexpected length: want 0, got %d", len(plans))
+	if len(plans) != 1 || plans[0].Action() != backends.ActionNoop {
+		t.Fatalf("unexpected plans: want one noop plan, got %v", plans)
+	}
+}
+
+func TestPlan_Attributes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got *secretsmanager.CreateSecretInput
+	var gotPolicy *secretsmanager.PutResourcePolicyInput
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("secret"), nil
+		}),
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("123456789012"),
+			}, nil
+		}),
+		SecretsManagerSecretGetter: mock.SecretsManagerSecretGetter(func(ctx context.Context, region string, in *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+			return nil, &types.ResourceNotFoundException{}
+		}),
+		SecretsManagerSecretCreator: mock.SecretsManagerSecretCreator(func(ctx context.Context, region string, in *secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error) {
+			got = in
+			return &secretsmanager.CreateSecretOutput{
+				ARN: aws.String("arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:secret-AbCdEf"),
+			}, nil
+		}),
+		SecretsManagerResourcePolicyPutter: mock.SecretsManagerResourcePolicyPutter(func(ctx context.Context, region string, in *secretsmanager.PutResourcePolicyInput) (*secretsmanager.PutResourcePolicyOutput, error) {
+			gotPolicy = in
+			return &secretsmanager.PutResourcePolicyOutput{}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"account":     "123456789012",
+		"region":      "ap-northeast-1",
+		"name":        "secret",
+		"description": "my secret",
+		"template": map[string]any{
+			"password": "{{ op://vault/item/field }}",
+		},
+		"kms_key_id": "arn:aws:kms:ap-northeast-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+		"tags": map[string]any{
+			"Team": "platform",
+		},
+		"resource_policy": `{"Version": "2012-10-17", "Statement": []}`,
+		"replica_regions": []any{"us-east-1", "eu-west-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+
+	// the plan survives saving into the plan file.
+	data, err := plans[0].MarshalPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := b.UnmarshalPlan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// apply the plan
+	if err := plan.Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the result
+	want := &secretsmanager.CreateSecretInput{
+		Name:         aws.String("secret"),
+		Description:  aws.String("my secret"),
+		SecretString: aws.String(`{"password":"secret"}`),
+		KmsKeyId:     aws.String("arn:aws:kms:ap-northeast-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"),
+		Tags: []types.Tag{
+			{Key: aws.String("Team"), Value: aws.String("platform")},
+		},
+		AddReplicaRegions: []types.ReplicaRegionType{
+			{Region: aws.String("eu-west-1")},
+			{Region: aws.String("us-east-1")},
+		},
+	}
+	opts := cmpopts.IgnoreUnexported(secretsmanager.CreateSecretInput{}, types.Tag{}, types.ReplicaRegionType{})
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("unexpected result (-want +got):\n%s", diff)
+	}
+	wantPolicy := &secretsmanager.PutResourcePolicyInput{
+		SecretId:       aws.String("arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:secret-AbCdEf"),
+		ResourcePolicy: aws.String(`{"Statement":[],"Version":"2012-10-17"}`),
+	}
+	if diff := cmp.Diff(wantPolicy, gotPolicy, cmpopts.IgnoreUnexported(secretsmanager.PutResourcePolicyInput{})); diff != "" {
+		t.Errorf("unexpected policy (-want +got):\n%s", diff)
+	}
+}
+
+func TestPlan_AttributesDrift(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const arn = "arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:secret-AbCdEf"
+	var calls []string
+	var gotTags *secretsmanager.TagResourceInput
+	var gotReplicate *secretsmanager.ReplicateSecretToRegionsInput
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("secret"), nil
+		}),
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("123456789012"),
+			}, nil
+		}),
+		SecretsManagerSecretGetter: mock.SecretsManagerSecretGetter(func(ctx context.Context, region string, in *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+			return &secretsmanager.GetSecretValueOutput{
+				ARN:          aws.String(arn),
+				SecretString: aws.String(`{"password":"secret"}`),
+				VersionId:    aws.String("version"),
+			}, nil
+		}),
+		SecretsManagerSecretDescriber: mock.SecretsManagerSecretDescriber(func(ctx context.Context, region string, in *secretsmanager.DescribeSecretInput) (*secretsmanager.DescribeSecretOutput, error) {
+			return &secretsmanager.DescribeSecretOutput{
+				ARN:      aws.String(arn),
+				KmsKeyId: aws.String("arn:aws:kms:ap-northeast-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"),
+				Tags: []types.Tag{
+					{Key: aws.String("Team"), Value: aws.String("security")},
+				},
+				ReplicationStatus: []types.ReplicationStatusType{
+					{Region: aws.String("us-east-1")},
+				},
+			}, nil
+		}),
+		SecretsManagerResourcePolicyGetter: mock.SecretsManagerResourcePolicyGetter(func(ctx context.Context, region string, in *secretsmanager.GetResourcePolicyInput) (*secretsmanager.GetResourcePolicyOutput, error) {
+			return &secretsmanager.GetResourcePolicyOutput{
+				ResourcePolicy: aws.String(`{"Statement":[],"Version":"2012-10-17"}`),
+			}, nil
+		}),
+		SecretsManagerSecretUpdater: mock.SecretsManagerSecretUpdater(func(ctx context.Context, region string, in *secretsmanager.UpdateSecretInput) (*secretsmanager.UpdateSecretOutput, error) {
+			calls = append(calls, "UpdateSecret")
+			return &secretsmanager.UpdateSecretOutput{}, nil
+		}),
+		SecretsManagerResourceTagger: mock.SecretsManagerResourceTagger(func(ctx context.Context, region string, in *secretsmanager.TagResourceInput) (*secretsmanager.TagResourceOutput, error) {
+			calls = append(calls, "TagResource")
+			gotTags = in
+			return &secretsmanager.TagResourceOutput{}, nil
+		}),
+		SecretsManagerResourcePolicyPutter: mock.SecretsManagerResourcePolicyPutter(func(ctx context.Context, region string, in *secretsmanager.PutResourcePolicyInput) (*secretsmanager.PutResourcePolicyOutput, error) {
+			calls = append(calls, "PutResourcePolicy")
+			return &secretsmanager.PutResourcePolicyOutput{}, nil
+		}),
+		SecretsManagerSecretReplicator: mock.SecretsManagerSecretReplicator(func(ctx context.Context, region string, in *secretsmanager.ReplicateSecretToRegionsInput) (*secretsmanager.ReplicateSecretToRegionsOutput, error) {
+			calls = append(calls, "ReplicateSecretToRegions")
+			gotReplicate = in
+			return &secretsmanager.ReplicateSecretToRegionsOutput{}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"account": "123456789012",
+		"region":  "ap-northeast-1",
+		"name":    "secret",
+		"template": map[string]any{
+			"password": "{{ op://vault/item/field }}",
+		},
+		"kms_key_id": "arn:aws:kms:ap-northeast-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+		"tags": map[string]any{
+			"Team": "platform",
+		},
+		"resource_policy": map[string]any{
+			"Version":   "2012-10-17",
+			"Statement": []any{},
+		},
+		"replica_regions": []any{"us-east-1", "eu-west-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plan
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Action(), backends.ActionUpdate; got != want {
+		t.Errorf("unexpected action: want %q, got %q", want, got)
+	}
+	if got, want := plans[0].Reason(), "the tags, replica_regions of the secret differ"; got != want {
+		t.Errorf("unexpected reason: want %q, got %q", want, got)
+	}
+
+	// apply the plan
+	if err := plans[0].Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"TagResource", "ReplicateSecretToRegions"}, calls); diff != "" {
+		t.Errorf("unexpected calls (-want +got):\n%s", diff)
+	}
+	if got := aws.ToString(gotTags.Tags[0].Value); got != "platform" {
+		t.Errorf("unexpected tag: want %q, got %q", "platform", got)
+	}
+	if len(gotReplicate.AddReplicaRegions) != 1 || aws.ToString(gotReplicate.AddReplicaRegions[0].Region) != "eu-west-1" {
+		t.Errorf("unexpected regions: %v", gotReplicate.AddReplicaRegions)
+	}
+}
+
+func TestPlan_KeyID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const keyARN = "arn:aws:kms:ap-northeast-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	tests := []struct {
+		name    string
+		keyID   string
+		current string
+		drift   bool
+	}{
+		{name: "default key", keyID: "alias/aws/secretsmanager", current: ""},
+		{name: "same key", keyID: keyARN, current: keyARN},
+		{name: "default to custom key", keyID: keyARN, current: "", drift: true},
+		{name: "custom key to default", keyID: "alias/aws/secretsmanager", current: keyARN, drift: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(&Options{
+				OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+					return []byte("secret"), nil
+				}),
+				STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+					return &sts.GetCallerIdentityOutput{
+						Account: aws.String("123456789012"),
+					}, nil
+				}),
+				SecretsManagerSecretGetter: mock.SecretsManagerSecretGetter(func(ctx context.Context, region string, in *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+					return &secretsmanager.GetSecretValueOutput{
+						ARN:          aws.String("arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:secret-abcd"),
+						SecretString: aws.String("secret"),
+					}, nil
+				}),
+				SecretsManagerSecretDescriber: mock.SecretsManagerSecretDescriber(func(ctx context.Context, region string, in *secretsmanager.DescribeSecretInput) (*secretsmanager.DescribeSecretOutput, error) {
+					out := &secretsmanager.DescribeSecretOutput{}
+					if tt.current != "" {
+						out.KmsKeyId = aws.String(tt.current)
+					}
+					return out, nil
+				}),
+			})
+
+			plans, err := b.Plan(ctx, map[string]any{
+				"account":    "123456789012",
+				"region":     "ap-northeast-1",
+				"name":       "secret",
+				"source":     "op://vault/item/password",
+				"kms_key_id": tt.keyID,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plans) != 1 {
+				t.Fatalf("unexpected length: want 1, got %d", len(plans))
+			}
+			if got := plans[0].Action() != backends.ActionNoop; got != tt.drift {
+				t.Errorf("unexpected drift: want %t, got %t", tt.drift, got)
+			}
+		})
+	}
+
+	// the aliases may be reported in another form.
+	b := New(&Options{})
+	for _, keyID := range []string{"alias/my-key", "1234abcd-12ab-34cd-56ef-1234567890ab", "arn:aws:kms:ap-northeast-1:123456789012:alias/my-key"} {
+		_, err := b.Plan(ctx, map[string]any{
+			"account":    "123456789012",
+			"region":     "ap-northeast-1",
+			"name":       "secret",
+			"source":     "op://vault/item/password",
+			"kms_key_id": keyID,
+		})
+		if err == nil {
+			t.Errorf("%q: want error, got nil", keyID)
+		}
+	}
+}
+
+func TestPlan_Source(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const arn = "arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:secret-AbCdEf"
+
+	tests := []struct {
+		name    string
+		params  map[string]any
+		current *secretsmanager.GetSecretValueOutput
+		want    *secretsmanager.UpdateSecretInput
+	}{
+		{
+			name:    "string up-to-date",
+			params:  map[string]any{"source": "op://vault/item/password"},
+			current: &secretsmanager.GetSecretValueOutput{SecretString: aws.String("not json")},
+		},
+		{
+			name:    "string differs",
+			params:  map[string]any{"source": "op://vault/item/password"},
+			current: &secretsmanager.GetSecretValueOutput{SecretString: aws.String("old")},
+			want: &secretsmanager.UpdateSecretInput{
+				SecretString: aws.String("not json"),
+				Description:  aws.String("managed by op-sync: op://vault/item/password"),
+			},
+		},
+		{
+			name:    "string was binary",
+			params:  map[string]any{"source": "op://vault/item/password"},
+			current: &secretsmanager.GetSecretValueOutput{SecretBinary: []byte("not json")},
+			want: &secretsmanager.UpdateSecretInput{
+				SecretString: aws.String("not json"),
+				Description:  aws.String("managed by op-sync: op://vault/item/password"),
+			},
+		},
+		{
+			name:    "binary up-to-date",
+			params:  map[string]any{"binary_source": "op://vault/item/keystore.jks"},
+			current: &secretsmanager.GetSecretValueOutput{SecretBinary: []byte{0x00, 0xff}},
+		},
+		{
+			name:    "binary differs",
+			params:  map[string]any{"binary_source": "op://vault/item/keystore.jks"},
+			current: &secretsmanager.GetSecretValueOutput{SecretBinary: []byte{0x00}},
+			want: &secretsmanager.UpdateSecretInput{
+				SecretBinary: []byte{0x00, 0xff},
+				Description:  aws.String("managed by op-sync: op://vault/item/keystore.jks"),
+			},
+		},
+		{
+			name:    "template was not json",
+			params:  map[string]any{"template": map[string]any{"password": "{{ op://vault/item/password }}"}},
+			current: &secretsmanager.GetSecretValueOutput{SecretString: aws.String("not json")},
+			want: &secretsmanager.UpdateSecretInput{
+				SecretString: aws.String(`{"password":"not json"}`),
+				Description:  aws.String("managed by op-sync:\n{\n  \"password\": \"{{ op://vault/item/password }}\"\n}"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *secretsmanager.UpdateSecretInput
+			b := New(&Options{
+				OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+					if uri == "op://vault/item/keystore.jks" {
+						return []byte{0x00, 0xff}, nil
+					}
+					return []byte("not json"), nil
+				}),
+				STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+					return &sts.GetCallerIdentityOutput{
+						Account: aws.String("123456789012"),
+					}, nil
+				}),
+				SecretsManagerSecretGetter: mock.SecretsManagerSecretGetter(func(ctx context.Context, region string, in *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+					current := *tt.current
+					current.ARN = aws.String(arn)
+					return &current, nil
+				}),
+				SecretsManagerSecretUpdater: mock.SecretsManagerSecretUpdater(func(ctx context.Context, region string, in *secretsmanager.UpdateSecretInput) (*secretsmanager.UpdateSecretOutput, error) {
+					got = in
+					return &secretsmanager.UpdateSecretOutput{}, nil
+				}),
+			})
+
+			params := map[string]any{
+				"account": "123456789012",
+				"region":  "ap-northeast-1",
+				"name":    "secret",
+			}
+			maps.Copy(params, tt.params)
+			plans, err := b.Plan(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if len(plans) != 1 || plans[0].Action() != backends.ActionNoop {
+					t.Fatalf("unexpected plans: want one noop plan, got %v", plans)
+				}
+				return
+			}
+			if len(plans) != 1 {
+				t.Fatalf("unexpected length: want 1, got %d", len(plans))
+			}
+			if err := plans[0].Apply(ctx); err != nil {
+				t.Fatal(err)
+			}
+			tt.want.SecretId = aws.String(arn)
+			opts := cmpopts.IgnoreUnexported(secretsmanager.UpdateSecretInput{})
+			if diff := cmp.Diff(tt.want, got, opts); diff != "" {
+				t.Errorf("unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlan_InvalidSource(t *testing.T) {
+	b := New(&Options{})
+	tests := []map[string]any{
+		{},
+		{"source": "op://vault/item/password", "binary_source": "op://vault/item/keystore.jks"},
+		{"source": "op://vault/item/password", "template": map[string]any{}},
+	}
+	for _, tt := range tests {
+		params := map[string]any{
+			"account": "123456789012",
+			"region":  "ap-northeast-1",
+			"name":    "secret",
+		}
+		maps.Copy(params, tt)
+		if _, err := b.Plan(context.Background(), params); err == nil {
+			t.Errorf("%v: want error, got nil", tt)
+		}
+	}
+}
+
+func TestPlan_Rotation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const arn = "arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:secret-AbCdEf"
+	tests := []struct {
+		name    string
+		params  map[string]any
+		current *secretsmanager.DescribeSecretOutput
+		want    any
+	}{
+		{
+			name: "enable",
+			params: map[string]any{
+				"rotation_lambda_arn": "arn:aws:lambda:ap-northeast-1:123456789012:function:rotate",
+				"rotation_rules": map[string]any{
+					"automatically_after_days": uint64(30),
+				},
+			},
+			current: &secretsmanager.DescribeSecretOutput{},
+			want: &secretsmanager.RotateSecretInput{
+				SecretId:          aws.String(arn),
+				RotationLambdaARN: aws.String("arn:aws:lambda:ap-northeast-1:123456789012:function:rotate"),
+				RotationRules: &types.RotationRulesType{
+					AutomaticallyAfterDays: aws.Int64(30),
+				},
+				RotateImmediately: aws.Bool(false),
+			},
+		},
+		{
+			name: "change schedule",
+			params: map[string]any{
+				"rotation_rules": map[string]any{
+					"schedule_expression": "rate(10 days)",
+				},
+			},
+			current: &secretsmanager.DescribeSecretOutput{
+				RotationEnabled:   aws.Bool(true),
+				RotationLambdaARN: aws.String("arn:aws:lambda:ap-northeast-1:123456789012:function:rotate"),
+				RotationRules: &types.RotationRulesType{
+					AutomaticallyAfterDays: aws.Int64(30),
+				},
+			},
+			want: &secretsmanager.RotateSecretInput{
+				SecretId: aws.String(arn),
+				RotationRules: &types.RotationRulesType{
+					ScheduleExpression: aws.String("rate(10 days)"),
+				},
+				RotateImmediately: aws.Bool(false),
+			},
+		},
+		{
+			name: "up-to-date",
+			params: map[string]any{
+				"rotation_rules": map[string]any{
+					"schedule_expression": "rate(10 days)",
+				},
+			},
+			current: &secretsmanager.DescribeSecretOutput{
+				RotationEnabled: aws.Bool(true),
+				RotationRules: &types.RotationRulesType{
+					AutomaticallyAfterDays: aws.Int64(10),
+					ScheduleExpression:     aws.String("rate(10 days)"),
+				},
+			},
+		},
+		{
+			name: "disable",
+			params: map[string]any{
+				"rotation_enabled": false,
+			},
+			current: &secretsmanager.DescribeSecretOutput{
+				RotationEnabled: aws.Bool(true),
+			},
+			want: &secretsmanager.CancelRotateSecretInput{
+				SecretId: aws.String(arn),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got any
+			b := New(&Options{
+				OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+					return []byte("secret"), nil
+				}),
+				STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+					return &sts.GetCallerIdentityOutput{
+						Account: aws.String("123456789012"),
+					}, nil
+				}),
+				SecretsManagerSecretGetter: mock.SecretsManagerSecretGetter(func(ctx context.Context, region string, in *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+					return &secretsmanager.GetSecretValueOutput{
+						ARN:          aws.String(arn),
+						SecretString: aws.String("secret"),
+					}, nil
+				}),
+				SecretsManagerSecretDescriber: mock.SecretsManagerSecretDescriber(func(ctx context.Context, region string, in *secretsmanager.DescribeSecretInput) (*secretsmanager.DescribeSecretOutput, error) {
+					return tt.current, nil
+				}),
+				SecretsManagerSecretRotator: mock.SecretsManagerSecretRotator(func(ctx context.Context, region string, in *secretsmanager.RotateSecretInput) (*secretsmanager.RotateSecretOutput, error) {
+					got = in
+					return &secretsmanager.RotateSecretOutput{}, nil
+				}),
+				SecretsManagerRotationCanceler: mock.SecretsManagerRotationCanceler(func(ctx context.Context, region string, in *secretsmanager.CancelRotateSecretInput) (*secretsmanager.CancelRotateSecretOutput, error) {
+					got = in
+					return &secretsmanager.CancelRotateSecretOutput{}, nil
+				}),
+			})
+
+			params := map[string]any{
+				"account": "123456789012",
+				"region":  "ap-northeast-1",
+				"name":    "secret",
+				"source":  "op://vault/item/password",
+			}
+			maps.Copy(params, tt.params)
+			plans, err := b.Plan(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if len(plans) != 1 || plans[0].Action() != backends.ActionNoop {
+					t.Fatalf("unexpected plans: want one noop plan, got %v", plans)
+				}
+				return
+			}
+			if len(plans) != 1 {
+				t.Fatalf("unexpected length: want 1, got %d", len(plans))
+			}
+			if got, want := plans[0].Reason(), "the rotation of the secret differ"; got != want {
+				t.Errorf("unexpected reason: want %q, got %q", want, got)
+			}
+			if err := plans[0].Apply(ctx); err != nil {
+				t.Fatal(err)
+			}
+			opts := cmpopts.IgnoreUnexported(
+				secretsmanager.RotateSecretInput{},
+				secretsmanager.CancelRotateSecretInput{},
+				types.RotationRulesType{},
+			)
+			if diff := cmp.Diff(tt.want, got, opts); diff != "" {
+				t.Errorf("unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlan_Rotate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const arn = "arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:secret-AbCdEf"
+	var pending *secretsmanager.GetSecretValueOutput
+	var put *secretsmanager.PutSecretValueInput
+	var promoted []*secretsmanager.UpdateSecretVersionStageInput
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("new-secret"), nil
+		}),
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("123456789012"),
+			}, nil
+		}),
+		SecretsManagerSecretGetter: mock.SecretsManagerSecretGetter(func(ctx context.Context, region string, in *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+			if aws.ToString(in.VersionStage) == "AWSPENDING" {
+				if pending == nil {
+					return nil, &types.ResourceNotFoundException{}
+				}
+				return pending, nil
+			}
+			return &secretsmanager.GetSecretValueOutput{
+				ARN:          aws.String(arn),
+				SecretString: aws.String("old-secret"),
+				VersionId:    aws.String("current"),
+			}, nil
+		}),
+		SecretsManagerSecretUpdater: mock.SecretsManagerSecretUpdater(func(ctx context.Context, region string, in *secretsmanager.UpdateSecretInput) (*secretsmanager.UpdateSecretOutput, error) {
+			t.Error("the value must not be overwritten in place")
+			return &secretsmanager.UpdateSecretOutput{}, nil
+		}),
+		SecretsManagerSecretValuePutter: mock.SecretsManagerSecretValuePutter(func(ctx context.Context, region string, in *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error) {
+			put = in
+			pending = &secretsmanager.GetSecretValueOutput{
+				ARN:          aws.String(arn),
+				SecretString: in.SecretString,
+				VersionId:    aws.String("pending"),
+			}
+			return &secretsmanager.PutSecretValueOutput{}, nil
+		}),
+		SecretsManagerVersionStageUpdater: mock.SecretsManagerVersionStageUpdater(func(ctx context.Context, region string, in *secretsmanager.UpdateSecretVersionStageInput) (*secretsmanager.UpdateSecretVersionStageOutput, error) {
+			promoted = append(promoted, in)
+			return &secretsmanager.UpdateSecretVersionStageOutput{}, nil
+		}),
+		Rotate: true,
+	})
+	params := map[string]any{
+		"account": "123456789012",
+		"region":  "ap-northeast-1",
+		"name":    "secret",
+		"source":  "op://vault/item/password",
+	}
+
+	// the first run stages the new value.
+	plans, err := b.Plan(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Reason(), "the value of the secret differs, and the new value will be staged"; got != want {
+		t.Errorf("unexpected reason: want %q, got %q", want, got)
+	}
+	if err := plans[0].Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if put == nil || aws.ToString(put.SecretString) != "new-secret" || !cmp.Equal(put.VersionStages, []string{"AWSPENDING"}) {
+		t.Errorf("unexpected put: %v", put)
+	}
+
+	// the second run promotes the staged value.
+	plans, err = b.Plan(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if err := plans[0].Verify(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := plans[0].Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []*secretsmanager.UpdateSecretVersionStageInput{
+		{
+			SecretId:            aws.String(arn),
+			VersionStage:        aws.String("AWSCURRENT"),
+			MoveToVersionId:     aws.String("pending"),
+			RemoveFromVersionId: aws.String("current"),
+		},
+		{
+			// AWSPENDING is removed from the promoted version.
+			SecretId:            aws.String(arn),
+			VersionStage:        aws.String("AWSPENDING"),
+			RemoveFromVersionId: aws.String("pending"),
+		},
+	}
+	opts := cmpopts.IgnoreUnexported(secretsmanager.UpdateSecretVersionStageInput{})
+	if diff := cmp.Diff(want, promoted, opts); diff != "" {
+		t.Errorf("unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestPlan_Regions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("secret"), nil
+		}),
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("123456789012"),
+			}, nil
+		}),
+		SecretsManagerSecretGetter: mock.SecretsManagerSecretGetter(func(ctx context.Context, region string, in *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+			return nil, &types.ResourceNotFoundException{}
+		}),
+		Regions: []string{"ap-northeast-1", "us-east-1"},
+	})
+	params := map[string]any{
+		"account": "123456789012",
+		"region":  "*",
+		"name":    "secret",
+		"source":  "op://vault/item/password",
+	}
+
+	plans, err := b.Plan(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, plan := range plans {
+		got = append(got, plan.Preview())
+	}
+	want := []string{
+		"create AWS Secrets Manager secret secret on account 123456789012 in ap-northeast-1",
+		"create AWS Secrets Manager secret secret on account 123456789012 in us-east-1",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected previews (-want +got):\n%s", diff)
+	}
+
+	// the secret can't be replicated to the regions where it is synced.
+	params["replica_regions"] = []any{"us-east-1"}
+	if _, err := b.Plan(ctx, params); err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const arn = "arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:removed-AbCdEf"
+	var got *secretsmanager.DeleteSecretInput
+	b := New(&Options{
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("123456789012"),
+			}, nil
+		}),
+		SecretsManagerSecretsLister: mock.SecretsManagerSecretsLister(func(ctx context.Context, region string, in *secretsmanager.ListSecretsInput) (*secretsmanager.ListSecretsOutput, error) {
+			if region != "ap-northeast-1" {
+				t.Errorf("unexpected region: %q", region)
+			}
+			return &secretsmanager.ListSecretsOutput{
+				SecretList: []types.SecretListEntry{
+					{
+						ARN:         aws.String("arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:secret-AbCdEf"),
+						Name:        aws.String("secret"),
+						Description: aws.String("managed by op-sync:\n{}"),
+					},
+					{
+						ARN:           aws.String(arn),
+						Name:          aws.String("removed"),
+						Description:   aws.String("managed by op-sync:\n{}"),
+						PrimaryRegion: aws.String("ap-northeast-1"),
+						SecretVersionsToStages: map[string][]string{
+							"v1": {"AWSPREVIOUS"},
+							"v2": {"AWSCURRENT"},
+						},
+					},
+					{
+						ARN:         aws.String("arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:unmanaged-AbCdEf"),
+						Name:        aws.String("unmanaged"),
+						Description: aws.String("Managed By Op-Sync, but by hand"),
+					},
+					{
+						// the replica of the secret in another region of the config file.
+						ARN:           aws.String("arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:replicated-AbCdEf"),
+						Name:          aws.String("replicated"),
+						Description:   aws.String("managed by op-sync:\n{}"),
+						PrimaryRegion: aws.String("us-west-2"),
+					},
+				},
+			}, nil
+		}),
+		SecretsManagerSecretGetter: mock.SecretsManagerSecretGetter(func(ctx context.Context, region string, in *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+			return &secretsmanager.GetSecretValueOutput{
+				ARN:       in.SecretId,
+				VersionId: aws.String("v2"),
+			}, nil
+		}),
+		SecretsManagerSecretDeleter: mock.SecretsManagerSecretDeleter(func(ctx context.Context, region string, in *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error) {
+			got = in
+			return &secretsmanager.DeleteSecretOutput{}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Prune(ctx, []map[string]any{
+		{
+			"account": "123456789012",
+			"region":  "ap-northeast-1",
+			"name":    "secret",
+			"template": map[string]any{
+				"password": "{{ op://vault/item/field }}",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plan
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if plans[0].Action() != backends.ActionDelete {
+		t.Errorf("unexpected action: %q", plans[0].Action())
+	}
+	if plans[0].Target() != arn {
+		t.Errorf("unexpected target: %q", plans[0].Target())
+	}
+
+	// the plan survives serialization.
+	data, err := plans[0].MarshalPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := b.UnmarshalPlan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Verify(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// apply the plan
+	if err := plan.Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the result
+	want := &secretsmanager.DeleteSecretInput{
+		SecretId: aws.String(arn),
+	}
+	opts := cmpopts.IgnoreUnexported(secretsmanager.DeleteSecretInput{})
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestPrune_AssumeRole(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const roleARN = "arn:aws:iam::210987654321:role/op-sync"
+	accountOf := func(ctx context.Context) string {
+		if services.AWSConfigFromContext(ctx).RoleARN == roleARN {
+			return "210987654321"
+		}
+		return "123456789012"
+	}
+	var deleted []string
+	b := New(&Options{
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String(accountOf(ctx)),
+			}, nil
+		}),
+		SecretsManagerSecretsLister: mock.SecretsManagerSecretsLister(func(ctx context.Context, region string, in *secretsmanager.ListSecretsInput) (*secretsmanager.ListSecretsOutput, error) {
+			account := accountOf(ctx)
+			return &secretsmanager.ListSecretsOutput{
+				SecretList: []types.SecretListEntry{
+					{
+						ARN:         aws.String("arn:aws:secretsmanager:ap-northeast-1:" + account + ":secret:secret-AbCdEf"),
+						Name:        aws.String("secret"),
+						Description: aws.String("managed by op-sync:\n{}"),
+					},
+					{
+						ARN:         aws.String("arn:aws:secretsmanager:ap-northeast-1:" + account + ":secret:removed-AbCdEf"),
+						Name:        aws.String("removed"),
+						Description: aws.String("managed by op-sync:\n{}"),
+					},
+				},
+			}, nil
+		}),
+		SecretsManagerSecretDeleter: mock.SecretsManagerSecretDeleter(func(ctx context.Context, region string, in *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error) {
+			deleted = append(deleted, accountOf(ctx)+" "+aws.ToString(in.SecretId))
+			return &secretsmanager.DeleteSecretOutput{}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Prune(ctx, []map[string]any{
+		{
+			"account":  "123456789012",
+			"region":   "ap-northeast-1",
+			"name":     "secret",
+			"template": map[string]any{},
+		},
+		{
+			"account":  "210987654321",
+			"region":   "ap-northeast-1",
+			"name":     "secret",
+			"template": map[string]any{},
+			"role_arn": roleARN,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("unexpected length: want 2, got %d", len(plans))
+	}
+
+	// apply the plans with the credentials of each account.
+	for _, plan := range plans {
+		data, err := plan.MarshalPlan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan, err := b.UnmarshalPlan(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := plan.Apply(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"123456789012 arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:removed-AbCdEf",
+		"210987654321 arn:aws:secretsmanager:ap-northeast-1:210987654321:secret:removed-AbCdEf",
+	}
+	if diff := cmp.Diff(want, deleted); diff != "" {
+		t.Errorf("unexpected result (-want +got):\n%s", diff)
 	}
 }
@@ -1,9 +1,15 @@
 package awsssm
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
@@ -11,6 +17,7 @@ import (
 	"github.com/shogo82148/op-sync/internal/backends"
 	"github.com/shogo82148/op-sync/internal/maputils"
 	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/awsconfig"
 )
 
 func isNotFoundError(err error) bool {
@@ -18,7 +25,11 @@ func isNotFoundError(err error) bool {
 	return errors.As(err, &awsErr)
 }
 
+// managedPrefix is the prefix of the descriptions of the parameters that op-sync creates.
+const managedPrefix = "managed by op-sync"
+
 var _ backends.Backend = (*Backend)(nil)
+var _ backends.Pruner = (*Backend)(nil)
 
 type Backend struct {
 	opts *Options
@@ -26,109 +37,770 @@ type Backend struct {
 
 type Options struct {
 	services.OnePasswordReader
+	services.OnePasswordItemGetter
 	services.STSCallerIdentityGetter
 	services.SSMParameterGetter
 	services.SSMParameterPutter
+	services.SSMTagsLister
+	services.SSMTagsAdder
+	services.SSMParametersDescriber
+	services.SSMParameterDeleter
+
+	// EndpointURL is the default endpoint of the AWS services.
+	// It can be overridden by the endpoint_url parameter of each entry.
+	EndpointURL string
+
+	// Regions are the allowed regions that region: "*" of the entries expands to.
+	Regions []string
 }
 
 func New(opts *Options) *Backend {
 	return &Backend{opts: opts}
 }
 
+// attributes are the optional attributes of the parameter.
+// Only the declared attributes are compared with the parameter.
+type attributes struct {
+	Type     types.ParameterType `json:"type,omitempty"`
+	KeyID    string              `json:"kms_key_id,omitempty"`
+	Tier     types.ParameterTier `json:"tier,omitempty"`
+	Tags     map[string]string   `json:"tags,omitempty"`
+	DataType string              `json:"data_type,omitempty"`
+
+	// Policies are the policies in the canonical JSON form.
+	Policies []string `json:"policies,omitempty"`
+}
+
+// parseAttributes parses the optional attributes of the entry.
+func parseAttributes(c *maputils.Context, params map[string]any) (attributes, error) {
+	typ, _ := maputils.Get[string](c, params, "parameter_type")
+	keyID, _ := maputils.Get[string](c, params, "kms_key_id")
+	tier, _ := maputils.Get[string](c, params, "tier")
+	tags, _ := maputils.Get[map[string]any](c, params, "tags")
+	dataType, _ := maputils.Get[string](c, params, "data_type")
+	policies, _ := maputils.Get[[]any](c, params, "policies")
+	if err := c.Err(); err != nil {
+		return attributes{}, err
+	}
+
+	attrs := attributes{
+		Type:     types.ParameterType(typ),
+		KeyID:    keyID,
+		Tier:     types.ParameterTier(tier),
+		DataType: dataType,
+	}
+	if typ != "" && !slices.Contains(attrs.Type.Values(), attrs.Type) {
+		return attributes{}, fmt.Errorf("invalid parameter_type %q", typ)
+	}
+	if keyID != "" && typ != "" && attrs.Type != types.ParameterTypeSecureString {
+		return attributes{}, fmt.Errorf("kms_key_id is available only for SecureString, but parameter_type is %q", typ)
+	}
+	if tier != "" && !slices.Contains(attrs.Tier.Values(), attrs.Tier) {
+		return attributes{}, fmt.Errorf("invalid tier %q", tier)
+	}
+	if len(tags) > 0 {
+		attrs.Tags = make(map[string]string, len(tags))
+		for k, v := range tags {
+			s, ok := v.(string)
+			if !ok {
+				return attributes{}, fmt.Errorf("invalid value %v of tag %q, want string", v, k)
+			}
+			attrs.Tags[k] = s
+		}
+	}
+	for _, policy := range policies {
+		if _, ok := policy.(map[string]any); !ok {
+			return attributes{}, fmt.Errorf("invalid policy %v", policy)
+		}
+		data, err := json.Marshal(policy)
+		if err != nil {
+			return attributes{}, fmt.Errorf("invalid policy: %w", err)
+		}
+		canonical, err := canonicalPolicy(string(data))
+		if err != nil {
+			return attributes{}, err
+		}
+		attrs.Policies = append(attrs.Policies, canonical)
+	}
+	slices.Sort(attrs.Policies)
+	return attrs, nil
+}
+
+// canonicalPolicy normalizes the JSON of the policy to compare.
+func canonicalPolicy(policy string) (string, error) {
+	var v any
+	if err := json.Unmarshal([]byte(policy), &v); err != nil {
+		return "", fmt.Errorf("invalid policy: %w", err)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("invalid policy: %w", err)
+	}
+	return string(data), nil
+}
+
+// diff returns the names of the declared attributes that differ from the parameter.
+func (b *Backend) diff(ctx context.Context, region string, attrs attributes, param *types.Parameter) ([]string, error) {
+	var changes []string
+	if attrs.Type != "" && param.Type != attrs.Type {
+		changes = append(changes, "parameter_type")
+	}
+	if attrs.DataType != "" && aws.ToString(param.DataType) != attrs.DataType {
+		changes = append(changes, "data_type")
+	}
+
+	if attrs.KeyID != "" || attrs.Tier != "" || len(attrs.Policies) > 0 {
+		out, err := b.opts.SSMDescribeParameters(ctx, region, &ssm.DescribeParametersInput{
+			ParameterFilters: []types.ParameterStringFilter{
+				{
+					Key:    aws.String("Name"),
+					Option: aws.String("Equals"),
+					Values: []string{aws.ToString(param.Name)},
+				},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe parameter: %w", err)
+		}
+		if len(out.Parameters) == 0 {
+			return nil, fmt.Errorf("parameter %s is not found", aws.ToString(param.Name))
+		}
+		meta := out.Parameters[0]
+		if attrs.KeyID != "" && aws.ToString(meta.KeyId) != attrs.KeyID {
+			changes = append(changes, "kms_key_id")
+		}
+		// the actual tier of Intelligent-Tiering parameters is Standard or Advanced.
+		if attrs.Tier != "" && attrs.Tier != types.ParameterTierIntelligentTiering && meta.Tier != attrs.Tier {
+			changes = append(changes, "tier")
+		}
+		if len(attrs.Policies) > 0 {
+			policies := make([]string, 0, len(meta.Policies))
+			for _, policy := range meta.Policies {
+				canonical, err := canonicalPolicy(aws.ToString(policy.PolicyText))
+				if err != nil {
+					return nil, err
+				}
+				policies = append(policies, canonical)
+			}
+			slices.Sort(policies)
+			if !slices.Equal(policies, attrs.Policies) {
+				changes = append(changes, "policies")
+			}
+		}
+	}
+
+	// the tags that are not declared are kept.
+	if len(attrs.Tags) > 0 {
+		out, err := b.opts.SSMListTagsForResource(ctx, region, &ssm.ListTagsForResourceInput{
+			ResourceType: types.ResourceTypeForTaggingParameter,
+			ResourceId:   param.Name,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of parameter: %w", err)
+		}
+		tags := make(map[string]string, len(out.TagList))
+		for _, tag := range out.TagList {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		for k, v := range attrs.Tags {
+			if got, ok := tags[k]; !ok || got != v {
+				changes = append(changes, "tags")
+				break
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Plan plans the parameters of the entry in each region.
 func (b *Backend) Plan(ctx context.Context, params map[string]any) ([]backends.Plan, error) {
+	regions, err := awsconfig.ParseRegions(new(maputils.Context), params, b.opts.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("awsssm: validation failed: %w", err)
+	}
+
+	var plans []backends.Plan
+	for _, region := range regions {
+		p, err := b.planRegion(ctx, region, params)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p...)
+	}
+	return plans, nil
+}
+
+// planRegion plans the parameter of the entry in the region.
+func (b *Backend) planRegion(ctx context.Context, region string, params map[string]any) ([]backends.Plan, error) {
 	c := new(maputils.Context)
 	account := maputils.Must[string](c, params, "account")
-	region := maputils.Must[string](c, params, "region")
-	name := maputils.Must[string](c, params, "name")
+	name, hasName := maputils.Get[string](c, params, "name")
+	pathPrefix, hasPathPrefix := maputils.Get[string](c, params, "path_prefix")
 	source := maputils.Must[string](c, params, "source")
 	description, hasDescription := maputils.Get[string](c, params, "description")
-	if err := c.Err(); err != nil {
+	prune, _ := maputils.Get[bool](c, params, "prune")
+	awsConfig := awsconfig.ParseConfig(c, params, b.opts.EndpointURL)
+	attrs, err := parseAttributes(c, params)
+	if err != nil {
 		return nil, fmt.Errorf("awsssm: validation failed: %w", err)
 	}
-	if !hasDescription {
-		description = fmt.Sprintf("managed by op-sync: %s", source)
+	if hasName == hasPathPrefix {
+		return nil, errors.New("awsssm: one of name or path_prefix is required")
+	}
+	if hasPathPrefix && !strings.HasPrefix(pathPrefix, "/") {
+		return nil, fmt.Errorf("awsssm: path_prefix %q must start with /", pathPrefix)
+	}
+	if prune && !hasPathPrefix {
+		return nil, errors.New("awsssm: prune requires path_prefix")
+	}
+	if err := awsconfig.Validate(awsConfig); err != nil {
+		return nil, fmt.Errorf("awsssm: %w", err)
 	}
+	ctx = services.WithAWSConfig(ctx, awsConfig)
 
 	id, err := b.opts.STSGetCallerIdentity(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get caller identity: %w", err)
 	}
-	if aws.ToString(id.Account) != account {
-		return []backends.Plan{}, nil
+	if got := aws.ToString(id.Account); got != account {
+		reason := fmt.Sprintf("credentials are for account %s", got)
+		if hasPathPrefix {
+			name = pathPrefix
+		}
+		return []backends.Plan{backends.NewSkipPlan(parameterARN(region, account, name), reason)}, nil
 	}
 
-	secret, err := b.opts.ReadOnePassword(ctx, source)
+	plan := &Plan{
+		backend:     b,
+		awsConfig:   awsConfig,
+		account:     account,
+		region:      region,
+		name:        name,
+		description: description,
+		attrs:       attrs,
+	}
+	if hasPathPrefix {
+		return b.planHierarchy(ctx, plan, source, pathPrefix, hasDescription, prune)
+	}
+
+	if !hasDescription {
+		plan.description = fmt.Sprintf("%s: %s", managedPrefix, source)
+	}
+	plan.secret, err = b.opts.ReadOnePassword(ctx, source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read secret: %w", err)
 	}
+	return b.planParameter(ctx, plan)
+}
 
-	param, err := b.opts.SSMGetParameter(ctx, region, &ssm.GetParameterInput{
-		Name:           aws.String(name),
+// planParameter completes the plan to put the parameter.
+// It returns no plans if the parameter is up-to-date.
+func (b *Backend) planParameter(ctx context.Context, plan *Plan) ([]backends.Plan, error) {
+	param, err := b.opts.SSMGetParameter(ctx, plan.region, &ssm.GetParameterInput{
+		Name:           aws.String(plan.name),
 		WithDecryption: aws.Bool(true),
 	})
 	if isNotFoundError(err) {
-		return []backends.Plan{
-			&Plan{
-				backend:     b,
-				account:     account,
-				region:      region,
-				name:        name,
-				description: description,
-				secret:      secret,
-				overwrite:   false,
-			},
-		}, nil
+		return []backends.Plan{plan}, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get parameter from parameter store: %w", err)
 	}
 
-	if aws.ToString(param.Parameter.Value) == string(secret) {
-		return []backends.Plan{}, nil
+	var changes []string
+	if aws.ToString(param.Parameter.Value) != string(plan.secret) {
+		changes = append(changes, "value")
+	}
+	attrChanges, err := b.diff(ctx, plan.region, plan.attrs, param.Parameter)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, attrChanges...)
+	if len(changes) == 0 {
+		return []backends.Plan{backends.NewNoopPlan(plan.Target())}, nil
 	}
 
-	return []backends.Plan{
-		&Plan{
-			backend:     b,
-			account:     account,
-			region:      region,
-			name:        name,
-			description: description,
-			secret:      secret,
-			overwrite:   true,
+	plan.version = param.Parameter.Version
+	plan.overwrite = true
+	plan.changes = changes
+	return []backends.Plan{plan}, nil
+}
+
+// validLabel matches the field labels that are available in the names of parameters.
+// "/" is not allowed, because it would make a deeper hierarchy.
+var validLabel = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// planHierarchy plans to put each field of the 1Password item into the parameter under the path prefix,
+// e.g. the field "password" of op://vault/item into /path/prefix/password.
+// The fields with empty values are skipped, because the parameters can't be empty.
+// If prune is true, it also plans to delete the parameters under the prefix that no longer correspond to the fields.
+func (b *Backend) planHierarchy(ctx context.Context, base *Plan, source, pathPrefix string, hasDescription, prune bool) ([]backends.Plan, error) {
+	vault, item, ok := parseItemURI(source)
+	if !ok {
+		return nil, fmt.Errorf("awsssm: source %q must be an item, e.g. op://vault/item", source)
+	}
+	opItem, err := b.opts.GetOnePasswordItem(ctx, vault, item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the item: %w", err)
+	}
+
+	prefix := strings.TrimSuffix(pathPrefix, "/")
+	labels := map[string]struct{}{}
+	for _, field := range opItem.Fields {
+		if field.Label == "" {
+			continue
+		}
+		if !validLabel.MatchString(field.Label) {
+			return nil, fmt.Errorf("awsssm: the label %q of the field in %s is not available in parameter names; use only a-z, A-Z, 0-9, and _.-", field.Label, source)
+		}
+		if _, ok := labels[field.Label]; ok {
+			return nil, fmt.Errorf("awsssm: more than one field in %s is labeled %q", source, field.Label)
+		}
+		labels[field.Label] = struct{}{}
+	}
+
+	// names are the parameters that the fields are put into.
+	// the empty fields are not in names, so their parameters are pruned.
+	names := map[string]struct{}{}
+	plans := []backends.Plan{}
+	for _, field := range opItem.Fields {
+		if field.Label == "" {
+			continue
+		}
+		name := prefix + "/" + field.Label
+
+		ref := field.Reference(vault, item)
+		secret, err := b.opts.ReadOnePassword(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret: %w", err)
+		}
+		if len(secret) == 0 {
+			continue
+		}
+		names[name] = struct{}{}
+
+		plan := *base
+		plan.name = name
+		plan.secret = secret
+		if !hasDescription {
+			plan.description = fmt.Sprintf("%s: %s", managedPrefix, ref)
+		}
+		pp, err := b.planParameter(ctx, &plan)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, pp...)
+	}
+
+	if !prune {
+		return plans, nil
+	}
+	in := &ssm.DescribeParametersInput{
+		ParameterFilters: []types.ParameterStringFilter{
+			{
+				Key:    aws.String("Path"),
+				Option: aws.String("OneLevel"),
+				Values: []string{cmp.Or(prefix, "/")},
+			},
 		},
-	}, nil
+	}
+	for {
+		out, err := b.opts.SSMDescribeParameters(ctx, base.region, in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe parameters: %w", err)
+		}
+		for _, param := range out.Parameters {
+			name := aws.ToString(param.Name)
+			if !strings.HasPrefix(aws.ToString(param.Description), managedPrefix) {
+				continue
+			}
+			if _, ok := names[name]; ok {
+				continue
+			}
+			plans = append(plans, &DeletePlan{
+				backend:   b,
+				awsConfig: base.awsConfig,
+				account:   base.account,
+				region:    base.region,
+				name:      name,
+				version:   param.Version,
+			})
+		}
+		if out.NextToken == nil {
+			break
+		}
+		in.NextToken = out.NextToken
+	}
+	return plans, nil
+}
+
+// parseItemURI parses the reference to the item, e.g. op://vault/item.
+func parseItemURI(uri string) (vault, item string, ok bool) {
+	rest, ok := strings.CutPrefix(uri, "op://")
+	if !ok {
+		return "", "", false
+	}
+	vault, item, ok = strings.Cut(rest, "/")
+	if !ok || vault == "" || item == "" || strings.Contains(item, "/") {
+		return "", "", false
+	}
+	return vault, item, true
+}
+
+// Prune plans to delete the parameters that op-sync created but none of cfgs refers to.
+// The parameters whose description starts with "managed by op-sync" are considered as created by op-sync.
+// Only the accounts and the regions that appear in cfgs are searched.
+// The parameters under the path prefixes in cfgs are left to the prune option of the entries.
+func (b *Backend) Prune(ctx context.Context, cfgs []map[string]any) ([]backends.Plan, error) {
+	// the accounts of the credentials.
+	accounts := awsconfig.NewAccounts(b.opts)
+
+	// collect the parameters in the configuration for each account and region.
+	names := map[awsconfig.Location]map[string]struct{}{}
+	prefixes := map[awsconfig.Location][]string{}
+	// the credentials to access each account.
+	creds := map[awsconfig.Location]services.AWSConfig{}
+	for _, params := range cfgs {
+		c := new(maputils.Context)
+		paramAccount := maputils.Must[string](c, params, "account")
+		name, hasName := maputils.Get[string](c, params, "name")
+		pathPrefix, hasPathPrefix := maputils.Get[string](c, params, "path_prefix")
+		awsConfig := awsconfig.ParseConfig(c, params, b.opts.EndpointURL)
+		regions, err := awsconfig.ParseRegions(c, params, b.opts.Regions)
+		if err != nil {
+			return nil, fmt.Errorf("awsssm: validation failed: %w", err)
+		}
+		if hasName == hasPathPrefix {
+			return nil, errors.New("awsssm: one of name or path_prefix is required")
+		}
+
+		account, err := accounts.Get(ctx, awsConfig)
+		if err != nil {
+			return nil, err
+		}
+		if paramAccount != account {
+			continue
+		}
+
+		for _, region := range regions {
+			loc := awsconfig.Location{Account: account, Region: region}
+			if _, ok := names[loc]; !ok {
+				names[loc] = map[string]struct{}{}
+				creds[loc] = awsConfig
+			}
+			if hasPathPrefix {
+				prefixes[loc] = append(prefixes[loc], strings.TrimSuffix(pathPrefix, "/")+"/")
+			} else {
+				names[loc][name] = struct{}{}
+			}
+		}
+	}
+
+	plans := []backends.Plan{}
+	for _, loc := range slices.SortedFunc(maps.Keys(names), awsconfig.CompareLocation) {
+		awsConfig, region := creds[loc], loc.Region
+		ctx := services.WithAWSConfig(ctx, awsConfig)
+		in := &ssm.DescribeParametersInput{}
+		for {
+			out, err := b.opts.SSMDescribeParameters(ctx, region, in)
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe parameters: %w", err)
+			}
+			for _, param := range out.Parameters {
+				name := aws.ToString(param.Name)
+				if !strings.HasPrefix(aws.ToString(param.Description), managedPrefix) {
+					continue
+				}
+				if _, ok := names[loc][name]; ok {
+					continue
+				}
+				if slices.ContainsFunc(prefixes[loc], func(prefix string) bool { return strings.HasPrefix(name, prefix) }) {
+					continue
+				}
+				plans = append(plans, &DeletePlan{
+					backend:   b,
+					awsConfig: awsConfig,
+					account:   loc.Account,
+					region:    region,
+					name:      name,
+					version:   param.Version,
+				})
+			}
+			if out.NextToken == nil {
+				break
+			}
+			in.NextToken = out.NextToken
+		}
+	}
+	return plans, nil
+}
+
+const planKindDelete = "delete"
+
+func (b *Backend) UnmarshalPlan(data []byte) (backends.Plan, error) {
+	var v planJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("awsssm: failed to unmarshal the plan: %w", err)
+	}
+	switch v.Kind {
+	case "":
+		return &Plan{
+			backend:     b,
+			awsConfig:   v.AWS,
+			account:     v.Account,
+			region:      v.Region,
+			name:        v.Name,
+			description: v.Description,
+			attrs:       v.Attributes,
+			secret:      v.Secret,
+			version:     v.Version,
+			overwrite:   v.Overwrite,
+			changes:     v.Changes,
+		}, nil
+	case planKindDelete:
+		return &DeletePlan{
+			backend:   b,
+			awsConfig: v.AWS,
+			account:   v.Account,
+			region:    v.Region,
+			name:      v.Name,
+			version:   v.Version,
+		}, nil
+	}
+	return nil, fmt.Errorf("awsssm: unknown plan kind %q", v.Kind)
+}
+
+// parameterARN returns the ARN of the parameter.
+func parameterARN(region, account, name string) string {
+	return fmt.Sprintf("arn:aws:ssm:%s:%s:parameter/%s", region, account, strings.TrimPrefix(name, "/"))
 }
 
 var _ backends.Plan = (*Plan)(nil)
 
 type Plan struct {
 	backend     *Backend
+	awsConfig   services.AWSConfig
 	account     string
 	region      string
 	name        string
 	description string
+	attrs       attributes
 	secret      []byte
-	overwrite   bool
+
+	// version is the version of the parameter when the plan was made.
+	version   int64
+	overwrite bool
+
+	// changes are the names of the differences, e.g. "value" and "tags".
+	changes []string
+}
+
+type planJSON struct {
+	Kind        string             `json:"kind,omitempty"`
+	AWS         services.AWSConfig `json:"aws,omitzero"`
+	Account     string             `json:"account"`
+	Region      string             `json:"region"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Attributes  attributes         `json:"attributes,omitzero"`
+	Secret      []byte             `json:"secret,omitempty"`
+	Version     int64              `json:"version,omitempty"`
+	Overwrite   bool               `json:"overwrite"`
+	Changes     []string           `json:"changes,omitempty"`
 }
 
 func (p *Plan) Preview() string {
 	if p.overwrite {
-		return fmt.Sprintf("aws ssm parameter store %s on account %s will be updated", p.name, p.account)
+		return fmt.Sprintf("aws ssm parameter store %s on account %s in %s will be updated", p.name, p.account, p.region)
+	}
+	return fmt.Sprintf("aws ssm parameter store %s on account %s in %s will be created", p.name, p.account, p.region)
+}
+
+func (p *Plan) Action() backends.Action {
+	if p.overwrite {
+		return backends.ActionUpdate
+	}
+	return backends.ActionCreate
+}
+
+func (p *Plan) Target() string {
+	return parameterARN(p.region, p.account, p.name)
+}
+
+func (p *Plan) Reason() string {
+	if !p.overwrite {
+		return "the parameter does not exist"
+	}
+	if len(p.changes) == 0 || slices.Equal(p.changes, []string{"value"}) {
+		return "the value of the parameter differs"
+	}
+	return fmt.Sprintf("the %s of the parameter differ", strings.Join(p.changes, ", "))
+}
+
+func (p *Plan) Verify(ctx context.Context) error {
+	ctx = services.WithAWSConfig(ctx, p.awsConfig)
+	param, err := p.backend.opts.SSMGetParameter(ctx, p.region, &ssm.GetParameterInput{
+		Name: aws.String(p.name),
+	})
+	if isNotFoundError(err) {
+		if p.overwrite {
+			return fmt.Errorf("parameter %s was removed: %w", p.name, backends.ErrStalePlan)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get parameter from parameter store: %w", err)
+	}
+	if !p.overwrite || param.Parameter.Version != p.version {
+		return fmt.Errorf("parameter %s was updated: %w", p.name, backends.ErrStalePlan)
 	}
-	return fmt.Sprintf("aws ssm parameter store %s on account %s will be created", p.name, p.account)
+	return nil
+}
+
+func (p *Plan) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		AWS:         p.awsConfig,
+		Account:     p.account,
+		Region:      p.region,
+		Name:        p.name,
+		Description: p.description,
+		Attributes:  p.attrs,
+		Secret:      p.secret,
+		Version:     p.version,
+		Overwrite:   p.overwrite,
+		Changes:     p.changes,
+	})
 }
 
 func (p *Plan) Apply(ctx context.Context) error {
-	_, err := p.backend.opts.SSMPutParameter(ctx, p.region, &ssm.PutParameterInput{
-		Name:        aws.String(p.name),
-		Type:        types.ParameterTypeSecureString,
-		Value:       aws.String(string(p.secret)),
-		Description: aws.String(p.description),
-		Overwrite:   aws.Bool(p.overwrite),
+	ctx = services.WithAWSConfig(ctx, p.awsConfig)
+
+	// the tags can be changed without putting the parameter.
+	tagsOnly := p.overwrite && slices.Equal(p.changes, []string{"tags"})
+	if !tagsOnly {
+		typ := p.attrs.Type
+		if typ == "" {
+			typ = types.ParameterTypeSecureString
+		}
+		in := &ssm.PutParameterInput{
+			Name:        aws.String(p.name),
+			Type:        typ,
+			Value:       aws.String(string(p.secret)),
+			Description: aws.String(p.description),
+			Overwrite:   aws.Bool(p.overwrite),
+			Tier:        p.attrs.Tier,
+		}
+		if p.attrs.KeyID != "" {
+			in.KeyId = aws.String(p.attrs.KeyID)
+		}
+		if p.attrs.DataType != "" {
+			in.DataType = aws.String(p.attrs.DataType)
+		}
+		if len(p.attrs.Policies) > 0 {
+			in.Policies = aws.String("[" + strings.Join(p.attrs.Policies, ",") + "]")
+		}
+		if !p.overwrite {
+			// PutParameter accepts the tags only when it creates the parameter.
+			in.Tags = p.tags()
+		}
+		if _, err := p.backend.opts.SSMPutParameter(ctx, p.region, in); err != nil {
+			return fmt.Errorf("failed to put parameter to parameter store: %w", err)
+		}
+	}
+
+	if p.overwrite && slices.Contains(p.changes, "tags") {
+		_, err := p.backend.opts.SSMAddTagsToResource(ctx, p.region, &ssm.AddTagsToResourceInput{
+			ResourceType: types.ResourceTypeForTaggingParameter,
+			ResourceId:   aws.String(p.name),
+			Tags:         p.tags(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add tags to parameter: %w", err)
+		}
+	}
+	return nil
+}
+
+// tags returns the declared tags sorted by the keys.
+func (p *Plan) tags() []types.Tag {
+	if len(p.attrs.Tags) == 0 {
+		return nil
+	}
+	tags := make([]types.Tag, 0, len(p.attrs.Tags))
+	for _, k := range slices.Sorted(maps.Keys(p.attrs.Tags)) {
+		tags = append(tags, types.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(p.attrs.Tags[k]),
+		})
+	}
+	return tags
+}
+
+var _ backends.Plan = (*DeletePlan)(nil)
+
+// DeletePlan is a plan to delete the parameter that is no longer in the configuration.
+type DeletePlan struct {
+	backend   *Backend
+	awsConfig services.AWSConfig
+	account   string
+	region    string
+	name      string
+
+	// version is the version of the parameter when the plan was made.
+	version int64
+}
+
+func (p *DeletePlan) Preview() string {
+	return fmt.Sprintf("aws ssm parameter store %s on account %s in %s will be deleted", p.name, p.account, p.region)
+}
+
+func (p *DeletePlan) Action() backends.Action {
+	return backends.ActionDelete
+}
+
+func (p *DeletePlan) Target() string {
+	return parameterARN(p.region, p.account, p.name)
+}
+
+func (p *DeletePlan) Reason() string {
+	return "the parameter is no longer in the configuration"
+}
+
+func (p *DeletePlan) Verify(ctx context.Context) error {
+	ctx = services.WithAWSConfig(ctx, p.awsConfig)
+	param, err := p.backend.opts.SSMGetParameter(ctx, p.region, &ssm.GetParameterInput{
+		Name: aws.String(p.name),
+	})
+	if isNotFoundError(err) {
+		return fmt.Errorf("parameter %s was removed: %w", p.name, backends.ErrStalePlan)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get parameter from parameter store: %w", err)
+	}
+	if param.Parameter.Version != p.version {
+		return fmt.Errorf("parameter %s was updated: %w", p.name, backends.ErrStalePlan)
+	}
+	return nil
+}
+
+func (p *DeletePlan) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Kind:    planKindDelete,
+		AWS:     p.awsConfig,
+		Account: p.account,
+		Region:  p.region,
+		Name:    p.name,
+		Version: p.version,
+	})
+}
+
+func (p *DeletePlan) Apply(ctx context.Context) error {
+	ctx = services.WithAWSConfig(ctx, p.awsConfig)
+	_, err := p.backend.opts.SSMDeleteParameter(ctx, p.region, &ssm.DeleteParameterInput{
+		Name: aws.String(p.name),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to put parameter to parameter store: %w", err)
+		return fmt.Errorf("failed to delete parameter from parameter store: %w", err)
 	}
 	return nil
 }
@@ -2,6 +2,8 @@ package awsssm
 
 import (
 	"context"
+	"encoding/json"
+	"maps"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -10,6 +12,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/shogo82148/op-sync/internal/backends"
+	"github.com/shogo82148/op-sync/internal/services"
 	"github.com/shogo82148/op-sync/internal/services/mock"
 )
 
@@ -51,6 +55,21 @@ func TestPlan(t *testing.T) {
 	if len(plans) != 1 {
 		t.Fatalf("unexpected length: want 1, got %d", len(plans))
 	}
+	if got, want := plans[0].Action(), backends.ActionCreate; got != want {
+		t.Errorf("unexpected action: want %q, got %q", want, got)
+	}
+	if got, want := plans[0].Target(), "arn:aws:ssm:ap-northeast-1:123456789012:parameter/path/to/secret"; got != want {
+		t.Errorf("unexpected target: want %q, got %q", want, got)
+	}
+
+	// encoding/json must not serialize the secret.
+	data, err := json.Marshal(plans[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{}" {
+		t.Errorf("the plan is serialized: %s", data)
+	}
 
 	// apply the plan
 	if err := plans[0].Apply(ctx); err != nil {
@@ -71,6 +90,48 @@ func TestPlan(t *testing.T) {
 	}
 }
 
+func TestPlan_AccountMismatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			t.Error("unexpected call of ReadOnePassword")
+			return nil, nil
+		}),
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("210987654321"),
+			}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"account": "123456789012",
+		"region":  "ap-northeast-1",
+		"name":    "/path/to/secret",
+		"source":  "op://vault/item/field",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plan
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Action(), backends.ActionSkip; got != want {
+		t.Errorf("unexpected action: want %q, got %q", want, got)
+	}
+	if got, want := plans[0].Target(), "arn:aws:ssm:ap-northeast-1:123456789012:parameter/path/to/secret"; got != want {
+		t.Errorf("unexpected target: want %q, got %q", want, got)
+	}
+	if got, want := plans[0].Reason(), "credentials are for account 210987654321"; got != want {
+		t.Errorf("unexpected reason: want %q, got %q", want, got)
+	}
+}
+
 func TestPlan_Overwrite(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -113,6 +174,9 @@ func TestPlan_Overwrite(t *testing.T) {
 	if len(plans) != 1 {
 		t.Fatalf("unexpected length: want 1, got %d", len(plans))
 	}
+	if got, want := plans[0].Action(), backends.ActionUpdate; got != want {
+		t.Errorf("unexpected action: want %q, got %q", want, got)
+	}
 
 	// apply the plan
 	if err := plans[0].Apply(ctx); err != nil {
@@ -167,7 +231,856 @@ func TestPlan_UpToDate(t *testing.T) {
 	}
 
 	// verify the plan
+	if len(plans) != 1 || plans[0].Action() != backends.ActionNoop {
+		t.Fatalf("unexpected plans: want one noop plan, got %v", plans)
+	}
+}
+
+func TestPlan_Attributes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var result *ssm.PutParameterInput
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("secret"), nil
+		}),
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("123456789012"),
+			}, nil
+		}),
+		SSMParameterGetter: mock.SSMParameterGetter(func(ctx context.Context, region string, in *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+			return nil, &types.ParameterNotFound{}
+		}),
+		SSMParameterPutter: mock.SSMParameterPutter(func(ctx context.Context, region string, in *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+			result = in
+			return &ssm.PutParameterOutput{}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"account":        "123456789012",
+		"region":         "ap-northeast-1",
+		"name":           "/path/to/secret",
+		"source":         "op://vault/item/field",
+		"parameter_type": "SecureString",
+		"kms_key_id":     "alias/my-key",
+		"tier":           "Advanced",
+		"data_type":      "text",
+		"tags": map[string]any{
+			"Team": "platform",
+			"Env":  "production",
+		},
+		"policies": []any{
+			map[string]any{
+				"Type":    "Expiration",
+				"Version": "1.0",
+				"Attributes": map[string]any{
+					"Timestamp": "2030-01-01T00:00:00.000Z",
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the plan survives saving into the plan file.
+	data, err := plans[0].MarshalPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := b.UnmarshalPlan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// apply the plan
+	if err := plan.Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the result
+	want := &ssm.PutParameterInput{
+		Name:        aws.String("/path/to/secret"),
+		Description: aws.String("managed by op-sync: op://vault/item/field"),
+		Type:        types.ParameterTypeSecureString,
+		Value:       aws.String("secret"),
+		Overwrite:   aws.Bool(false),
+		KeyId:       aws.String("alias/my-key"),
+		Tier:        types.ParameterTierAdvanced,
+		DataType:    aws.String("text"),
+		Policies:    aws.String(`[{"Attributes":{"Timestamp":"2030-01-01T00:00:00.000Z"},"Type":"Expiration","Version":"1.0"}]`),
+		Tags: []types.Tag{
+			{Key: aws.String("Env"), Value: aws.String("production")},
+			{Key: aws.String("Team"), Value: aws.String("platform")},
+		},
+	}
+	opts := cmpopts.IgnoreUnexported(ssm.PutParameterInput{}, types.Tag{})
+	if diff := cmp.Diff(want, result, opts); diff != "" {
+		t.Errorf("unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestPlan_AttributesDrift(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var putResult *ssm.PutParameterInput
+	var tagsResult *ssm.AddTagsToResourceInput
+	newBackend := func(keyID string) *Backend {
+		return New(&Options{
+			OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+				return []byte("secret"), nil
+			}),
+			STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+				return &sts.GetCallerIdentityOutput{
+					Account: aws.String("123456789012"),
+				}, nil
+			}),
+			SSMParameterGetter: mock.SSMParameterGetter(func(ctx context.Context, region string, in *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+				return &ssm.GetParameterOutput{
+					Parameter: &types.Parameter{
+						Name:     in.Name,
+						Type:     types.ParameterTypeSecureString,
+						DataType: aws.String("text"),
+						Value:    aws.String("secret"),
+						Version:  2,
+					},
+				}, nil
+			}),
+			SSMParametersDescriber: mock.SSMParametersDescriber(func(ctx context.Context, region string, in *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error) {
+				if got := in.ParameterFilters[0].Values; !cmp.Equal(got, []string{"/path/to/secret"}) {
+					t.Errorf("unexpected filter: %v", got)
+				}
+				return &ssm.DescribeParametersOutput{
+					Parameters: []types.ParameterMetadata{
+						{
+							Name:  aws.String("/path/to/secret"),
+							KeyId: aws.String(keyID),
+							Tier:  types.ParameterTierStandard,
+						},
+					},
+				}, nil
+			}),
+			SSMTagsLister: mock.SSMTagsLister(func(ctx context.Context, region string, in *ssm.ListTagsForResourceInput) (*ssm.ListTagsForResourceOutput, error) {
+				return &ssm.ListTagsForResourceOutput{
+					TagList: []types.Tag{
+						{Key: aws.String("Team"), Value: aws.String("security")},
+						{Key: aws.String("Owner"), Value: aws.String("someone")},
+					},
+				}, nil
+			}),
+			SSMParameterPutter: mock.SSMParameterPutter(func(ctx context.Context, region string, in *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+				putResult = in
+				return &ssm.PutParameterOutput{}, nil
+			}),
+			SSMTagsAdder: mock.SSMTagsAdder(func(ctx context.Context, region string, in *ssm.AddTagsToResourceInput) (*ssm.AddTagsToResourceOutput, error) {
+				tagsResult = in
+				return &ssm.AddTagsToResourceOutput{}, nil
+			}),
+		})
+	}
+	params := map[string]any{
+		"account":    "123456789012",
+		"region":     "ap-northeast-1",
+		"name":       "/path/to/secret",
+		"source":     "op://vault/item/field",
+		"kms_key_id": "alias/my-key",
+		"tier":       "Standard",
+		"data_type":  "text",
+		"tags": map[string]any{
+			"Team": "platform",
+		},
+	}
+
+	t.Run("key and tags", func(t *testing.T) {
+		putResult, tagsResult = nil, nil
+		b := newBackend("alias/aws/ssm")
+		plans, err := b.Plan(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plans) != 1 {
+			t.Fatalf("unexpected length: want 1, got %d", len(plans))
+		}
+		if got, want := plans[0].Action(), backends.ActionUpdate; got != want {
+			t.Errorf("unexpected action: want %q, got %q", want, got)
+		}
+		if got, want := plans[0].Reason(), "the kms_key_id, tags of the parameter differ"; got != want {
+			t.Errorf("unexpected reason: want %q, got %q", want, got)
+		}
+		if err := plans[0].Apply(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if putResult == nil || aws.ToString(putResult.KeyId) != "alias/my-key" || putResult.Tags != nil {
+			t.Errorf("unexpected put: %v", putResult)
+		}
+		if tagsResult == nil || len(tagsResult.Tags) != 1 || aws.ToString(tagsResult.Tags[0].Value) != "platform" {
+			t.Errorf("unexpected tags: %v", tagsResult)
+		}
+	})
+
+	t.Run("tags only", func(t *testing.T) {
+		putResult, tagsResult = nil, nil
+		b := newBackend("alias/my-key")
+		plans, err := b.Plan(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plans) != 1 {
+			t.Fatalf("unexpected length: want 1, got %d", len(plans))
+		}
+		if err := plans[0].Apply(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if putResult != nil {
+			t.Errorf("unexpected put: %v", putResult)
+		}
+		if tagsResult == nil {
+			t.Error("want tags added, but not")
+		}
+	})
+}
+
+func TestPlan_InvalidAttributes(t *testing.T) {
+	b := New(&Options{})
+	tests := []map[string]any{
+		{"parameter_type": "Secret"},
+		{"tier": "Premium"},
+		{"parameter_type": "String", "kms_key_id": "alias/my-key"},
+		{"tags": map[string]any{"Team": 1}},
+	}
+	for _, tt := range tests {
+		params := map[string]any{
+			"account": "123456789012",
+			"region":  "ap-northeast-1",
+			"name":    "/path/to/secret",
+			"source":  "op://vault/item/field",
+		}
+		maps.Copy(params, tt)
+		if _, err := b.Plan(context.Background(), params); err == nil {
+			t.Errorf("%v: want error, got nil", tt)
+		}
+	}
+}
+
+func TestPlan_Hierarchy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := New(&Options{
+		OnePasswordItemGetter: mock.OnePasswordItemGetter(func(ctx context.Context, vault, item string) (*services.OnePasswordItem, error) {
+			if vault != "vault" || item != "item" {
+				t.Errorf("unexpected item: %s/%s", vault, item)
+			}
+			return &services.OnePasswordItem{
+				Fields: []*services.OnePasswordField{
+					{ID: "username", Label: "username"},
+					{ID: "password", Label: "password"},
+					{ID: "notesPlain", Label: "notesPlain"},
+				},
+			}, nil
+		}),
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			switch uri {
+			case "op://vault/item/username":
+				return []byte("admin"), nil
+			case "op://vault/item/password":
+				return []byte("secret"), nil
+			case "op://vault/item/notesPlain":
+				return []byte{}, nil
+			}
+			t.Errorf("unexpected uri: %s", uri)
+			return nil, nil
+		}),
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("123456789012"),
+			}, nil
+		}),
+		SSMParameterGetter: mock.SSMParameterGetter(func(ctx context.Context, region string, in *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+			switch aws.ToString(in.Name) {
+			case "/app/prod/username":
+				return &ssm.GetParameterOutput{
+					Parameter: &types.Parameter{Value: aws.String("admin")},
+				}, nil
+			case "/app/prod/password":
+				return &ssm.GetParameterOutput{
+					Parameter: &types.Parameter{Value: aws.String("old-secret"), Version: 2},
+				}, nil
+			}
+			return nil, &types.ParameterNotFound{}
+		}),
+		SSMParametersDescriber: mock.SSMParametersDescriber(func(ctx context.Context, region string, in *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error) {
+			if got := in.ParameterFilters[0].Values; !cmp.Equal(got, []string{"/app/prod"}) {
+				t.Errorf("unexpected filter: %v", got)
+			}
+			return &ssm.DescribeParametersOutput{
+				Parameters: []types.ParameterMetadata{
+					{
+						Name:        aws.String("/app/prod/password"),
+						Description: aws.String("managed by op-sync: op://vault/item/password"),
+					},
+					{
+						Name:        aws.String("/app/prod/removed"),
+						Description: aws.String("managed by op-sync: op://vault/item/removed"),
+						Version:     1,
+					},
+					{
+						// the field is emptied.
+						Name:        aws.String("/app/prod/notesPlain"),
+						Description: aws.String("managed by op-sync: op://vault/item/notesPlain"),
+						Version:     3,
+					},
+					{
+						Name:        aws.String("/app/prod/manual"),
+						Description: aws.String("created by hand"),
+					},
+				},
+			}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"account":     "123456789012",
+		"region":      "ap-northeast-1",
+		"path_prefix": "/app/prod/",
+		"source":      "op://vault/item",
+		"prune":       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plans
+	type result struct {
+		Action backends.Action
+		Target string
+	}
+	got := make([]result, 0, len(plans))
+	for _, plan := range plans {
+		got = append(got, result{Action: plan.Action(), Target: plan.Target()})
+	}
+	want := []result{
+		{Action: backends.ActionNoop, Target: "arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/prod/username"},
+		{Action: backends.ActionUpdate, Target: "arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/prod/password"},
+		{Action: backends.ActionDelete, Target: "arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/prod/removed"},
+		{Action: backends.ActionDelete, Target: "arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/prod/notesPlain"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected plans (-want +got):\n%s", diff)
+	}
+}
+
+func TestPlan_HierarchyInvalidLabel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, label := range []string{"api key", "nested/key", "key:1"} {
+		b := New(&Options{
+			OnePasswordItemGetter: mock.OnePasswordItemGetter(func(ctx context.Context, vault, item string) (*services.OnePasswordItem, error) {
+				return &services.OnePasswordItem{
+					Fields: []*services.OnePasswordField{
+						{ID: "password", Label: "password"},
+						{ID: "invalid", Label: label},
+					},
+				}, nil
+			}),
+			OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+				t.Errorf("unexpected read: %s", uri)
+				return nil, nil
+			}),
+			STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+				return &sts.GetCallerIdentityOutput{
+					Account: aws.String("123456789012"),
+				}, nil
+			}),
+		})
+
+		_, err := b.Plan(ctx, map[string]any{
+			"account":     "123456789012",
+			"region":      "ap-northeast-1",
+			"path_prefix": "/app/prod/",
+			"source":      "op://vault/item",
+		})
+		if err == nil {
+			t.Errorf("label %q: want error, got nil", label)
+		}
+	}
+}
+
+func TestPrune_PathPrefix(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := New(&Options{
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("123456789012"),
+			}, nil
+		}),
+		SSMParametersDescriber: mock.SSMParametersDescriber(func(ctx context.Context, region string, in *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error) {
+			return &ssm.DescribeParametersOutput{
+				Parameters: []types.ParameterMetadata{
+					{
+						Name:        aws.String("/app/prod/password"),
+						Description: aws.String("managed by op-sync: op://vault/item/password"),
+					},
+					{
+						Name:        aws.String("/app/production"),
+						Description: aws.String("managed by op-sync: op://vault/item/field"),
+					},
+				},
+			}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Prune(ctx, []map[string]any{
+		{
+			"account":     "123456789012",
+			"region":      "ap-northeast-1",
+			"path_prefix": "/app/prod",
+			"source":      "op://vault/item",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the parameters under the prefix are kept.
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Target(), "arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/production"; got != want {
+		t.Errorf("unexpected target: want %q, got %q", want, got)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var result *ssm.DeleteParameterInput
+	b := New(&Options{
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("123456789012"),
+			}, nil
+		}),
+		SSMParametersDescriber: mock.SSMParametersDescriber(func(ctx context.Context, region string, in *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error) {
+			if region != "ap-northeast-1" {
+				t.Errorf("unexpected region: %q", region)
+			}
+			if in.NextToken == nil {
+				return &ssm.DescribeParametersOutput{
+					Parameters: []types.ParameterMetadata{
+						{
+							Name:        aws.String("/path/to/secret"),
+							Description: aws.String("managed by op-sync: op://vault/item/field"),
+							Version:     1,
+						},
+						{
+							Name:        aws.String("/path/to/unmanaged"),
+							Description: aws.String("created by hand"),
+							Version:     1,
+						},
+					},
+					NextToken: aws.String("next"),
+				}, nil
+			}
+			return &ssm.DescribeParametersOutput{
+				Parameters: []types.ParameterMetadata{
+					{
+						Name:        aws.String("/path/to/removed"),
+						Description: aws.String("managed by op-sync: op://vault/item/removed"),
+						Version:     3,
+					},
+				},
+			}, nil
+		}),
+		SSMParameterGetter: mock.SSMParameterGetter(func(ctx context.Context, region string, in *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+			return &ssm.GetParameterOutput{
+				Parameter: &types.Parameter{
+					Name:    in.Name,
+					Version: 3,
+				},
+			}, nil
+		}),
+		SSMParameterDeleter: mock.SSMParameterDeleter(func(ctx context.Context, region string, in *ssm.DeleteParameterInput) (*ssm.DeleteParameterOutput, error) {
+			result = in
+			return &ssm.DeleteParameterOutput{}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Prune(ctx, []map[string]any{
+		{
+			"account": "123456789012",
+			"region":  "ap-northeast-1",
+			"name":    "/path/to/secret",
+			"source":  "op://vault/item/field",
+		},
+		{
+			"account": "000000000000",
+			"region":  "us-east-1",
+			"name":    "/path/to/other-account",
+			"source":  "op://vault/item/field",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plan
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Action(), backends.ActionDelete; got != want {
+		t.Errorf("unexpected action: want %q, got %q", want, got)
+	}
+	if got, want := plans[0].Target(), "arn:aws:ssm:ap-northeast-1:123456789012:parameter/path/to/removed"; got != want {
+		t.Errorf("unexpected target: want %q, got %q", want, got)
+	}
+
+	// the plan survives serialization.
+	data, err := plans[0].MarshalPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := b.UnmarshalPlan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Verify(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// apply the plan
+	if err := plan.Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the result
+	want := &ssm.DeleteParameterInput{
+		Name: aws.String("/path/to/removed"),
+	}
+	opts := cmpopts.IgnoreUnexported(ssm.DeleteParameterInput{})
+	if diff := cmp.Diff(want, result, opts); diff != "" {
+		t.Errorf("unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestPlan_AssumeRole(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wantConfig := services.AWSConfig{
+		Profile:    "admin",
+		RoleARN:    "arn:aws:iam::210987654321:role/op-sync",
+		ExternalID: "external-id",
+	}
+	var result *ssm.PutParameterInput
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("secret"), nil
+		}),
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			if services.AWSConfigFromContext(ctx).RoleARN != "" {
+				return &sts.GetCallerIdentityOutput{
+					Account: aws.String("210987654321"),
+				}, nil
+			}
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("123456789012"),
+			}, nil
+		}),
+		SSMParameterGetter: mock.SSMParameterGetter(func(ctx context.Context, region string, in *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+			if got := services.AWSConfigFromContext(ctx); got != wantConfig {
+				t.Errorf("unexpected aws config: %v", got)
+			}
+			return nil, &types.ParameterNotFound{}
+		}),
+		SSMParameterPutter: mock.SSMParameterPutter(func(ctx context.Context, region string, in *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+			if got := services.AWSConfigFromContext(ctx); got != wantConfig {
+				t.Errorf("unexpected aws config: %v", got)
+			}
+			result = in
+			return &ssm.PutParameterOutput{}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"account":     "210987654321",
+		"region":      "ap-northeast-1",
+		"name":        "/path/to/secret",
+		"source":      "op://vault/item/field",
+		"profile":     "admin",
+		"role_arn":    "arn:aws:iam::210987654321:role/op-sync",
+		"external_id": "external-id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Target(), "arn:aws:ssm:ap-northeast-1:210987654321:parameter/path/to/secret"; got != want {
+		t.Errorf("unexpected target: want %q, got %q", want, got)
+	}
+
+	// the plan survives serialization.
+	data, err := plans[0].MarshalPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := b.UnmarshalPlan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Verify(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if result == nil {
+		t.Fatal("the parameter is not put")
+	}
+
+	// external_id requires role_arn.
+	_, err = b.Plan(ctx, map[string]any{
+		"account":     "123456789012",
+		"region":      "ap-northeast-1",
+		"name":        "/path/to/secret",
+		"source":      "op://vault/item/field",
+		"external_id": "external-id",
+	})
+	if err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestPlan_EndpointURL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tests := []struct {
+		name   string
+		global string
+		entry  string
+		want   string
+	}{
+		{name: "default", want: ""},
+		{name: "global", global: "http://localhost:4566", want: "http://localhost:4566"},
+		{name: "entry", global: "http://localhost:4566", entry: "http://localstack:4566", want: "http://localstack:4566"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(&Options{
+				OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+					return []byte("secret"), nil
+				}),
+				STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+					if got := services.AWSConfigFromContext(ctx).EndpointURL; got != tt.want {
+						t.Errorf("unexpected endpoint: want %q, got %q", tt.want, got)
+					}
+					return &sts.GetCallerIdentityOutput{
+						Account: aws.String("000000000000"),
+					}, nil
+				}),
+				SSMParameterGetter: mock.SSMParameterGetter(func(ctx context.Context, region string, in *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+					if got := services.AWSConfigFromContext(ctx).EndpointURL; got != tt.want {
+						t.Errorf("unexpected endpoint: want %q, got %q", tt.want, got)
+					}
+					return nil, &types.ParameterNotFound{}
+				}),
+				EndpointURL: tt.global,
+			})
+
+			params := map[string]any{
+				"account": "000000000000",
+				"region":  "ap-northeast-1",
+				"name":    "/path/to/secret",
+				"source":  "op://vault/item/field",
+			}
+			if tt.entry != "" {
+				params["endpoint_url"] = tt.entry
+			}
+			plans, err := b.Plan(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plans) != 1 {
+				t.Fatalf("unexpected length: want 1, got %d", len(plans))
+			}
+		})
+	}
+}
+
+func TestPlan_InvalidEndpointURL(t *testing.T) {
+	b := New(&Options{})
+	for _, endpoint := range []string{"localhost:4566", "/path", "http://"} {
+		_, err := b.Plan(context.Background(), map[string]any{
+			"account":      "000000000000",
+			"region":       "ap-northeast-1",
+			"name":         "/path/to/secret",
+			"source":       "op://vault/item/field",
+			"endpoint_url": endpoint,
+		})
+		if err == nil {
+			t.Errorf("%q: want error, got nil", endpoint)
+		}
+	}
+}
+
+func TestPlan_Regions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tests := []struct {
+		name    string
+		params  map[string]any
+		allowed []string
+		want    []string
+	}{
+		{
+			name:   "regions",
+			params: map[string]any{"regions": []any{"ap-northeast-1", "us-east-1", "ap-northeast-1"}},
+			want: []string{
+				"aws ssm parameter store /path/to/secret on account 123456789012 in ap-northeast-1 will be created",
+				"aws ssm parameter store /path/to/secret on account 123456789012 in us-east-1 will be created",
+			},
+		},
+		{
+			name:    "wildcard",
+			params:  map[string]any{"region": "*"},
+			allowed: []string{"eu-west-1", "us-west-2"},
+			want: []string{
+				"aws ssm parameter store /path/to/secret on account 123456789012 in eu-west-1 will be created",
+				"aws ssm parameter store /path/to/secret on account 123456789012 in us-west-2 will be created",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			put := map[string]string{}
+			b := New(&Options{
+				OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+					return []byte("secret"), nil
+				}),
+				STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+					return &sts.GetCallerIdentityOutput{
+						Account: aws.String("123456789012"),
+					}, nil
+				}),
+				SSMParameterGetter: mock.SSMParameterGetter(func(ctx context.Context, region string, in *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+					return nil, &types.ParameterNotFound{}
+				}),
+				SSMParameterPutter: mock.SSMParameterPutter(func(ctx context.Context, region string, in *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+					put[region] = aws.ToString(in.Value)
+					return &ssm.PutParameterOutput{}, nil
+				}),
+				Regions: tt.allowed,
+			})
+
+			params := map[string]any{
+				"account": "123456789012",
+				"name":    "/path/to/secret",
+				"source":  "op://vault/item/field",
+			}
+			maps.Copy(params, tt.params)
+			plans, err := b.Plan(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, plan := range plans {
+				got = append(got, plan.Preview())
+				if err := plan.Apply(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected previews (-want +got):\n%s", diff)
+			}
+			if len(put) != len(tt.want) {
+				t.Errorf("unexpected puts: %v", put)
+			}
+		})
+	}
+}
+
+func TestPlan_InvalidRegions(t *testing.T) {
+	b := New(&Options{})
+	tests := []map[string]any{
+		{},
+		{"region": "ap-northeast-1", "regions": []any{"us-east-1"}},
+		{"regions": []any{}},
+		{"regions": []any{"us-east-1", 1}},
+		{"regions": []any{"*"}},
+		// no allowed regions
+		{"region": "*"},
+	}
+	for _, tt := range tests {
+		params := map[string]any{
+			"account": "123456789012",
+			"name":    "/path/to/secret",
+			"source":  "op://vault/item/field",
+		}
+		maps.Copy(params, tt)
+		if _, err := b.Plan(context.Background(), params); err == nil {
+			t.Errorf("%v: want error, got nil", tt)
+		}
+	}
+}
+
+func TestPrune_Regions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got []string
+	b := New(&Options{
+		STSCallerIdentityGetter: mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+			return &sts.GetCallerIdentityOutput{
+				Account: aws.String("123456789012"),
+			}, nil
+		}),
+		SSMParametersDescriber: mock.SSMParametersDescriber(func(ctx context.Context, region string, in *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error) {
+			got = append(got, region)
+			return &ssm.DescribeParametersOutput{
+				Parameters: []types.ParameterMetadata{
+					{
+						Name:        aws.String("/path/to/secret"),
+						Description: aws.String("managed by op-sync: op://vault/item/field"),
+						Version:     1,
+					},
+				},
+			}, nil
+		}),
+		Regions: []string{"ap-northeast-1", "us-east-1"},
+	})
+
+	plans, err := b.Prune(ctx, []map[string]any{
+		{
+			"account": "123456789012",
+			"region":  "*",
+			"name":    "/path/to/secret",
+			"source":  "op://vault/item/field",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
 	if len(plans) != 0 {
-		t.Fatalf("unexpected length: want 0, got %d", len(plans))
+		t.Errorf("unexpected length: want 0, got %d", len(plans))
+	}
+	if diff := cmp.Diff([]string{"ap-northeast-1", "us-east-1"}, got); diff != "" {
+		t.Errorf("unexpected regions (-want +got):\n%s", diff)
 	}
 }
@@ -1,14 +1,159 @@
 package backends
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrStalePlan is returned by [Plan.Verify] when the target was changed after planning.
+var ErrStalePlan = errors.New("the target was changed after planning")
 
 // Backend is a backend of op-sync.
 type Backend interface {
 	Plan(ctx context.Context, cfg map[string]any) ([]Plan, error)
+
+	// UnmarshalPlan restores the plan serialized by [Plan.MarshalPlan].
+	UnmarshalPlan(data []byte) (Plan, error)
+}
+
+// Pruner is implemented by the backends that can delete the targets
+// which op-sync created but are no longer in the configuration.
+type Pruner interface {
+	// Prune plans to delete the targets managed by op-sync that none of cfgs refers to.
+	// cfgs are the configurations of all secrets of the backend.
+	Prune(ctx context.Context, cfgs []map[string]any) ([]Plan, error)
 }
 
+// Action is the kind of the change that a plan makes.
+type Action string
+
+const (
+	// ActionCreate means the target will be created.
+	ActionCreate Action = "create"
+
+	// ActionUpdate means the target will be updated.
+	ActionUpdate Action = "update"
+
+	// ActionDelete means the target will be deleted.
+	ActionDelete Action = "delete"
+
+	// ActionNoop means the target is up-to-date.
+	ActionNoop Action = "noop"
+
+	// ActionSkip means the target was not checked,
+	// e.g. the credentials are for another account.
+	ActionSkip Action = "skip"
+)
+
 // Plan is a plan of op-sync.
 type Plan interface {
+	// Preview returns the human-readable description of the plan.
 	Preview() string
+
+	// Action returns the kind of the change.
+	Action() Action
+
+	// Target returns the identifier of the target.
+	// It must not contain any secret.
+	Target() string
+
+	// Reason returns why the change is needed.
+	// It must not contain any secret.
+	Reason() string
+
+	// Verify checks that the target has not been changed since the plan was made.
+	// It returns an error wrapping [ErrStalePlan] if the target was changed.
+	Verify(ctx context.Context) error
+
+	// MarshalPlan serializes the plan so that it can be applied later.
+	// The result may contain secrets.
+	// It is not MarshalJSON so that encoding/json and log/slog never serialize the secrets by accident.
+	MarshalPlan() ([]byte, error)
+
+	// Apply applies the plan.
 	Apply(ctx context.Context) error
 }
+
+var _ Plan = (*SkipPlan)(nil)
+
+// SkipPlan is a plan for the target that was not checked.
+// It makes no changes.
+type SkipPlan struct {
+	target string
+	reason string
+}
+
+// NewSkipPlan returns a plan reporting that the target was skipped for the reason.
+func NewSkipPlan(target, reason string) *SkipPlan {
+	return &SkipPlan{target: target, reason: reason}
+}
+
+func (p *SkipPlan) Preview() string {
+	return fmt.Sprintf("%s is skipped: %s", p.target, p.reason)
+}
+
+func (p *SkipPlan) Action() Action {
+	return ActionSkip
+}
+
+func (p *SkipPlan) Target() string {
+	return p.target
+}
+
+func (p *SkipPlan) Reason() string {
+	return p.reason
+}
+
+func (p *SkipPlan) Verify(ctx context.Context) error {
+	return nil
+}
+
+func (p *SkipPlan) MarshalPlan() ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+func (p *SkipPlan) Apply(ctx context.Context) error {
+	return nil
+}
+
+var _ Plan = (*NoopPlan)(nil)
+
+// NoopPlan is a plan for the target that is up-to-date.
+// It makes no changes.
+type NoopPlan struct {
+	target string
+}
+
+// NewNoopPlan returns a plan reporting that the target is up-to-date.
+func NewNoopPlan(target string) *NoopPlan {
+	return &NoopPlan{target: target}
+}
+
+func (p *NoopPlan) Preview() string {
+	return fmt.Sprintf("%s is up-to-date", p.target)
+}
+
+func (p *NoopPlan) Action() Action {
+	return ActionNoop
+}
+
+func (p *NoopPlan) Target() string {
+	return p.target
+}
+
+func (p *NoopPlan) Reason() string {
+	return "the secret is up-to-date"
+}
+
+func (p *NoopPlan) Verify(ctx context.Context) error {
+	return nil
+}
+
+func (p *NoopPlan) MarshalPlan() ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+func (p *NoopPlan) Apply(ctx context.Context) error {
+	return nil
+}
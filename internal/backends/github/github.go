@@ -1,12 +1,19 @@
 package github
 
 import (
+	"cmp"
 	"context"
+	"crypto/hmac"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"path"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v56/github"
 	"github.com/shogo82148/op-sync/internal/backends"
@@ -17,6 +24,7 @@ import (
 )
 
 var _ backends.Backend = (*Backend)(nil)
+var _ backends.Pruner = (*Backend)(nil)
 
 type Backend struct {
 	opts *Options
@@ -26,9 +34,15 @@ type Options struct {
 	services.OnePasswordItemGetter
 	services.OnePasswordReader
 	services.GitHubRepoGetter
+	services.GitHubReposLister
 	services.GitHubRepoSecretGetter
 	services.GitHubRepoSecretCreator
 	services.GitHubRepoPublicKeyGetter
+	services.GitHubUserByLoginGetter
+	services.GitHubTeamGetter
+	services.GitHubEnvGetter
+	services.GitHubEnvCreator
+	services.GitHubDeploymentBranchPolicyCreator
 	services.GitHubEnvSecretGetter
 	services.GitHubEnvSecretCreator
 	services.GitHubEnvPublicKeyGetter
@@ -36,29 +50,144 @@ type Options struct {
 	services.GitHubOrgSecretCreator
 	services.GitHubOrgPublicKeyGetter
 	services.GitHubReposIDForOrgSecretLister
+	services.GitHubRepoSecretsLister
+	services.GitHubRepoSecretDeleter
+	services.GitHubEnvSecretsLister
+	services.GitHubEnvSecretDeleter
+	services.GitHubOrgSecretsLister
+	services.GitHubOrgSecretDeleter
+	services.GitHubUserSecretGetter
+	services.GitHubUserSecretCreator
+	services.GitHubUserPublicKeyGetter
+	services.GitHubReposIDForUserSecretLister
+	services.GitHubUserSecretsLister
+	services.GitHubUserSecretDeleter
+
+	// SecretHasher, StateGetter, and StatePutter are optional.
+	// If they are set, the changes are detected by the keyed hashes of the values recorded in the state.
+	services.SecretHasher
+	services.StateGetter
+	services.StatePutter
+
+	// StateDeleter is optional. If it is set, the states of the deleted secrets are deleted.
+	services.StateDeleter
+
+	// PruneScopes are the scopes where Prune deletes the secrets that are not in the configuration.
+	// They are in the same form as the configuration of secrets without the name and the source.
+	PruneScopes []map[string]any
 }
 
 func New(opts *Options) *Backend {
 	return &Backend{opts: opts}
 }
 
-func (b *Backend) Plan(ctx context.Context, params map[string]any) ([]backends.Plan, error) {
+// secretParams is the parsed configuration of a secret.
+type secretParams struct {
+	app          services.GitHubApplication
+	organization string
+	name         string
+	source       string
+
+	// user means the Codespaces secrets of the authenticated user.
+	user bool
+
+	// host is the host of GitHub Enterprise Server.
+	// The host of the GitHub identity in the context is used if it is empty.
+	host string
+
+	// repositories are the target repositories in the form of "owner/repo".
+	// The name may be a glob pattern such as "owner/service-*".
+	// topics filters the target repositories by their topics.
+	repositories []string
+	topics       []string
+
+	// environments are the target environments in each repository.
+	// createEnvironment is the settings of the environments that op-sync creates if they don't exist.
+	// It is nil if op-sync doesn't create the environments.
+	environments      []string
+	createEnvironment *envOptions
+
+	// visibility and selectedRepositories are the access policy of organization secrets.
+	// The policy of the existing secret is kept if visibility is empty.
+	// selectedRepositories are also the repositories that can access user secrets.
+	// They are in the form of "owner/repo".
+	visibility           string
+	selectedRepositories []string
+}
+
+// parseParams parses and validates the configuration of a secret.
+func parseParams(params map[string]any) (*secretParams, error) {
+	c := new(maputils.Context)
+	name := maputils.Must[string](c, params, "name")
+	source := maputils.Must[string](c, params, "source")
+	if err := c.Err(); err != nil {
+		return nil, fmt.Errorf("github: validation failed: %w", err)
+	}
+
+	p, err := parseScopeParams(params)
+	if err != nil {
+		return nil, err
+	}
+	p.name = name
+	p.source = source
+	return p, nil
+}
+
+// parseScopeParams parses and validates the scope of a secret, i.e. the configuration except the name and the source.
+// The scopes support the following applications:
+//
+//	scope         | actions | codespaces | dependabot
+//	--------------+---------+------------+-----------
+//	repository    | yes     | yes        | yes
+//	environment   | yes     | no         | no
+//	organization  | yes     | yes        | yes
+//	user          | no      | yes        | no
+func parseScopeParams(params map[string]any) (*secretParams, error) {
 	c := new(maputils.Context)
 	organization, hasOrganization := maputils.Get[string](c, params, "organization")
 	repository, hasRepository := maputils.Get[string](c, params, "repository")
+	repositories, hasRepositories := maputils.Get[[]any](c, params, "repositories")
+	topics, hasTopics := maputils.Get[[]any](c, params, "topics")
 	environment, hasEnvironment := maputils.Get[string](c, params, "environment")
+	environments, hasEnvironments := maputils.Get[[]any](c, params, "environments")
 	application, hasApplication := maputils.Get[string](c, params, "application")
-	name := maputils.Must[string](c, params, "name")
-	source := maputils.Must[string](c, params, "source")
+	user, _ := maputils.Get[bool](c, params, "user")
+	visibility, hasVisibility := maputils.Get[string](c, params, "visibility")
+	host, _ := maputils.Get[string](c, params, "github_host")
+	createEnvironment, hasCreateEnvironment := maputils.Get[any](c, params, "create_environment")
 	if err := c.Err(); err != nil {
 		return nil, fmt.Errorf("github: validation failed: %w", err)
 	}
 
+	if !hasOrganization && !hasRepository && !hasRepositories && !user {
+		return nil, errors.New("github: one of organization, repository, repositories, or user is required")
+	}
 	if hasOrganization && hasRepository {
 		return nil, errors.New("github: both organization and repository are specified")
 	}
-	if hasEnvironment && hasApplication {
-		return nil, errors.New("github: both environment and application are specified")
+	if user && (hasOrganization || hasRepository) {
+		return nil, errors.New("github: user is specified with organization or repository")
+	}
+	if hasRepository && hasRepositories {
+		return nil, errors.New("github: both repository and repositories are specified")
+	}
+	if hasEnvironment && hasEnvironments {
+		return nil, errors.New("github: both environment and environments are specified")
+	}
+	if hasOrganization && (hasEnvironment || hasEnvironments) {
+		return nil, errors.New("github: environments are not available for organization secrets")
+	}
+	if user && (hasEnvironment || hasEnvironments) {
+		return nil, errors.New("github: environments are not available for user secrets")
+	}
+	if user && hasTopics {
+		return nil, errors.New("github: topics are not available for user secrets")
+	}
+	if hasTopics && !hasRepositories {
+		return nil, errors.New("github: topics require repositories")
+	}
+	if hasCreateEnvironment && !hasEnvironment && !hasEnvironments {
+		return nil, errors.New("github: create_environment requires environment")
 	}
 
 	var app services.GitHubApplication
@@ -74,25 +203,456 @@ func (b *Backend) Plan(ctx context.Context, params map[string]any) ([]backends.P
 			return nil, fmt.Errorf("github: unknown application %q", application)
 		}
 		app = services.GitHubApplicationActions
+		if user {
+			app = services.GitHubApplicationCodespaces
+		}
+	}
+	if (hasEnvironment || hasEnvironments) && app != services.GitHubApplicationActions {
+		return nil, fmt.Errorf("github: environment secrets are available only for actions, but got %s", app)
+	}
+	if user && app != services.GitHubApplicationCodespaces {
+		return nil, fmt.Errorf("github: user secrets are available only for codespaces, but got %s", app)
+	}
+
+	repoNames, err := stringList("repositories", repositories)
+	if err != nil {
+		return nil, err
+	}
+	topicNames, err := stringList("topics", topics)
+	if err != nil {
+		return nil, err
+	}
+	envNames, err := stringList("environments", environments)
+	if err != nil {
+		return nil, err
+	}
+	if hasEnvironment {
+		envNames = []string{environment}
+	}
+	envOpts, err := parseEnvOptions(createEnvironment)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &secretParams{
+		app:          app,
+		organization: organization,
+		user:         user,
+		host:         host,
+		topics:       topicNames,
+		environments: envNames,
+
+		createEnvironment: envOpts,
+	}
+
+	if !hasOrganization && hasVisibility {
+		return nil, errors.New("github: visibility is available only for organization secrets")
+	}
+
+	if user {
+		// the repositories of user secrets are the repositories that can access the secret.
+		for _, repo := range repoNames {
+			owner, r, ok := strings.Cut(repo, "/")
+			if !ok || owner == "" || r == "" {
+				return nil, fmt.Errorf("github: invalid repository name %q", repo)
+			}
+		}
+		if hasRepositories {
+			p.selectedRepositories = repoNames
+		}
+		return p, nil
 	}
 
-	if hasOrganization {
-		return b.planOrgSecret(ctx, app, organization, name, source)
+	if !hasOrganization {
+		if hasRepository {
+			repoNames = []string{repository}
+		}
+		for _, repo := range repoNames {
+			owner, r, ok := strings.Cut(repo, "/")
+			if !ok || owner == "" || r == "" {
+				return nil, fmt.Errorf("github: invalid repository name %q", repo)
+			}
+			if _, err := path.Match(r, ""); err != nil {
+				return nil, fmt.Errorf("github: invalid repository pattern %q: %w", repo, err)
+			}
+		}
+		p.repositories = repoNames
+		return p, nil
 	}
 
-	if hasRepository {
-		owner, repo, ok := strings.Cut(repository, "/")
+	// the repositories of organization secrets are the repositories that can access the secret.
+	if hasTopics {
+		return nil, errors.New("github: topics are not available for organization secrets")
+	}
+	switch visibility {
+	case "", "all", "private", "selected":
+	default:
+		return nil, fmt.Errorf("github: unknown visibility %q", visibility)
+	}
+	if hasRepositories {
+		if !hasVisibility {
+			visibility = "selected"
+		}
+		if visibility != "selected" {
+			return nil, fmt.Errorf("github: repositories require the visibility selected, but got %q", visibility)
+		}
+	}
+	for i, repoName := range repoNames {
+		// accept both "repo" and "org/repo".
+		if owner, r, ok := strings.Cut(repoName, "/"); ok {
+			if !strings.EqualFold(owner, organization) {
+				return nil, fmt.Errorf("github: repository %q is not in organization %s", repoName, organization)
+			}
+			repoName = r
+		}
+		repoNames[i] = organization + "/" + repoName
+	}
+	p.visibility = visibility
+	p.selectedRepositories = repoNames
+	return p, nil
+}
+
+// envOptions is the settings of the environment that op-sync creates.
+type envOptions struct {
+	// reviewers are the required reviewers.
+	// "org/team" means the team, and others mean the users.
+	reviewers []string
+
+	// protectedBranches allows only the protected branches to deploy to the environment.
+	protectedBranches bool
+
+	// branches are the name patterns of the branches that can deploy to the environment.
+	branches []string
+}
+
+// parseEnvOptions parses the create_environment parameter.
+// It is true, false, or the map of the settings.
+func parseEnvOptions(v any) (*envOptions, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if !v {
+			return nil, nil
+		}
+		return &envOptions{}, nil
+	case map[string]any:
+		c := new(maputils.Context)
+		reviewers, _ := maputils.Get[[]any](c, v, "reviewers")
+		protectedBranches, _ := maputils.Get[bool](c, v, "protected_branches")
+		branches, hasBranches := maputils.Get[[]any](c, v, "deployment_branches")
+		if err := c.Err(); err != nil {
+			return nil, fmt.Errorf("github: validation of create_environment failed: %w", err)
+		}
+		if protectedBranches && hasBranches {
+			return nil, errors.New("github: both protected_branches and deployment_branches are specified")
+		}
+		reviewerNames, err := stringList("reviewers", reviewers)
+		if err != nil {
+			return nil, err
+		}
+		branchNames, err := stringList("deployment_branches", branches)
+		if err != nil {
+			return nil, err
+		}
+		return &envOptions{
+			reviewers:         reviewerNames,
+			protectedBranches: protectedBranches,
+			branches:          branchNames,
+		}, nil
+	}
+	return nil, fmt.Errorf("github: invalid create_environment %v", v)
+}
+
+// stringList converts the list in the configuration to a list of strings.
+func stringList(key string, list []any) ([]string, error) {
+	ret := make([]string, 0, len(list))
+	for _, v := range list {
+		s, ok := v.(string)
 		if !ok {
-			return nil, fmt.Errorf("github: invalid repository name %q", repository)
+			return nil, fmt.Errorf("github: invalid value %v in %s", v, key)
 		}
-		if hasEnvironment {
-			return b.planEnvSecret(ctx, owner, repo, environment, name, source)
-		} else {
-			return b.planRepoSecret(ctx, app, owner, repo, name, source)
+		ret = append(ret, s)
+	}
+	return ret, nil
+}
+
+// scope is the place where secrets are stored.
+type scope struct {
+	id services.GitHubIdentity
+
+	// app is empty for environment secrets.
+	app   services.GitHubApplication
+	org   string
+	owner string
+	repo  string
+	env   string
+
+	// user means the Codespaces secrets of the authenticated user.
+	user bool
+}
+
+// key returns the scope with the case-insensitive names in lower case.
+// The keys of the scopes of the same place are equal even if the config file spells them differently.
+func (s scope) key() scope {
+	s.org = strings.ToLower(s.org)
+	s.owner = strings.ToLower(s.owner)
+	s.repo = strings.ToLower(s.repo)
+	return s
+}
+
+// scopes returns the places where the secret is stored.
+// The repositories are expanded into one scope for each repository and environment.
+func (b *Backend) scopes(ctx context.Context, p *secretParams) ([]scope, error) {
+	id := services.GitHubIdentityFromContext(ctx).WithHost(p.host)
+	ctx = services.WithGitHubIdentity(ctx, id)
+
+	if p.organization != "" {
+		return []scope{{id: id, app: p.app, org: p.organization}}, nil
+	}
+	if p.user {
+		return []scope{{id: id, app: p.app, user: true}}, nil
+	}
+
+	repos, err := b.expandRepositories(ctx, p.repositories, p.topics)
+	if err != nil {
+		return nil, err
+	}
+	var ret []scope
+	for _, repo := range repos {
+		if len(p.environments) == 0 {
+			ret = append(ret, scope{id: id, app: p.app, owner: repo.owner, repo: repo.repo})
+			continue
+		}
+		for _, env := range p.environments {
+			ret = append(ret, scope{id: id, owner: repo.owner, repo: repo.repo, env: env})
 		}
 	}
+	return ret, nil
+}
 
-	return []backends.Plan{}, nil
+type repoName struct {
+	owner string
+	repo  string
+}
+
+// expandRepositories expands the glob patterns in the repositories, and filters them by the topics.
+// The repositories without patterns are used as is unless topics are specified.
+// The archived repositories never match the patterns because they are read-only.
+func (b *Backend) expandRepositories(ctx context.Context, repositories, topics []string) ([]repoName, error) {
+	listed := map[string][]*github.Repository{}
+	var ret []repoName
+	add := func(r repoName) {
+		for _, v := range ret {
+			if strings.EqualFold(v.owner, r.owner) && strings.EqualFold(v.repo, r.repo) {
+				return
+			}
+		}
+		ret = append(ret, r)
+	}
+
+	for _, repository := range repositories {
+		owner, pattern, _ := strings.Cut(repository, "/")
+		if !isGlob(pattern) {
+			if len(topics) == 0 {
+				add(repoName{owner: owner, repo: pattern})
+				continue
+			}
+			ghRepo, err := b.opts.GetGitHubRepo(ctx, owner, pattern)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get GitHub repo %s: %w", repository, err)
+			}
+			if hasTopics(ghRepo, topics) {
+				add(repoName{owner: owner, repo: ghRepo.GetName()})
+			}
+			continue
+		}
+
+		repos, ok := listed[strings.ToLower(owner)]
+		if !ok {
+			var err error
+			repos, err = b.opts.ListGitHubRepos(ctx, owner)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list GitHub repos of %s: %w", owner, err)
+			}
+			listed[strings.ToLower(owner)] = repos
+		}
+		for _, ghRepo := range repos {
+			if ghRepo.GetArchived() {
+				continue
+			}
+			// the names of repositories are case-insensitive.
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(ghRepo.GetName())); !ok {
+				continue
+			}
+			if hasTopics(ghRepo, topics) {
+				add(repoName{owner: owner, repo: ghRepo.GetName()})
+			}
+		}
+	}
+	return ret, nil
+}
+
+// isGlob reports whether the pattern has any meta characters of path.Match.
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// hasTopics reports whether the repository has all the topics.
+func hasTopics(repo *github.Repository, topics []string) bool {
+	for _, topic := range topics {
+		if !slices.Contains(repo.Topics, topic) {
+			return false
+		}
+	}
+	return true
+}
+
+func compareScope(a, b scope) int {
+	return cmp.Or(
+		strings.Compare(a.id.Host, b.id.Host),
+		compareBool(a.user, b.user),
+		strings.Compare(a.org, b.org),
+		strings.Compare(a.owner, b.owner),
+		strings.Compare(a.repo, b.repo),
+		strings.Compare(a.env, b.env),
+		strings.Compare(string(a.app), string(b.app)),
+	)
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func (b *Backend) Plan(ctx context.Context, params map[string]any) ([]backends.Plan, error) {
+	p, err := parseParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	ss, err := b.scopes(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	plans := []backends.Plan{}
+	for _, s := range ss {
+		ctx := services.WithGitHubIdentity(ctx, s.id)
+		var ps []backends.Plan
+		switch {
+		case s.org != "":
+			ps, err = b.planOrgSecret(ctx, s.app, s.org, p.name, p.source, p.visibility, p.selectedRepositories)
+		case s.user:
+			ps, err = b.planUserSecret(ctx, p.name, p.source, p.selectedRepositories)
+		case s.env != "":
+			ps, err = b.planEnvSecret(ctx, s.owner, s.repo, s.env, p.name, p.source, p.createEnvironment)
+		default:
+			ps, err = b.planRepoSecret(ctx, s.app, s.owner, s.repo, p.name, p.source)
+		}
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, ps...)
+	}
+	return plans, nil
+}
+
+// Prune plans to delete the secrets that none of cfgs refers to.
+// GitHub secrets have no description, so all secrets in the scopes of PruneScopes
+// are considered as managed by op-sync. The scopes that cfgs refer to are not pruned unless they are listed.
+func (b *Backend) Prune(ctx context.Context, cfgs []map[string]any) ([]backends.Plan, error) {
+	// expand the scopes to prune.
+	pruneScopes := map[scope]struct{}{}
+	for _, params := range b.opts.PruneScopes {
+		p, err := parseScopeParams(params)
+		if err != nil {
+			return nil, fmt.Errorf("github: invalid prune scope: %w", err)
+		}
+		ss, err := b.scopes(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range ss {
+			// the names of GitHub owners and repositories are case-insensitive.
+			pruneScopes[s.key()] = struct{}{}
+		}
+	}
+	if len(pruneScopes) == 0 {
+		return []backends.Plan{}, nil
+	}
+
+	// collect the secrets in the configuration for each scope.
+	names := map[scope]map[string]struct{}{}
+	for _, params := range cfgs {
+		p, err := parseParams(params)
+		if err != nil {
+			return nil, err
+		}
+		ss, err := b.scopes(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range ss {
+			// the names of GitHub owners and repositories are case-insensitive.
+			s = s.key()
+			if _, ok := names[s]; !ok {
+				names[s] = map[string]struct{}{}
+			}
+			// the names of GitHub secrets are case-insensitive.
+			names[s][strings.ToUpper(p.name)] = struct{}{}
+		}
+	}
+
+	plans := []backends.Plan{}
+	for _, s := range slices.SortedFunc(maps.Keys(pruneScopes), compareScope) {
+		ctx := services.WithGitHubIdentity(ctx, s.id)
+		var secrets []*github.Secret
+		var repoID int64
+		var err error
+		switch {
+		case s.org != "":
+			secrets, err = b.opts.ListGitHubOrgSecrets(ctx, s.app, s.org)
+		case s.user:
+			secrets, err = b.opts.ListGitHubUserSecrets(ctx)
+		case s.env != "":
+			var ghRepo *github.Repository
+			ghRepo, err = b.opts.GetGitHubRepo(ctx, s.owner, s.repo)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get GitHub repo: %w", err)
+			}
+			repoID = ghRepo.GetID()
+			secrets, err = b.opts.ListGitHubEnvSecrets(ctx, int(repoID), s.env)
+			if isNotFound(err) {
+				// the environment will be created.
+				continue
+			}
+		default:
+			secrets, err = b.opts.ListGitHubRepoSecrets(ctx, s.app, s.owner, s.repo)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitHub secrets: %w", err)
+		}
+
+		for _, secret := range secrets {
+			if _, ok := names[s][strings.ToUpper(secret.Name)]; ok {
+				continue
+			}
+			plans = append(plans, &PlanDeleteSecret{
+				backend:   b,
+				scope:     s,
+				repoID:    repoID,
+				name:      secret.Name,
+				updatedAt: secret.UpdatedAt.Time,
+			})
+		}
+	}
+	return plans, nil
 }
 
 func isNotFound(err error) bool {
@@ -105,34 +665,30 @@ func (b *Backend) planRepoSecret(ctx context.Context, app services.GitHubApplica
 	if isNotFound(err) {
 		// the secret is not found.
 		// we should create it.
-		return b.newPlanRepoSecret(ctx, app, owner, repo, name, source)
+		return b.newPlanRepoSecret(ctx, app, owner, repo, name, source, nil)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get GitHub repo secret: %w", err)
 	}
 
-	uri, err := op.ParseURI(source)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse source: %w", err)
-	}
-
 	// check the secret is up-to-date
-	opItem, err := b.opts.GetOnePasswordItem(ctx, uri.Vault, uri.Item)
+	target := repoSecretTarget(services.GitHubIdentityFromContext(ctx), app, owner, repo, name)
+	upToDate, err := b.isUpToDate(ctx, target, secret, source)
 	if err != nil {
 		return nil, err
 	}
-
-	if secret.UpdatedAt.After(opItem.UpdatedAt) {
-		// the secret is up-to-date.
-		return []backends.Plan{}, nil
+	if upToDate {
+		return []backends.Plan{backends.NewNoopPlan(target)}, nil
 	}
 
-	return b.newPlanRepoSecret(ctx, app, owner, repo, name, source)
+	return b.newPlanRepoSecret(ctx, app, owner, repo, name, source, secret)
 }
 
-func (b *Backend) newPlanRepoSecret(ctx context.Context, app services.GitHubApplication, owner, repo, name, source string) ([]backends.Plan, error) {
+// newPlanRepoSecret plans to create or update the repository secret.
+// current is the secret on GitHub, or nil if it doesn't exist.
+func (b *Backend) newPlanRepoSecret(ctx context.Context, app services.GitHubApplication, owner, repo, name, source string, current *github.Secret) ([]backends.Plan, error) {
 	// get the public key
-	key, err := b.opts.GetGitHubRepoPublicKey(ctx, owner, repo)
+	key, err := b.opts.GetGitHubRepoPublicKey(ctx, app, owner, repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get GitHub repo public key: %w", err)
 	}
@@ -148,56 +704,70 @@ func (b *Backend) newPlanRepoSecret(ctx context.Context, app services.GitHubAppl
 	if err != nil {
 		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
 	}
+	hash, err := b.hashSecret(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
 
 	return []backends.Plan{
 		&PlanRepoSecret{
 			backend:         b,
+			id:              services.GitHubIdentityFromContext(ctx),
 			app:             app,
 			owner:           owner,
 			repo:            repo,
 			name:            name,
 			keyID:           key.GetKeyID(),
 			encryptedSecret: encryptedSecret,
-			overwrite:       false,
+			hmac:            hash,
+			updatedAt:       updatedAt(current),
+			overwrite:       current != nil,
 		},
 	}, nil
 }
 
-func (b *Backend) planEnvSecret(ctx context.Context, owner, repo, env, name, source string) ([]backends.Plan, error) {
+// planEnvSecret plans the environment secret.
+// If create is not nil, it also plans to create the environment if it doesn't exist.
+func (b *Backend) planEnvSecret(ctx context.Context, owner, repo, env, name, source string, create *envOptions) ([]backends.Plan, error) {
 	ghRepo, err := b.opts.GetGitHubRepo(ctx, owner, repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get GitHub repo: %w", err)
 	}
+	if create != nil {
+		_, err := b.opts.GetGitHubEnv(ctx, owner, repo, env)
+		if isNotFound(err) {
+			return b.newPlanCreateEnv(ctx, ghRepo, env, name, source, create)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GitHub environment: %w", err)
+		}
+	}
 	secret, err := b.opts.GetGitHubEnvSecret(ctx, int(ghRepo.GetID()), env, name)
 	if isNotFound(err) {
 		// the secret is not found.
 		// we should create it.
-		return b.newPlanEnvSecret(ctx, ghRepo, env, name, source)
+		return b.newPlanEnvSecret(ctx, ghRepo, env, name, source, nil)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get GitHub repo secret: %w", err)
 	}
 
-	uri, err := op.ParseURI(source)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse source: %w", err)
-	}
-
 	// check the secret is up-to-date
-	opItem, err := b.opts.GetOnePasswordItem(ctx, uri.Vault, uri.Item)
+	target := envSecretTarget(services.GitHubIdentityFromContext(ctx), owner, repo, env, name)
+	upToDate, err := b.isUpToDate(ctx, target, secret, source)
 	if err != nil {
 		return nil, err
 	}
-
-	if secret.UpdatedAt.After(opItem.UpdatedAt) {
-		// the secret is up-to-date.
-		return []backends.Plan{}, nil
+	if upToDate {
+		return []backends.Plan{backends.NewNoopPlan(target)}, nil
 	}
 
-	return b.newPlanEnvSecret(ctx, ghRepo, env, name, source)
+	return b.newPlanEnvSecret(ctx, ghRepo, env, name, source, secret)
 }
 
-func (b *Backend) newPlanEnvSecret(ctx context.Context, ghRepo *github.Repository, env, name, source string) ([]backends.Plan, error) {
+// newPlanEnvSecret plans to create or update the environment secret.
+// current is the secret on GitHub, or nil if it doesn't exist.
+func (b *Backend) newPlanEnvSecret(ctx context.Context, ghRepo *github.Repository, env, name, source string, current *github.Secret) ([]backends.Plan, error) {
 	// get the public key
 	key, err := b.opts.GetGitHubEnvPublicKey(ctx, int(ghRepo.GetID()), env)
 	if err != nil {
@@ -215,10 +785,15 @@ func (b *Backend) newPlanEnvSecret(ctx context.Context, ghRepo *github.Repositor
 	if err != nil {
 		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
 	}
+	hash, err := b.hashSecret(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
 
 	return []backends.Plan{
 		&PlanEnvSecret{
 			backend:         b,
+			id:              services.GitHubIdentityFromContext(ctx),
 			owner:           ghRepo.GetOwner().GetLogin(),
 			repo:            ghRepo.GetName(),
 			repoID:          ghRepo.GetID(),
@@ -226,38 +801,119 @@ func (b *Backend) newPlanEnvSecret(ctx context.Context, ghRepo *github.Repositor
 			name:            name,
 			keyID:           key.GetKeyID(),
 			encryptedSecret: encryptedSecret,
-			overwrite:       false,
+			hmac:            hash,
+			updatedAt:       updatedAt(current),
+			overwrite:       current != nil,
 		},
 	}, nil
 }
 
-func (b *Backend) planOrgSecret(ctx context.Context, app services.GitHubApplication, org, name, source string) ([]backends.Plan, error) {
+// newPlanCreateEnv plans to create the environment, and then to create the secret in it.
+// The secret is encrypted when it is applied because the environment has no public key yet.
+func (b *Backend) newPlanCreateEnv(ctx context.Context, ghRepo *github.Repository, env, name, source string, opts *envOptions) ([]backends.Plan, error) {
+	owner, repo := ghRepo.GetOwner().GetLogin(), ghRepo.GetName()
+	reviewers, err := b.reviewers(ctx, opts.reviewers)
+	if err != nil {
+		return nil, err
+	}
+
+	// get the secret from 1password
+	secret, err := b.opts.ReadOnePassword(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret from 1password: %w", err)
+	}
+	hash, err := b.hashSecret(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	id := services.GitHubIdentityFromContext(ctx)
+	return []backends.Plan{
+		&PlanCreateEnv{
+			backend:           b,
+			id:                id,
+			owner:             owner,
+			repo:              repo,
+			env:               env,
+			reviewers:         reviewers,
+			protectedBranches: opts.protectedBranches,
+			branches:          opts.branches,
+		},
+		&PlanEnvSecret{
+			backend: b,
+			id:      id,
+			owner:   owner,
+			repo:    repo,
+			repoID:  ghRepo.GetID(),
+			env:     env,
+			name:    name,
+			secret:  secret,
+			hmac:    hash,
+		},
+	}, nil
+}
+
+// reviewers resolves the names of the reviewers to their IDs.
+// "org/team" means the team, and others mean the users.
+func (b *Backend) reviewers(ctx context.Context, names []string) ([]*github.EnvReviewers, error) {
+	ret := make([]*github.EnvReviewers, 0, len(names))
+	for _, name := range names {
+		if org, slug, ok := strings.Cut(name, "/"); ok {
+			team, err := b.opts.GetGitHubTeam(ctx, org, slug)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get GitHub team %s: %w", name, err)
+			}
+			ret = append(ret, &github.EnvReviewers{
+				Type: github.String("Team"),
+				ID:   github.Int64(team.GetID()),
+			})
+			continue
+		}
+		user, err := b.opts.GetGitHubUserByLogin(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GitHub user %s: %w", name, err)
+		}
+		ret = append(ret, &github.EnvReviewers{
+			Type: github.String("User"),
+			ID:   github.Int64(user.GetID()),
+		})
+	}
+	return ret, nil
+}
+
+// planOrgSecret plans the organization secret.
+// visibility and repositories are the desired access policy. The current policy is kept if visibility is empty.
+func (b *Backend) planOrgSecret(ctx context.Context, app services.GitHubApplication, org, name, source, visibility string, repositories []string) ([]backends.Plan, error) {
+	var wantReposID []int64
+	if visibility == "selected" {
+		var err error
+		wantReposID, err = b.reposID(ctx, repositories)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	secret, err := b.opts.GetGitHubOrgSecret(ctx, app, org, name)
 	if isNotFound(err) {
 		// the secret is not found.
 		// we should create it.
-		return b.newPlanOrgSecret(ctx, app, org, name, source, false, "selected", []int64{})
+		if visibility == "" {
+			visibility = "selected"
+			wantReposID = []int64{}
+		}
+		return b.newPlanOrgSecret(ctx, app, org, name, source, nil, visibility, wantReposID, false)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get GitHub org secret: %w", err)
 	}
 
-	uri, err := op.ParseURI(source)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse source: %w", err)
-	}
-
 	// check the secret is up-to-date
-	opItem, err := b.opts.GetOnePasswordItem(ctx, uri.Vault, uri.Item)
+	target := orgSecretTarget(services.GitHubIdentityFromContext(ctx), app, org, name)
+	upToDate, err := b.isUpToDate(ctx, target, secret, source)
 	if err != nil {
 		return nil, err
 	}
 
-	if secret.UpdatedAt.After(opItem.UpdatedAt) {
-		// the secret is up-to-date.
-		return []backends.Plan{}, nil
-	}
-
 	var reposID []int64
 	if secret.Visibility == "selected" {
 		reposID, err = b.opts.ListGitHubReposIDForOrgSecret(ctx, app, org, name)
@@ -265,12 +921,141 @@ func (b *Backend) planOrgSecret(ctx context.Context, app services.GitHubApplicat
 			return nil, err
 		}
 	}
-	return b.newPlanOrgSecret(ctx, app, org, name, source, true, secret.Visibility, reposID)
+	if visibility == "" {
+		// keep the current access policy.
+		visibility, wantReposID = secret.Visibility, reposID
+	}
+
+	// check the access policy is up-to-date
+	accessUpToDate := visibility == secret.Visibility && sameIDs(wantReposID, reposID)
+	if upToDate && accessUpToDate {
+		return []backends.Plan{backends.NewNoopPlan(target)}, nil
+	}
+
+	// GitHub has no API to change the visibility only, so put the secret again.
+	return b.newPlanOrgSecret(ctx, app, org, name, source, secret, visibility, wantReposID, upToDate)
+}
+
+// reposID resolves the names of the repositories in the form of "owner/repo" to their IDs.
+func (b *Backend) reposID(ctx context.Context, repositories []string) ([]int64, error) {
+	ids := make([]int64, 0, len(repositories))
+	for _, repository := range repositories {
+		owner, repo, _ := strings.Cut(repository, "/")
+		ghRepo, err := b.opts.GetGitHubRepo(ctx, owner, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GitHub repo %s: %w", repository, err)
+		}
+		if !slices.Contains(ids, ghRepo.GetID()) {
+			ids = append(ids, ghRepo.GetID())
+		}
+	}
+	return ids, nil
+}
+
+// planUserSecret plans the Codespaces secret of the authenticated user.
+// repositories are the repositories that can access the secret. The current selection is kept if it is nil.
+func (b *Backend) planUserSecret(ctx context.Context, name, source string, repositories []string) ([]backends.Plan, error) {
+	var wantReposID []int64
+	if repositories != nil {
+		var err error
+		wantReposID, err = b.reposID(ctx, repositories)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	secret, err := b.opts.GetGitHubUserSecret(ctx, name)
+	if isNotFound(err) {
+		// the secret is not found.
+		// we should create it.
+		return b.newPlanUserSecret(ctx, name, source, nil, wantReposID, false)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub user secret: %w", err)
+	}
+
+	// check the secret is up-to-date
+	target := userSecretTarget(services.GitHubIdentityFromContext(ctx), name)
+	upToDate, err := b.isUpToDate(ctx, target, secret, source)
+	if err != nil {
+		return nil, err
+	}
+
+	reposID, err := b.opts.ListGitHubReposIDForUserSecret(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if repositories == nil {
+		// keep the current selection.
+		wantReposID = reposID
+	}
+
+	// check the selected repositories are up-to-date
+	accessUpToDate := sameIDs(wantReposID, reposID)
+	if upToDate && accessUpToDate {
+		return []backends.Plan{backends.NewNoopPlan(target)}, nil
+	}
+
+	// the selected repositories are updated with the secret.
+	return b.newPlanUserSecret(ctx, name, source, secret, wantReposID, upToDate)
+}
+
+// newPlanUserSecret plans to create or update the Codespaces secret of the authenticated user.
+// current is the secret on GitHub, or nil if it doesn't exist.
+// accessOnly means that the value is up-to-date, and only the selected repositories differ.
+func (b *Backend) newPlanUserSecret(ctx context.Context, name, source string, current *github.Secret, reposID []int64, accessOnly bool) ([]backends.Plan, error) {
+	// get the public key
+	key, err := b.opts.GetGitHubUserPublicKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub user public key: %w", err)
+	}
+
+	// get the secret from 1password
+	secret, err := b.opts.ReadOnePassword(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret from 1password: %w", err)
+	}
+
+	// encrypt the secret
+	encryptedSecret, err := encryptSecret(key.GetKey(), secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	hash, err := b.hashSecret(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return []backends.Plan{
+		&PlanUserSecret{
+			backend:         b,
+			id:              services.GitHubIdentityFromContext(ctx),
+			name:            name,
+			reposID:         reposID,
+			keyID:           key.GetKeyID(),
+			encryptedSecret: encryptedSecret,
+			hmac:            hash,
+			updatedAt:       updatedAt(current),
+			overwrite:       current != nil,
+			accessOnly:      accessOnly,
+		},
+	}, nil
 }
 
-func (b *Backend) newPlanOrgSecret(ctx context.Context, app services.GitHubApplication, organization, name, source string, overwrite bool, visibility string, reposID []int64) ([]backends.Plan, error) {
+// sameIDs reports whether a and b have the same IDs ignoring the order.
+func sameIDs(a, b []int64) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// newPlanOrgSecret plans to create or update the organization secret.
+// current is the secret on GitHub, or nil if it doesn't exist.
+// accessOnly means that the value is up-to-date, and only the access policy differs.
+func (b *Backend) newPlanOrgSecret(ctx context.Context, app services.GitHubApplication, organization, name, source string, current *github.Secret, visibility string, reposID []int64, accessOnly bool) ([]backends.Plan, error) {
 	// get the public key
-	key, err := b.opts.GetGitHubOrgPublicKey(ctx, organization)
+	key, err := b.opts.GetGitHubOrgPublicKey(ctx, app, organization)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get GitHub org public key: %w", err)
 	}
@@ -286,10 +1071,15 @@ func (b *Backend) newPlanOrgSecret(ctx context.Context, app services.GitHubAppli
 	if err != nil {
 		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
 	}
+	hash, err := b.hashSecret(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
 
 	return []backends.Plan{
 		&PlanOrgSecret{
 			backend:         b,
+			id:              services.GitHubIdentityFromContext(ctx),
 			app:             app,
 			org:             organization,
 			name:            name,
@@ -297,11 +1087,258 @@ func (b *Backend) newPlanOrgSecret(ctx context.Context, app services.GitHubAppli
 			reposID:         reposID,
 			keyID:           key.GetKeyID(),
 			encryptedSecret: encryptedSecret,
-			overwrite:       overwrite,
+			hmac:            hash,
+			updatedAt:       updatedAt(current),
+			overwrite:       current != nil,
+			accessOnly:      accessOnly,
 		},
 	}, nil
 }
 
+// isUpToDate reports whether the secret on GitHub has the value of source.
+// If the state is available, it compares the keyed hash of the value recorded in the state,
+// and the secret must not be updated after op-sync recorded it.
+// Otherwise, it compares the update time of the secret and the 1Password item.
+func (b *Backend) isUpToDate(ctx context.Context, target string, current *github.Secret, source string) (bool, error) {
+	if !b.hasState() {
+		uri, err := op.ParseURI(source)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse source: %w", err)
+		}
+		opItem, err := b.opts.GetOnePasswordItem(ctx, uri.Vault, uri.Item)
+		if err != nil {
+			return false, err
+		}
+		return current.UpdatedAt.After(opItem.UpdatedAt), nil
+	}
+
+	state, err := b.opts.GetState(ctx, target)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the state: %w", err)
+	}
+	if state == nil {
+		// we don't know the value of the secret.
+		return false, nil
+	}
+	if !state.UpdatedAt.Equal(current.UpdatedAt.Time) {
+		// someone else updated the secret.
+		return false, nil
+	}
+	secret, err := b.opts.ReadOnePassword(ctx, source)
+	if err != nil {
+		return false, fmt.Errorf("failed to read secret from 1password: %w", err)
+	}
+	hash, err := b.opts.HashSecret(ctx, secret)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal([]byte(hash), []byte(state.HMAC)), nil
+}
+
+func (b *Backend) hasState() bool {
+	return b.opts.SecretHasher != nil && b.opts.StateGetter != nil && b.opts.StatePutter != nil
+}
+
+// hashSecret returns the keyed hash of the secret.
+// It returns an empty string if the state is not available.
+func (b *Backend) hashSecret(ctx context.Context, secret []byte) (string, error) {
+	if !b.hasState() {
+		return "", nil
+	}
+	return b.opts.HashSecret(ctx, secret)
+}
+
+// recordState records the keyed hash of the secret that op-sync has just written.
+func (b *Backend) recordState(ctx context.Context, target, hash string, secret *github.Secret, err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to get the secret to record the state: %w", err)
+	}
+	return b.opts.PutState(ctx, target, &services.SecretState{
+		HMAC:      hash,
+		UpdatedAt: secret.UpdatedAt.Time,
+	})
+}
+
+func repoSecretTarget(id services.GitHubIdentity, app services.GitHubApplication, owner, repo, name string) string {
+	return fmt.Sprintf("%srepos/%s/%s/%s/secrets/%s", id.TargetPrefix(), owner, repo, app, name)
+}
+
+func envTarget(id services.GitHubIdentity, owner, repo, env string) string {
+	return fmt.Sprintf("%srepos/%s/%s/environments/%s", id.TargetPrefix(), owner, repo, env)
+}
+
+func envSecretTarget(id services.GitHubIdentity, owner, repo, env, name string) string {
+	return fmt.Sprintf("%srepos/%s/%s/environments/%s/secrets/%s", id.TargetPrefix(), owner, repo, env, name)
+}
+
+func orgSecretTarget(id services.GitHubIdentity, app services.GitHubApplication, org, name string) string {
+	return fmt.Sprintf("%sorgs/%s/%s/secrets/%s", id.TargetPrefix(), org, app, name)
+}
+
+func userSecretTarget(id services.GitHubIdentity, name string) string {
+	return fmt.Sprintf("%suser/codespaces/secrets/%s", id.TargetPrefix(), name)
+}
+
+// updatedAt returns the time when the secret was updated.
+// It returns the zero time if secret is nil.
+func updatedAt(secret *github.Secret) time.Time {
+	if secret == nil {
+		return time.Time{}
+	}
+	return secret.UpdatedAt.Time
+}
+
+// verifySecret checks that the secret has not been changed since the plan was made.
+func verifySecret(secret *github.Secret, err error, overwrite bool, updatedAt time.Time) error {
+	if isNotFound(err) {
+		if overwrite {
+			return fmt.Errorf("the secret was removed: %w", backends.ErrStalePlan)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !overwrite || !secret.UpdatedAt.Time.Equal(updatedAt) {
+		return fmt.Errorf("the secret was updated at %s: %w", secret.UpdatedAt.Time, backends.ErrStalePlan)
+	}
+	return nil
+}
+
+const (
+	planKindRepoSecret = "repo_secret"
+	planKindEnvSecret  = "env_secret"
+	planKindOrgSecret  = "org_secret"
+	planKindCreateEnv  = "create_env"
+	planKindUserSecret = "user_secret"
+
+	planKindDeleteSecret = "delete_secret"
+)
+
+// planJSON is the serialized form of the plans.
+type planJSON struct {
+	Kind            string                     `json:"kind"`
+	Identity        services.GitHubIdentity    `json:"identity,omitzero"`
+	App             services.GitHubApplication `json:"app,omitempty"`
+	Owner           string                     `json:"owner,omitempty"`
+	Repo            string                     `json:"repo,omitempty"`
+	RepoID          int64                      `json:"repo_id,omitempty"`
+	Env             string                     `json:"env,omitempty"`
+	Org             string                     `json:"org,omitempty"`
+	User            bool                       `json:"user,omitempty"`
+	Name            string                     `json:"name"`
+	KeyID           string                     `json:"key_id,omitempty"`
+	EncryptedSecret string                     `json:"encrypted_secret,omitempty"`
+	Secret          []byte                     `json:"secret,omitempty"`
+	Visibility      string                     `json:"visibility,omitempty"`
+	ReposID         []int64                    `json:"repos_id,omitempty"`
+	HMAC            string                     `json:"hmac,omitempty"`
+	UpdatedAt       time.Time                  `json:"updated_at"`
+	Overwrite       bool                       `json:"overwrite"`
+	AccessOnly      bool                       `json:"access_only,omitempty"`
+
+	// the settings of the environment.
+	Reviewers         []*github.EnvReviewers `json:"reviewers,omitempty"`
+	ProtectedBranches bool                   `json:"protected_branches,omitempty"`
+	Branches          []string               `json:"branches,omitempty"`
+}
+
+func (b *Backend) UnmarshalPlan(data []byte) (backends.Plan, error) {
+	var v planJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("github: failed to unmarshal the plan: %w", err)
+	}
+	switch v.Kind {
+	case planKindRepoSecret:
+		return &PlanRepoSecret{
+			backend:         b,
+			id:              v.Identity,
+			app:             v.App,
+			owner:           v.Owner,
+			repo:            v.Repo,
+			name:            v.Name,
+			keyID:           v.KeyID,
+			encryptedSecret: v.EncryptedSecret,
+			hmac:            v.HMAC,
+			updatedAt:       v.UpdatedAt,
+			overwrite:       v.Overwrite,
+		}, nil
+	case planKindEnvSecret:
+		return &PlanEnvSecret{
+			backend:         b,
+			id:              v.Identity,
+			owner:           v.Owner,
+			repo:            v.Repo,
+			repoID:          v.RepoID,
+			env:             v.Env,
+			name:            v.Name,
+			keyID:           v.KeyID,
+			encryptedSecret: v.EncryptedSecret,
+			secret:          v.Secret,
+			hmac:            v.HMAC,
+			updatedAt:       v.UpdatedAt,
+			overwrite:       v.Overwrite,
+		}, nil
+	case planKindCreateEnv:
+		return &PlanCreateEnv{
+			backend:           b,
+			id:                v.Identity,
+			owner:             v.Owner,
+			repo:              v.Repo,
+			env:               v.Env,
+			reviewers:         v.Reviewers,
+			protectedBranches: v.ProtectedBranches,
+			branches:          v.Branches,
+		}, nil
+	case planKindOrgSecret:
+		return &PlanOrgSecret{
+			backend:         b,
+			id:              v.Identity,
+			app:             v.App,
+			org:             v.Org,
+			name:            v.Name,
+			keyID:           v.KeyID,
+			encryptedSecret: v.EncryptedSecret,
+			visibility:      v.Visibility,
+			reposID:         v.ReposID,
+			hmac:            v.HMAC,
+			updatedAt:       v.UpdatedAt,
+			overwrite:       v.Overwrite,
+			accessOnly:      v.AccessOnly,
+		}, nil
+	case planKindUserSecret:
+		return &PlanUserSecret{
+			backend:         b,
+			id:              v.Identity,
+			name:            v.Name,
+			keyID:           v.KeyID,
+			encryptedSecret: v.EncryptedSecret,
+			reposID:         v.ReposID,
+			hmac:            v.HMAC,
+			updatedAt:       v.UpdatedAt,
+			overwrite:       v.Overwrite,
+			accessOnly:      v.AccessOnly,
+		}, nil
+	case planKindDeleteSecret:
+		return &PlanDeleteSecret{
+			backend: b,
+			scope: scope{
+				id:    v.Identity,
+				app:   v.App,
+				org:   v.Org,
+				owner: v.Owner,
+				repo:  v.Repo,
+				env:   v.Env,
+				user:  v.User,
+			},
+			repoID:    v.RepoID,
+			name:      v.Name,
+			updatedAt: v.UpdatedAt,
+		}, nil
+	}
+	return nil, fmt.Errorf("github: unknown plan kind %q", v.Kind)
+}
+
 func encryptSecret(pubKey string, secret []byte) (string, error) {
 	decodedPubKey, err := base64.StdEncoding.DecodeString(pubKey)
 	if err != nil {
@@ -322,39 +1359,102 @@ func encryptSecret(pubKey string, secret []byte) (string, error) {
 	return base64.StdEncoding.EncodeToString(encrypted), nil
 }
 
+func secretAction(overwrite bool) backends.Action {
+	if overwrite {
+		return backends.ActionUpdate
+	}
+	return backends.ActionCreate
+}
+
+func secretReason(overwrite, hasState bool) string {
+	if overwrite && hasState {
+		return "the value of the secret differs from the state"
+	}
+	if overwrite {
+		return "the 1Password item was updated after the secret"
+	}
+	return "the secret does not exist"
+}
+
 var _ backends.Plan = (*PlanRepoSecret)(nil)
 
 type PlanRepoSecret struct {
 	backend         *Backend
+	id              services.GitHubIdentity
 	app             services.GitHubApplication
 	owner           string
 	repo            string
 	name            string
 	keyID           string
 	encryptedSecret string
+	hmac            string
+	updatedAt       time.Time
 	overwrite       bool
 }
 
 func (p *PlanRepoSecret) Preview() string {
 	if p.overwrite {
-		return fmt.Sprintf("secret %q in %s/%s will be updated", p.name, p.owner, p.repo)
+		return fmt.Sprintf("secret %q in %s%s/%s will be updated", p.name, p.id.TargetPrefix(), p.owner, p.repo)
 	}
-	return fmt.Sprintf("secret %q in %s/%s will be created", p.name, p.owner, p.repo)
+	return fmt.Sprintf("secret %q in %s%s/%s will be created", p.name, p.id.TargetPrefix(), p.owner, p.repo)
+}
+
+func (p *PlanRepoSecret) Action() backends.Action {
+	return secretAction(p.overwrite)
+}
+
+func (p *PlanRepoSecret) Target() string {
+	return repoSecretTarget(p.id, p.app, p.owner, p.repo, p.name)
+}
+
+func (p *PlanRepoSecret) Reason() string {
+	return secretReason(p.overwrite, p.hmac != "")
+}
+
+func (p *PlanRepoSecret) Verify(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
+	secret, err := p.backend.opts.GetGitHubRepoSecret(ctx, p.app, p.owner, p.repo, p.name)
+	return verifySecret(secret, err, p.overwrite, p.updatedAt)
+}
+
+func (p *PlanRepoSecret) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Kind:            planKindRepoSecret,
+		Identity:        p.id,
+		App:             p.app,
+		Owner:           p.owner,
+		Repo:            p.repo,
+		Name:            p.name,
+		KeyID:           p.keyID,
+		EncryptedSecret: p.encryptedSecret,
+		HMAC:            p.hmac,
+		UpdatedAt:       p.updatedAt,
+		Overwrite:       p.overwrite,
+	})
 }
 
 func (p *PlanRepoSecret) Apply(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
 	eSecret := &github.EncryptedSecret{
 		Name:           p.name,
 		KeyID:          p.keyID,
 		EncryptedValue: p.encryptedSecret,
 	}
-	return p.backend.opts.CreateGitHubRepoSecret(ctx, p.app, p.owner, p.repo, eSecret)
+	if err := p.backend.opts.CreateGitHubRepoSecret(ctx, p.app, p.owner, p.repo, eSecret); err != nil {
+		return err
+	}
+	if p.hmac == "" {
+		return nil
+	}
+	secret, err := p.backend.opts.GetGitHubRepoSecret(ctx, p.app, p.owner, p.repo, p.name)
+	return p.backend.recordState(ctx, p.Target(), p.hmac, secret, err)
 }
 
 var _ backends.Plan = (*PlanEnvSecret)(nil)
 
 type PlanEnvSecret struct {
 	backend         *Backend
+	id              services.GitHubIdentity
 	owner           string
 	repo            string
 	repoID          int64
@@ -362,29 +1462,176 @@ type PlanEnvSecret struct {
 	name            string
 	keyID           string
 	encryptedSecret string
-	overwrite       bool
+
+	// secret is the plain secret if the environment doesn't exist when the plan was made.
+	// It is encrypted with the public key of the environment when the plan is applied.
+	secret []byte
+
+	hmac      string
+	updatedAt time.Time
+	overwrite bool
 }
 
 func (p *PlanEnvSecret) Preview() string {
 	if p.overwrite {
-		return fmt.Sprintf("secret %q in %s/%s environment %s will be updated", p.name, p.owner, p.repo, p.env)
+		return fmt.Sprintf("secret %q in %s%s/%s environment %s will be updated", p.name, p.id.TargetPrefix(), p.owner, p.repo, p.env)
 	}
-	return fmt.Sprintf("secret %q in %s/%s environment %s will be created", p.name, p.owner, p.repo, p.env)
+	return fmt.Sprintf("secret %q in %s%s/%s environment %s will be created", p.name, p.id.TargetPrefix(), p.owner, p.repo, p.env)
+}
+
+func (p *PlanEnvSecret) Action() backends.Action {
+	return secretAction(p.overwrite)
+}
+
+func (p *PlanEnvSecret) Target() string {
+	return envSecretTarget(p.id, p.owner, p.repo, p.env, p.name)
+}
+
+func (p *PlanEnvSecret) Reason() string {
+	return secretReason(p.overwrite, p.hmac != "")
+}
+
+func (p *PlanEnvSecret) Verify(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
+	secret, err := p.backend.opts.GetGitHubEnvSecret(ctx, int(p.repoID), p.env, p.name)
+	return verifySecret(secret, err, p.overwrite, p.updatedAt)
+}
+
+func (p *PlanEnvSecret) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Kind:            planKindEnvSecret,
+		Identity:        p.id,
+		Owner:           p.owner,
+		Repo:            p.repo,
+		RepoID:          p.repoID,
+		Env:             p.env,
+		Name:            p.name,
+		KeyID:           p.keyID,
+		EncryptedSecret: p.encryptedSecret,
+		Secret:          p.secret,
+		HMAC:            p.hmac,
+		UpdatedAt:       p.updatedAt,
+		Overwrite:       p.overwrite,
+	})
 }
 
 func (p *PlanEnvSecret) Apply(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
 	eSecret := &github.EncryptedSecret{
 		Name:           p.name,
 		KeyID:          p.keyID,
 		EncryptedValue: p.encryptedSecret,
 	}
-	return p.backend.opts.CreateGitHubEnvSecret(ctx, int(p.repoID), p.env, eSecret)
+	if p.secret != nil {
+		key, err := p.backend.opts.GetGitHubEnvPublicKey(ctx, int(p.repoID), p.env)
+		if err != nil {
+			return fmt.Errorf("failed to get GitHub environment public key: %w", err)
+		}
+		encryptedSecret, err := encryptSecret(key.GetKey(), p.secret)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt secret: %w", err)
+		}
+		eSecret.KeyID = key.GetKeyID()
+		eSecret.EncryptedValue = encryptedSecret
+	}
+	if err := p.backend.opts.CreateGitHubEnvSecret(ctx, int(p.repoID), p.env, eSecret); err != nil {
+		return err
+	}
+	if p.hmac == "" {
+		return nil
+	}
+	secret, err := p.backend.opts.GetGitHubEnvSecret(ctx, int(p.repoID), p.env, p.name)
+	return p.backend.recordState(ctx, p.Target(), p.hmac, secret, err)
+}
+
+var _ backends.Plan = (*PlanCreateEnv)(nil)
+
+// PlanCreateEnv is a plan to create the environment of the repository.
+type PlanCreateEnv struct {
+	backend           *Backend
+	id                services.GitHubIdentity
+	owner             string
+	repo              string
+	env               string
+	reviewers         []*github.EnvReviewers
+	protectedBranches bool
+	branches          []string
+}
+
+func (p *PlanCreateEnv) Preview() string {
+	return fmt.Sprintf("environment %s in %s%s/%s will be created", p.env, p.id.TargetPrefix(), p.owner, p.repo)
+}
+
+func (p *PlanCreateEnv) Action() backends.Action {
+	return backends.ActionCreate
+}
+
+func (p *PlanCreateEnv) Target() string {
+	return envTarget(p.id, p.owner, p.repo, p.env)
+}
+
+func (p *PlanCreateEnv) Reason() string {
+	return "the environment does not exist"
+}
+
+func (p *PlanCreateEnv) Verify(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
+	_, err := p.backend.opts.GetGitHubEnv(ctx, p.owner, p.repo, p.env)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("the environment was created: %w", backends.ErrStalePlan)
+}
+
+func (p *PlanCreateEnv) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Kind:              planKindCreateEnv,
+		Identity:          p.id,
+		Owner:             p.owner,
+		Repo:              p.repo,
+		Env:               p.env,
+		Reviewers:         p.reviewers,
+		ProtectedBranches: p.protectedBranches,
+		Branches:          p.branches,
+	})
+}
+
+func (p *PlanCreateEnv) Apply(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
+	opts := &github.CreateUpdateEnvironment{
+		Reviewers: p.reviewers,
+	}
+	switch {
+	case p.protectedBranches:
+		opts.DeploymentBranchPolicy = &github.BranchPolicy{
+			ProtectedBranches:    github.Bool(true),
+			CustomBranchPolicies: github.Bool(false),
+		}
+	case len(p.branches) > 0:
+		opts.DeploymentBranchPolicy = &github.BranchPolicy{
+			ProtectedBranches:    github.Bool(false),
+			CustomBranchPolicies: github.Bool(true),
+		}
+	}
+	if err := p.backend.opts.CreateGitHubEnv(ctx, p.owner, p.repo, p.env, opts); err != nil {
+		return err
+	}
+	for _, branch := range p.branches {
+		if err := p.backend.opts.CreateGitHubDeploymentBranchPolicy(ctx, p.owner, p.repo, p.env, branch); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 var _ backends.Plan = (*PlanOrgSecret)(nil)
 
 type PlanOrgSecret struct {
 	backend         *Backend
+	id              services.GitHubIdentity
 	app             services.GitHubApplication
 	org             string
 	name            string
@@ -392,17 +1639,62 @@ type PlanOrgSecret struct {
 	encryptedSecret string
 	visibility      string
 	reposID         []int64
+	hmac            string
+	updatedAt       time.Time
 	overwrite       bool
+
+	// accessOnly means that the value is up-to-date, and only the access policy differs.
+	accessOnly bool
 }
 
 func (p *PlanOrgSecret) Preview() string {
 	if p.overwrite {
-		return fmt.Sprintf("secret %q in organization %s will be updated", p.name, p.org)
+		return fmt.Sprintf("secret %q in organization %s%s will be updated", p.name, p.id.TargetPrefix(), p.org)
 	}
-	return fmt.Sprintf("secret %q in organization %s will be created", p.name, p.org)
+	return fmt.Sprintf("secret %q in organization %s%s will be created", p.name, p.id.TargetPrefix(), p.org)
+}
+
+func (p *PlanOrgSecret) Action() backends.Action {
+	return secretAction(p.overwrite)
+}
+
+func (p *PlanOrgSecret) Target() string {
+	return orgSecretTarget(p.id, p.app, p.org, p.name)
+}
+
+func (p *PlanOrgSecret) Reason() string {
+	if p.accessOnly {
+		return "the visibility or the selected repositories of the secret differ"
+	}
+	return secretReason(p.overwrite, p.hmac != "")
+}
+
+func (p *PlanOrgSecret) Verify(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
+	secret, err := p.backend.opts.GetGitHubOrgSecret(ctx, p.app, p.org, p.name)
+	return verifySecret(secret, err, p.overwrite, p.updatedAt)
+}
+
+func (p *PlanOrgSecret) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Kind:            planKindOrgSecret,
+		Identity:        p.id,
+		App:             p.app,
+		Org:             p.org,
+		Name:            p.name,
+		KeyID:           p.keyID,
+		EncryptedSecret: p.encryptedSecret,
+		Visibility:      p.visibility,
+		ReposID:         p.reposID,
+		HMAC:            p.hmac,
+		UpdatedAt:       p.updatedAt,
+		Overwrite:       p.overwrite,
+		AccessOnly:      p.accessOnly,
+	})
 }
 
 func (p *PlanOrgSecret) Apply(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
 	eSecret := &github.EncryptedSecret{
 		Name:                  p.name,
 		KeyID:                 p.keyID,
@@ -410,5 +1702,202 @@ func (p *PlanOrgSecret) Apply(ctx context.Context) error {
 		Visibility:            p.visibility,
 		SelectedRepositoryIDs: p.reposID,
 	}
-	return p.backend.opts.CreateGitHubOrgSecret(ctx, p.app, p.org, eSecret)
+	if err := p.backend.opts.CreateGitHubOrgSecret(ctx, p.app, p.org, eSecret); err != nil {
+		return err
+	}
+	if p.hmac == "" {
+		return nil
+	}
+	secret, err := p.backend.opts.GetGitHubOrgSecret(ctx, p.app, p.org, p.name)
+	return p.backend.recordState(ctx, p.Target(), p.hmac, secret, err)
+}
+
+var _ backends.Plan = (*PlanUserSecret)(nil)
+
+// PlanUserSecret is a plan to create or update the Codespaces secret of the authenticated user.
+type PlanUserSecret struct {
+	backend         *Backend
+	id              services.GitHubIdentity
+	name            string
+	keyID           string
+	encryptedSecret string
+	reposID         []int64
+	hmac            string
+	updatedAt       time.Time
+	overwrite       bool
+
+	// accessOnly means that the value is up-to-date, and only the selected repositories differ.
+	accessOnly bool
+}
+
+func (p *PlanUserSecret) Preview() string {
+	if p.overwrite {
+		return fmt.Sprintf("codespaces secret %q in %suser will be updated", p.name, p.id.TargetPrefix())
+	}
+	return fmt.Sprintf("codespaces secret %q in %suser will be created", p.name, p.id.TargetPrefix())
+}
+
+func (p *PlanUserSecret) Action() backends.Action {
+	return secretAction(p.overwrite)
+}
+
+func (p *PlanUserSecret) Target() string {
+	return userSecretTarget(p.id, p.name)
+}
+
+func (p *PlanUserSecret) Reason() string {
+	if p.accessOnly {
+		return "the selected repositories of the secret differ"
+	}
+	return secretReason(p.overwrite, p.hmac != "")
+}
+
+func (p *PlanUserSecret) Verify(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
+	secret, err := p.backend.opts.GetGitHubUserSecret(ctx, p.name)
+	return verifySecret(secret, err, p.overwrite, p.updatedAt)
+}
+
+func (p *PlanUserSecret) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Kind:            planKindUserSecret,
+		Identity:        p.id,
+		Name:            p.name,
+		KeyID:           p.keyID,
+		EncryptedSecret: p.encryptedSecret,
+		ReposID:         p.reposID,
+		HMAC:            p.hmac,
+		UpdatedAt:       p.updatedAt,
+		Overwrite:       p.overwrite,
+		AccessOnly:      p.accessOnly,
+	})
+}
+
+func (p *PlanUserSecret) Apply(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
+	eSecret := &github.EncryptedSecret{
+		Name:                  p.name,
+		KeyID:                 p.keyID,
+		EncryptedValue:        p.encryptedSecret,
+		SelectedRepositoryIDs: p.reposID,
+	}
+	if err := p.backend.opts.CreateGitHubUserSecret(ctx, eSecret); err != nil {
+		return err
+	}
+	if p.hmac == "" {
+		return nil
+	}
+	secret, err := p.backend.opts.GetGitHubUserSecret(ctx, p.name)
+	return p.backend.recordState(ctx, p.Target(), p.hmac, secret, err)
+}
+
+var _ backends.Plan = (*PlanDeleteSecret)(nil)
+
+// PlanDeleteSecret is a plan to delete the secret that is no longer in the configuration.
+type PlanDeleteSecret struct {
+	backend *Backend
+	scope   scope
+
+	// repoID is the ID of the repository. It is used only by environment secrets.
+	repoID    int64
+	name      string
+	updatedAt time.Time
+}
+
+func (p *PlanDeleteSecret) Preview() string {
+	s := p.scope
+	switch {
+	case s.org != "":
+		return fmt.Sprintf("secret %q in organization %s%s will be deleted", p.name, s.id.TargetPrefix(), s.org)
+	case s.user:
+		return fmt.Sprintf("codespaces secret %q in %suser will be deleted", p.name, s.id.TargetPrefix())
+	case s.env != "":
+		return fmt.Sprintf("secret %q in %s%s/%s environment %s will be deleted", p.name, s.id.TargetPrefix(), s.owner, s.repo, s.env)
+	default:
+		return fmt.Sprintf("secret %q in %s%s/%s will be deleted", p.name, s.id.TargetPrefix(), s.owner, s.repo)
+	}
+}
+
+func (p *PlanDeleteSecret) Action() backends.Action {
+	return backends.ActionDelete
+}
+
+func (p *PlanDeleteSecret) Target() string {
+	s := p.scope
+	switch {
+	case s.org != "":
+		return orgSecretTarget(s.id, s.app, s.org, p.name)
+	case s.user:
+		return userSecretTarget(s.id, p.name)
+	case s.env != "":
+		return envSecretTarget(s.id, s.owner, s.repo, s.env, p.name)
+	default:
+		return repoSecretTarget(s.id, s.app, s.owner, s.repo, p.name)
+	}
+}
+
+func (p *PlanDeleteSecret) Reason() string {
+	return "the secret is no longer in the configuration"
+}
+
+func (p *PlanDeleteSecret) Verify(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.scope.id)
+	var secret *github.Secret
+	var err error
+	s := p.scope
+	switch {
+	case s.org != "":
+		secret, err = p.backend.opts.GetGitHubOrgSecret(ctx, s.app, s.org, p.name)
+	case s.user:
+		secret, err = p.backend.opts.GetGitHubUserSecret(ctx, p.name)
+	case s.env != "":
+		secret, err = p.backend.opts.GetGitHubEnvSecret(ctx, int(p.repoID), s.env, p.name)
+	default:
+		secret, err = p.backend.opts.GetGitHubRepoSecret(ctx, s.app, s.owner, s.repo, p.name)
+	}
+	return verifySecret(secret, err, true, p.updatedAt)
+}
+
+func (p *PlanDeleteSecret) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Kind:      planKindDeleteSecret,
+		Identity:  p.scope.id,
+		App:       p.scope.app,
+		Owner:     p.scope.owner,
+		Repo:      p.scope.repo,
+		RepoID:    p.repoID,
+		Env:       p.scope.env,
+		Org:       p.scope.org,
+		User:      p.scope.user,
+		Name:      p.name,
+		UpdatedAt: p.updatedAt,
+	})
+}
+
+func (p *PlanDeleteSecret) Apply(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.scope.id)
+	s := p.scope
+	var err error
+	switch {
+	case s.org != "":
+		err = p.backend.opts.DeleteGitHubOrgSecret(ctx, s.app, s.org, p.name)
+	case s.user:
+		err = p.backend.opts.DeleteGitHubUserSecret(ctx, p.name)
+	case s.env != "":
+		err = p.backend.opts.DeleteGitHubEnvSecret(ctx, int(p.repoID), s.env, p.name)
+	default:
+		err = p.backend.opts.DeleteGitHubRepoSecret(ctx, s.app, s.owner, s.repo, p.name)
+	}
+	if err != nil {
+		return err
+	}
+
+	// forget the state of the deleted secret.
+	if p.backend.opts.StateDeleter == nil {
+		return nil
+	}
+	if err := p.backend.opts.DeleteState(ctx, p.Target()); err != nil {
+		return fmt.Errorf("failed to delete the state: %w", err)
+	}
+	return nil
 }
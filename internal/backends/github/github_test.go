@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/base64"
 	"net/http"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/google/go-github/v56/github"
+	"github.com/shogo82148/op-sync/internal/backends"
 	"github.com/shogo82148/op-sync/internal/services"
 	"github.com/shogo82148/op-sync/internal/services/mock"
 	"golang.org/x/crypto/nacl/box"
@@ -49,7 +51,7 @@ func TestPlan_RepoSecret(t *testing.T) {
 			repoSecret = secret
 			return nil
 		}),
-		GitHubRepoPublicKeyGetter: mock.GitHubRepoPublicKeyGetter(func(ctx context.Context, owner, repo string) (*github.PublicKey, error) {
+		GitHubRepoPublicKeyGetter: mock.GitHubRepoPublicKeyGetter(func(ctx context.Context, app services.GitHubApplication, owner, repo string) (*github.PublicKey, error) {
 			return &github.PublicKey{
 				KeyID: github.String("key_id"),
 				Key:   github.String(pubKey),
@@ -138,9 +140,9 @@ func TestPlan_RepoSecret_UpToDate(t *testing.T) {
 	}
 
 	// verify the plan
-	// the secret is up-to-date, so the plan should be empty.
-	if len(plans) != 0 {
-		t.Fatalf("unexpected length: want 0, got %d", len(plans))
+	// the secret is up-to-date, so the plan should be noop.
+	if len(plans) != 1 || plans[0].Action() != backends.ActionNoop {
+		t.Fatalf("unexpected plans: want one noop plan, got %v", plans)
 	}
 }
 
@@ -180,7 +182,10 @@ func TestPlan_RepoSecret_for_Dependabot(t *testing.T) {
 			repoSecret = secret
 			return nil
 		}),
-		GitHubRepoPublicKeyGetter: mock.GitHubRepoPublicKeyGetter(func(ctx context.Context, owner, repo string) (*github.PublicKey, error) {
+		GitHubRepoPublicKeyGetter: mock.GitHubRepoPublicKeyGetter(func(ctx context.Context, app services.GitHubApplication, owner, repo string) (*github.PublicKey, error) {
+			if app != services.GitHubApplicationDependabot {
+				t.Errorf("unexpected application: want dependabot, got %s", app)
+			}
 			return &github.PublicKey{
 				KeyID: github.String("key_id"),
 				Key:   github.String(pubKey),
@@ -376,8 +381,8 @@ func TestPlan_EnvSecret_UpToDate(t *testing.T) {
 	}
 
 	// verify the plan
-	if len(plans) != 0 {
-		t.Fatalf("unexpected length: want 0, got %d", len(plans))
+	if len(plans) != 1 || plans[0].Action() != backends.ActionNoop {
+		t.Fatalf("unexpected plans: want one noop plan, got %v", plans)
 	}
 }
 
@@ -414,7 +419,7 @@ func TestPlan_OrgSecret(t *testing.T) {
 			orgSecret = secret
 			return nil
 		}),
-		GitHubOrgPublicKeyGetter: mock.GitHubOrgPublicKeyGetter(func(ctx context.Context, org string) (*github.PublicKey, error) {
+		GitHubOrgPublicKeyGetter: mock.GitHubOrgPublicKeyGetter(func(ctx context.Context, app services.GitHubApplication, org string) (*github.PublicKey, error) {
 			return &github.PublicKey{
 				KeyID: github.String("key_id"),
 				Key:   github.String(pubKey),
@@ -471,3 +476,954 @@ func TestPlan_OrgSecret(t *testing.T) {
 		t.Errorf("unexpected message: want secret, got %s", string(message))
 	}
 }
+
+func TestPrune(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updatedAt := github.Timestamp{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var deleted, deletedStates []string
+	b := New(&Options{
+		GitHubRepoGetter: mock.GitHubRepoGetter(func(ctx context.Context, owner, repo string) (*github.Repository, error) {
+			return &github.Repository{
+				ID: github.Int64(123),
+			}, nil
+		}),
+		GitHubRepoSecretsLister: mock.GitHubRepoSecretsLister(func(ctx context.Context, app services.GitHubApplication, owner, repo string) ([]*github.Secret, error) {
+			if app != services.GitHubApplicationActions {
+				t.Errorf("unexpected application: want actions, got %s", app)
+			}
+			return []*github.Secret{
+				{Name: "VERY_SECRET_TOKEN", UpdatedAt: updatedAt},
+				{Name: "REMOVED_TOKEN", UpdatedAt: updatedAt},
+			}, nil
+		}),
+		GitHubRepoSecretGetter: mock.GitHubRepoSecretGetter(func(ctx context.Context, app services.GitHubApplication, owner, repo, name string) (*github.Secret, error) {
+			return &github.Secret{Name: name, UpdatedAt: updatedAt}, nil
+		}),
+		GitHubRepoSecretDeleter: mock.GitHubRepoSecretDeleter(func(ctx context.Context, app services.GitHubApplication, owner, repo, name string) error {
+			deleted = append(deleted, owner+"/"+repo+"/"+name)
+			return nil
+		}),
+		GitHubEnvSecretsLister: mock.GitHubEnvSecretsLister(func(ctx context.Context, repoID int, env string) ([]*github.Secret, error) {
+			if repoID != 123 || env != "production" {
+				t.Errorf("unexpected environment: %d %s", repoID, env)
+			}
+			return []*github.Secret{
+				{Name: "VERY_SECRET_TOKEN", UpdatedAt: updatedAt},
+			}, nil
+		}),
+		StateDeleter: mock.StateDeleter(func(ctx context.Context, key string) error {
+			deletedStates = append(deletedStates, key)
+			return nil
+		}),
+		PruneScopes: []map[string]any{
+			{"repository": "shogo82148/op-sync"},
+			{"repository": "shogo82148/op-sync", "environment": "production"},
+		},
+	})
+
+	// do planning
+	plans, err := b.Prune(ctx, []map[string]any{
+		{
+			"repository": "shogo82148/op-sync",
+			"name":       "very_secret_token",
+			"source":     "op://vault/item/VERY_SECRET_TOKEN",
+		},
+		{
+			"repository":  "shogo82148/op-sync",
+			"environment": "production",
+			"name":        "VERY_SECRET_TOKEN",
+			"source":      "op://vault/item/VERY_SECRET_TOKEN",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plan
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Target(), "repos/shogo82148/op-sync/actions/secrets/REMOVED_TOKEN"; got != want {
+		t.Errorf("unexpected target: want %q, got %q", want, got)
+	}
+
+	// the plan survives serialization.
+	data, err := plans[0].MarshalPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := b.UnmarshalPlan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Verify(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// apply the plan
+	if err := plan.Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0] != "shogo82148/op-sync/REMOVED_TOKEN" {
+		t.Errorf("unexpected deleted secrets: %v", deleted)
+	}
+
+	// the state of the deleted secret is deleted, too.
+	if len(deletedStates) != 1 || deletedStates[0] != "repos/shogo82148/op-sync/actions/secrets/REMOVED_TOKEN" {
+		t.Errorf("unexpected deleted states: %v", deletedStates)
+	}
+}
+
+func TestPrune_CaseInsensitive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updatedAt := github.Timestamp{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var listed []string
+	b := New(&Options{
+		GitHubReposLister: mock.GitHubReposLister(func(ctx context.Context, owner string) ([]*github.Repository, error) {
+			return []*github.Repository{
+				{Name: github.String("service-a"), Owner: &github.User{Login: github.String("org")}},
+			}, nil
+		}),
+		GitHubRepoSecretsLister: mock.GitHubRepoSecretsLister(func(ctx context.Context, app services.GitHubApplication, owner, repo string) ([]*github.Secret, error) {
+			listed = append(listed, owner+"/"+repo)
+			return []*github.Secret{
+				{Name: "TOKEN_A", UpdatedAt: updatedAt},
+				{Name: "TOKEN_B", UpdatedAt: updatedAt},
+			}, nil
+		}),
+		PruneScopes: []map[string]any{
+			{"repository": "ORG/service-a"},
+		},
+	})
+
+	// the entries refer to the same repository in different cases.
+	plans, err := b.Prune(ctx, []map[string]any{
+		{
+			"repository": "Org/Service-A",
+			"name":       "TOKEN_A",
+			"source":     "op://vault/item/TOKEN_A",
+		},
+		{
+			"repositories": []any{"org/service-*"},
+			"name":         "TOKEN_B",
+			"source":       "op://vault/item/TOKEN_B",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 0 {
+		for _, plan := range plans {
+			t.Errorf("unexpected plan: %s", plan.Preview())
+		}
+	}
+	if len(listed) != 1 {
+		t.Errorf("unexpected listed repositories: %v", listed)
+	}
+}
+
+func TestPrune_Scopes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updatedAt := github.Timestamp{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var listed []string
+	b := New(&Options{
+		GitHubRepoSecretsLister: mock.GitHubRepoSecretsLister(func(ctx context.Context, app services.GitHubApplication, owner, repo string) ([]*github.Secret, error) {
+			listed = append(listed, owner+"/"+repo)
+			return []*github.Secret{
+				{Name: "TOKEN", UpdatedAt: updatedAt},
+			}, nil
+		}),
+		GitHubOrgSecretsLister: mock.GitHubOrgSecretsLister(func(ctx context.Context, app services.GitHubApplication, org string) ([]*github.Secret, error) {
+			t.Errorf("unexpected listing of organization %s", org)
+			return nil, nil
+		}),
+		PruneScopes: []map[string]any{
+			{"repository": "shogo82148/removed"},
+		},
+	})
+
+	// the scopes of the configuration are not pruned unless they are listed,
+	// and the listed scopes are pruned even if no secrets refer to them.
+	plans, err := b.Prune(ctx, []map[string]any{
+		{
+			"organization": "shogo82148",
+			"name":         "TOKEN",
+			"source":       "op://vault/item/TOKEN",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Target(), "repos/shogo82148/removed/actions/secrets/TOKEN"; got != want {
+		t.Errorf("unexpected target: want %q, got %q", want, got)
+	}
+	if len(listed) != 1 {
+		t.Errorf("unexpected listed repositories: %v", listed)
+	}
+}
+
+func TestPrune_NoScopes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := New(&Options{
+		GitHubRepoSecretsLister: mock.GitHubRepoSecretsLister(func(ctx context.Context, app services.GitHubApplication, owner, repo string) ([]*github.Secret, error) {
+			t.Errorf("unexpected listing of %s/%s", owner, repo)
+			return nil, nil
+		}),
+	})
+
+	plans, err := b.Prune(ctx, []map[string]any{
+		{
+			"repository": "shogo82148/op-sync",
+			"name":       "TOKEN",
+			"source":     "op://vault/item/TOKEN",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 0 {
+		t.Errorf("unexpected length: want 0, got %d", len(plans))
+	}
+}
+
+func TestPlan_RepoSecret_State(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const target = "repos/shogo82148/op-sync/actions/secrets/VERY_SECRET_TOKEN"
+	pubKey := "X4Dk1lVSh8C21cDezdGivFERcid2L7UAfFCWeiRn1mU="
+	updatedAt := github.Timestamp{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	state := map[string]*services.SecretState{
+		target: {HMAC: "hash-of-secret", UpdatedAt: updatedAt.Time},
+	}
+	value := "secret"
+	b := New(&Options{
+		OnePasswordItemGetter: mock.OnePasswordItemGetter(func(ctx context.Context, vault, item string) (*services.OnePasswordItem, error) {
+			t.Error("the update time of the item must not be used")
+			return &services.OnePasswordItem{}, nil
+		}),
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte(value), nil
+		}),
+		GitHubRepoSecretGetter: mock.GitHubRepoSecretGetter(func(ctx context.Context, app services.GitHubApplication, owner, repo, name string) (*github.Secret, error) {
+			return &github.Secret{Name: name, UpdatedAt: updatedAt}, nil
+		}),
+		GitHubRepoSecretCreator: mock.GitHubRepoSecretCreator(func(ctx context.Context, app services.GitHubApplication, owner, repo string, secret *github.EncryptedSecret) error {
+			updatedAt = github.Timestamp{Time: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)}
+			return nil
+		}),
+		GitHubRepoPublicKeyGetter: mock.GitHubRepoPublicKeyGetter(func(ctx context.Context, app services.GitHubApplication, owner, repo string) (*github.PublicKey, error) {
+			return &github.PublicKey{
+				KeyID: github.String("key_id"),
+				Key:   github.String(pubKey),
+			}, nil
+		}),
+		SecretHasher: mock.SecretHasher(func(ctx context.Context, secret []byte) (string, error) {
+			return "hash-of-" + string(secret), nil
+		}),
+		StateGetter: mock.StateGetter(func(ctx context.Context, key string) (*services.SecretState, error) {
+			return state[key], nil
+		}),
+		StatePutter: mock.StatePutter(func(ctx context.Context, key string, s *services.SecretState) error {
+			state[key] = s
+			return nil
+		}),
+	})
+	params := map[string]any{
+		"repository": "shogo82148/op-sync",
+		"name":       "VERY_SECRET_TOKEN",
+		"source":     "op://vault/item/VERY_SECRET_TOKEN",
+	}
+
+	// the hash matches the state.
+	plans, err := b.Plan(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 || plans[0].Action() != backends.ActionNoop {
+		t.Fatalf("unexpected plans: want one noop plan, got %v", plans)
+	}
+
+	// the value in 1Password is changed.
+	value = "new-secret"
+	plans, err = b.Plan(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if err := plans[0].Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the new value is recorded.
+	if got := state[target]; got.HMAC != "hash-of-new-secret" || !got.UpdatedAt.Equal(updatedAt.Time) {
+		t.Errorf("unexpected state: %v", got)
+	}
+	plans, err = b.Plan(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 || plans[0].Action() != backends.ActionNoop {
+		t.Fatalf("unexpected plans: want one noop plan, got %v", plans)
+	}
+}
+
+func TestPlan_OrgSecret_Access(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pubKey := "X4Dk1lVSh8C21cDezdGivFERcid2L7UAfFCWeiRn1mU="
+	var orgSecret *github.EncryptedSecret
+	b := New(&Options{
+		OnePasswordItemGetter: mock.OnePasswordItemGetter(func(ctx context.Context, vault, item string) (*services.OnePasswordItem, error) {
+			// the item is older than the secret.
+			return &services.OnePasswordItem{}, nil
+		}),
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("secret"), nil
+		}),
+		GitHubRepoGetter: mock.GitHubRepoGetter(func(ctx context.Context, owner, repo string) (*github.Repository, error) {
+			if owner != "my-org" {
+				t.Errorf("unexpected owner: want my-org, got %s", owner)
+			}
+			ids := map[string]int64{"op-sync": 1, "other": 2}
+			return &github.Repository{
+				ID:   github.Int64(ids[repo]),
+				Name: github.String(repo),
+			}, nil
+		}),
+		GitHubOrgSecretGetter: mock.GitHubOrgSecretGetter(func(ctx context.Context, app services.GitHubApplication, org, name string) (*github.Secret, error) {
+			return &github.Secret{
+				Name:       name,
+				UpdatedAt:  github.Timestamp{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+				Visibility: "selected",
+			}, nil
+		}),
+		GitHubReposIDForOrgSecretLister: mock.GitHubReposIDForOrgSecretLister(func(ctx context.Context, app services.GitHubApplication, org, name string) ([]int64, error) {
+			return []int64{1}, nil
+		}),
+		GitHubOrgSecretCreator: mock.GitHubOrgSecretCreator(func(ctx context.Context, app services.GitHubApplication, org string, secret *github.EncryptedSecret) error {
+			orgSecret = secret
+			return nil
+		}),
+		GitHubOrgPublicKeyGetter: mock.GitHubOrgPublicKeyGetter(func(ctx context.Context, app services.GitHubApplication, org string) (*github.PublicKey, error) {
+			return &github.PublicKey{
+				KeyID: github.String("key_id"),
+				Key:   github.String(pubKey),
+			}, nil
+		}),
+	})
+
+	// the access policy is up-to-date.
+	plans, err := b.Plan(ctx, map[string]any{
+		"organization": "my-org",
+		"name":         "VERY_SECRET_TOKEN",
+		"source":       "op://vault/item/VERY_SECRET_TOKEN",
+		"repositories": []any{"op-sync"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 || plans[0].Action() != backends.ActionNoop {
+		t.Fatalf("unexpected plans: want one noop plan, got %v", plans)
+	}
+
+	// a repository is added.
+	plans, err = b.Plan(ctx, map[string]any{
+		"organization": "my-org",
+		"name":         "VERY_SECRET_TOKEN",
+		"source":       "op://vault/item/VERY_SECRET_TOKEN",
+		"visibility":   "selected",
+		"repositories": []any{"op-sync", "my-org/other"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Reason(), "the visibility or the selected repositories of the secret differ"; got != want {
+		t.Errorf("unexpected reason: want %q, got %q", want, got)
+	}
+	if err := plans[0].Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if orgSecret.Visibility != "selected" || !slices.Equal(orgSecret.SelectedRepositoryIDs, github.SelectedRepoIDs{1, 2}) {
+		t.Errorf("unexpected access policy: %s %v", orgSecret.Visibility, orgSecret.SelectedRepositoryIDs)
+	}
+
+	// the visibility is changed.
+	plans, err = b.Plan(ctx, map[string]any{
+		"organization": "my-org",
+		"name":         "VERY_SECRET_TOKEN",
+		"source":       "op://vault/item/VERY_SECRET_TOKEN",
+		"visibility":   "private",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if err := plans[0].Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if orgSecret.Visibility != "private" || len(orgSecret.SelectedRepositoryIDs) != 0 {
+		t.Errorf("unexpected access policy: %s %v", orgSecret.Visibility, orgSecret.SelectedRepositoryIDs)
+	}
+}
+
+func TestPlan_OrgSecret_InvalidAccess(t *testing.T) {
+	b := New(&Options{})
+	tests := []map[string]any{
+		{
+			"repository": "shogo82148/op-sync",
+			"name":       "VERY_SECRET_TOKEN",
+			"source":     "op://vault/item/VERY_SECRET_TOKEN",
+			"visibility": "all",
+		},
+		{
+			"organization": "my-org",
+			"name":         "VERY_SECRET_TOKEN",
+			"source":       "op://vault/item/VERY_SECRET_TOKEN",
+			"visibility":   "public",
+		},
+		{
+			"organization": "my-org",
+			"name":         "VERY_SECRET_TOKEN",
+			"source":       "op://vault/item/VERY_SECRET_TOKEN",
+			"visibility":   "all",
+			"repositories": []any{"op-sync"},
+		},
+		{
+			"organization": "my-org",
+			"name":         "VERY_SECRET_TOKEN",
+			"source":       "op://vault/item/VERY_SECRET_TOKEN",
+			"repositories": []any{"other-org/op-sync"},
+		},
+	}
+	for _, params := range tests {
+		if _, err := b.Plan(context.Background(), params); err == nil {
+			t.Errorf("want error, got nil: %v", params)
+		}
+	}
+}
+
+func TestPlan_FanOut_Repositories(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pubKey := "X4Dk1lVSh8C21cDezdGivFERcid2L7UAfFCWeiRn1mU="
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("secret"), nil
+		}),
+		GitHubReposLister: mock.GitHubReposLister(func(ctx context.Context, owner string) ([]*github.Repository, error) {
+			if owner != "my-org" {
+				t.Errorf("unexpected owner: want my-org, got %s", owner)
+			}
+			return []*github.Repository{
+				{Name: github.String("service-a"), Topics: []string{"go", "backend"}},
+				{Name: github.String("Service-B"), Topics: []string{"backend"}},
+				{Name: github.String("service-c"), Topics: []string{"backend"}, Archived: github.Bool(true)},
+				{Name: github.String("service-d"), Topics: []string{"frontend"}},
+				{Name: github.String("website"), Topics: []string{"backend"}},
+			}, nil
+		}),
+		GitHubRepoGetter: mock.GitHubRepoGetter(func(ctx context.Context, owner, repo string) (*github.Repository, error) {
+			return &github.Repository{
+				Name:   github.String(repo),
+				Topics: []string{"backend"},
+			}, nil
+		}),
+		GitHubRepoSecretGetter: mock.GitHubRepoSecretGetter(func(ctx context.Context, app services.GitHubApplication, owner, repo, name string) (*github.Secret, error) {
+			return nil, &github.ErrorResponse{
+				Response: &http.Response{
+					StatusCode: http.StatusNotFound,
+				},
+			}
+		}),
+		GitHubRepoPublicKeyGetter: mock.GitHubRepoPublicKeyGetter(func(ctx context.Context, app services.GitHubApplication, owner, repo string) (*github.PublicKey, error) {
+			return &github.PublicKey{
+				KeyID: github.String("key_id"),
+				Key:   github.String(pubKey),
+			}, nil
+		}),
+	})
+
+	plans, err := b.Plan(ctx, map[string]any{
+		"repositories": []any{"my-org/service-*", "my-org/service-a", "shogo82148/op-sync"},
+		"topics":       []any{"backend"},
+		"name":         "VERY_SECRET_TOKEN",
+		"source":       "op://vault/item/VERY_SECRET_TOKEN",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var targets []string
+	for _, plan := range plans {
+		targets = append(targets, plan.Target())
+	}
+	want := []string{
+		"repos/my-org/service-a/actions/secrets/VERY_SECRET_TOKEN",
+		"repos/my-org/Service-B/actions/secrets/VERY_SECRET_TOKEN",
+		"repos/shogo82148/op-sync/actions/secrets/VERY_SECRET_TOKEN",
+	}
+	if !slices.Equal(targets, want) {
+		t.Errorf("unexpected targets: want %v, got %v", want, targets)
+	}
+}
+
+func TestPlan_FanOut_Environments(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pubKey := "X4Dk1lVSh8C21cDezdGivFERcid2L7UAfFCWeiRn1mU="
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("secret"), nil
+		}),
+		GitHubRepoGetter: mock.GitHubRepoGetter(func(ctx context.Context, owner, repo string) (*github.Repository, error) {
+			return &github.Repository{
+				ID:    github.Int64(1234567890),
+				Name:  github.String(repo),
+				Owner: &github.User{Login: github.String(owner)},
+			}, nil
+		}),
+		GitHubEnvSecretGetter: mock.GitHubEnvSecretGetter(func(ctx context.Context, repoID int, env, name string) (*github.Secret, error) {
+			return nil, &github.ErrorResponse{
+				Response: &http.Response{
+					StatusCode: http.StatusNotFound,
+				},
+			}
+		}),
+		GitHubEnvPublicKeyGetter: mock.GitHubEnvPublicKeyGetter(func(ctx context.Context, repoID int, env string) (*github.PublicKey, error) {
+			return &github.PublicKey{
+				KeyID: github.String("key_id"),
+				Key:   github.String(pubKey),
+			}, nil
+		}),
+	})
+
+	plans, err := b.Plan(ctx, map[string]any{
+		"repositories": []any{"shogo82148/op-sync", "shogo82148/other"},
+		"environments": []any{"staging", "production"},
+		"name":         "VERY_SECRET_TOKEN",
+		"source":       "op://vault/item/VERY_SECRET_TOKEN",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var targets []string
+	for _, plan := range plans {
+		targets = append(targets, plan.Target())
+	}
+	want := []string{
+		"repos/shogo82148/op-sync/environments/staging/secrets/VERY_SECRET_TOKEN",
+		"repos/shogo82148/op-sync/environments/production/secrets/VERY_SECRET_TOKEN",
+		"repos/shogo82148/other/environments/staging/secrets/VERY_SECRET_TOKEN",
+		"repos/shogo82148/other/environments/production/secrets/VERY_SECRET_TOKEN",
+	}
+	if !slices.Equal(targets, want) {
+		t.Errorf("unexpected targets: want %v, got %v", want, targets)
+	}
+}
+
+func TestPlan_RepoSecret_EnterpriseServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = services.WithGitHubIdentity(ctx, services.GitHubIdentity{TokenEnv: "GHES_TOKEN"})
+
+	pubKey := "X4Dk1lVSh8C21cDezdGivFERcid2L7UAfFCWeiRn1mU="
+	want := services.GitHubIdentity{Host: "github.example.com", TokenEnv: "GHES_TOKEN"}
+	checkIdentity := func(ctx context.Context) {
+		t.Helper()
+		if got := services.GitHubIdentityFromContext(ctx); got != want {
+			t.Errorf("unexpected identity: want %v, got %v", want, got)
+		}
+	}
+	var created bool
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("secret"), nil
+		}),
+		GitHubRepoSecretGetter: mock.GitHubRepoSecretGetter(func(ctx context.Context, app services.GitHubApplication, owner, repo, name string) (*github.Secret, error) {
+			checkIdentity(ctx)
+			return nil, &github.ErrorResponse{
+				Response: &http.Response{
+					StatusCode: http.StatusNotFound,
+				},
+			}
+		}),
+		GitHubRepoSecretCreator: mock.GitHubRepoSecretCreator(func(ctx context.Context, app services.GitHubApplication, owner, repo string, secret *github.EncryptedSecret) error {
+			checkIdentity(ctx)
+			created = true
+			return nil
+		}),
+		GitHubRepoPublicKeyGetter: mock.GitHubRepoPublicKeyGetter(func(ctx context.Context, app services.GitHubApplication, owner, repo string) (*github.PublicKey, error) {
+			checkIdentity(ctx)
+			return &github.PublicKey{
+				KeyID: github.String("key_id"),
+				Key:   github.String(pubKey),
+			}, nil
+		}),
+	})
+
+	plans, err := b.Plan(ctx, map[string]any{
+		"repository":  "shogo82148/op-sync",
+		"github_host": "github.example.com",
+		"name":        "VERY_SECRET_TOKEN",
+		"source":      "op://vault/item/VERY_SECRET_TOKEN",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Target(), "github.example.com/repos/shogo82148/op-sync/actions/secrets/VERY_SECRET_TOKEN"; got != want {
+		t.Errorf("unexpected target: want %s, got %s", want, got)
+	}
+
+	// the saved plan keeps the identity.
+	data, err := plans[0].MarshalPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := b.UnmarshalPlan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Error("the secret is not created")
+	}
+}
+
+func TestPlan_EnvSecret_CreateEnvironment(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Key is generated by gen_key.go
+	pubKey := "X4Dk1lVSh8C21cDezdGivFERcid2L7UAfFCWeiRn1mU="
+	privKey := "BnLGcl9+miWXxrHDmWOROLRqBU6mM/biZTYO16LcRag="
+
+	notFound := &github.ErrorResponse{
+		Response: &http.Response{
+			StatusCode: http.StatusNotFound,
+		},
+	}
+	var envCreated bool
+	var envOpts *github.CreateUpdateEnvironment
+	var branches []string
+	var envSecret *github.EncryptedSecret
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("secret"), nil
+		}),
+		GitHubRepoGetter: mock.GitHubRepoGetter(func(ctx context.Context, owner, repo string) (*github.Repository, error) {
+			return &github.Repository{
+				ID:    github.Int64(1234567890),
+				Name:  github.String(repo),
+				Owner: &github.User{Login: github.String(owner)},
+			}, nil
+		}),
+		GitHubUserByLoginGetter: mock.GitHubUserByLoginGetter(func(ctx context.Context, login string) (*github.User, error) {
+			if login != "octocat" {
+				t.Errorf("unexpected login: want octocat, got %s", login)
+			}
+			return &github.User{ID: github.Int64(1)}, nil
+		}),
+		GitHubTeamGetter: mock.GitHubTeamGetter(func(ctx context.Context, org, slug string) (*github.Team, error) {
+			if org != "my-org" || slug != "admins" {
+				t.Errorf("unexpected team: want my-org/admins, got %s/%s", org, slug)
+			}
+			return &github.Team{ID: github.Int64(2)}, nil
+		}),
+		GitHubEnvGetter: mock.GitHubEnvGetter(func(ctx context.Context, owner, repo, env string) (*github.Environment, error) {
+			if envCreated {
+				return &github.Environment{Name: github.String(env)}, nil
+			}
+			return nil, notFound
+		}),
+		GitHubEnvCreator: mock.GitHubEnvCreator(func(ctx context.Context, owner, repo, env string, opts *github.CreateUpdateEnvironment) error {
+			if env != "production" {
+				t.Errorf("unexpected environment: want production, got %s", env)
+			}
+			envCreated = true
+			envOpts = opts
+			return nil
+		}),
+		GitHubDeploymentBranchPolicyCreator: mock.GitHubDeploymentBranchPolicyCreator(func(ctx context.Context, owner, repo, env, pattern string) error {
+			branches = append(branches, pattern)
+			return nil
+		}),
+		GitHubEnvSecretGetter: mock.GitHubEnvSecretGetter(func(ctx context.Context, repoID int, env, name string) (*github.Secret, error) {
+			return nil, notFound
+		}),
+		GitHubEnvSecretCreator: mock.GitHubEnvSecretCreator(func(ctx context.Context, repoID int, env string, secret *github.EncryptedSecret) error {
+			if !envCreated {
+				t.Error("the secret is created before the environment")
+			}
+			envSecret = secret
+			return nil
+		}),
+		GitHubEnvPublicKeyGetter: mock.GitHubEnvPublicKeyGetter(func(ctx context.Context, repoID int, env string) (*github.PublicKey, error) {
+			if !envCreated {
+				return nil, notFound
+			}
+			return &github.PublicKey{
+				KeyID: github.String("key_id"),
+				Key:   github.String(pubKey),
+			}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"repository":  "shogo82148/op-sync",
+		"environment": "production",
+		"create_environment": map[string]any{
+			"reviewers":           []any{"octocat", "my-org/admins"},
+			"deployment_branches": []any{"main", "release/*"},
+		},
+		"name":   "VERY_SECRET_TOKEN",
+		"source": "op://vault/item/VERY_SECRET_TOKEN",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plan
+	if len(plans) != 2 {
+		t.Fatalf("unexpected length: want 2, got %d", len(plans))
+	}
+	if got, want := plans[0].Preview(), "environment production in shogo82148/op-sync will be created"; got != want {
+		t.Errorf("unexpected preview: want %q, got %q", want, got)
+	}
+	if got, want := plans[1].Preview(), `secret "VERY_SECRET_TOKEN" in shogo82148/op-sync environment production will be created`; got != want {
+		t.Errorf("unexpected preview: want %q, got %q", want, got)
+	}
+
+	// apply the plans via the saved plans
+	for _, plan := range plans {
+		data, err := plan.MarshalPlan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan, err := b.UnmarshalPlan(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := plan.Verify(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := plan.Apply(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// verify the environment
+	if len(envOpts.Reviewers) != 2 || envOpts.Reviewers[0].GetType() != "User" || envOpts.Reviewers[0].GetID() != 1 ||
+		envOpts.Reviewers[1].GetType() != "Team" || envOpts.Reviewers[1].GetID() != 2 {
+		t.Errorf("unexpected reviewers: %v", envOpts.Reviewers)
+	}
+	if !envOpts.DeploymentBranchPolicy.GetCustomBranchPolicies() {
+		t.Error("want custom branch policies")
+	}
+	if !slices.Equal(branches, []string{"main", "release/*"}) {
+		t.Errorf("unexpected branches: %v", branches)
+	}
+
+	// verify the secret
+	if envSecret == nil {
+		t.Fatal("envSecret is not set")
+	}
+	encrypted, err := base64.StdEncoding.DecodeString(envSecret.EncryptedValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedPubKey, err := base64.StdEncoding.DecodeString(pubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedPrivKey, err := base64.StdEncoding.DecodeString(privKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var peersPubKey [32]byte
+	copy(peersPubKey[:], decodedPubKey)
+	var peersPrivKey [32]byte
+	copy(peersPrivKey[:], decodedPrivKey)
+	message, ok := box.OpenAnonymous(nil, encrypted, &peersPubKey, &peersPrivKey)
+	if !ok {
+		t.Fatal("failed to decrypt")
+	}
+	if string(message) != "secret" {
+		t.Errorf("unexpected message: want secret, got %s", string(message))
+	}
+}
+
+func TestPlan_UserSecret(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pubKey := "X4Dk1lVSh8C21cDezdGivFERcid2L7UAfFCWeiRn1mU="
+	var userSecret *github.EncryptedSecret
+	b := New(&Options{
+		OnePasswordItemGetter: mock.OnePasswordItemGetter(func(ctx context.Context, vault, item string) (*services.OnePasswordItem, error) {
+			return &services.OnePasswordItem{}, nil
+		}),
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("secret"), nil
+		}),
+		GitHubRepoGetter: mock.GitHubRepoGetter(func(ctx context.Context, owner, repo string) (*github.Repository, error) {
+			ids := map[string]int64{"shogo82148/op-sync": 1, "my-org/other": 2}
+			return &github.Repository{
+				ID:   github.Int64(ids[owner+"/"+repo]),
+				Name: github.String(repo),
+			}, nil
+		}),
+		GitHubUserSecretGetter: mock.GitHubUserSecretGetter(func(ctx context.Context, name string) (*github.Secret, error) {
+			return nil, &github.ErrorResponse{
+				Response: &http.Response{
+					StatusCode: http.StatusNotFound,
+				},
+			}
+		}),
+		GitHubUserSecretCreator: mock.GitHubUserSecretCreator(func(ctx context.Context, secret *github.EncryptedSecret) error {
+			userSecret = secret
+			return nil
+		}),
+		GitHubUserPublicKeyGetter: mock.GitHubUserPublicKeyGetter(func(ctx context.Context) (*github.PublicKey, error) {
+			return &github.PublicKey{
+				KeyID: github.String("key_id"),
+				Key:   github.String(pubKey),
+			}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"user":         true,
+		"name":         "VERY_SECRET_TOKEN",
+		"source":       "op://vault/item/VERY_SECRET_TOKEN",
+		"repositories": []any{"shogo82148/op-sync", "my-org/other"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plan
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Target(), "user/codespaces/secrets/VERY_SECRET_TOKEN"; got != want {
+		t.Errorf("unexpected target: want %q, got %q", want, got)
+	}
+
+	// apply the plan
+	if err := plans[0].Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if userSecret.Name != "VERY_SECRET_TOKEN" || userSecret.KeyID != "key_id" {
+		t.Errorf("unexpected secret: %s %s", userSecret.Name, userSecret.KeyID)
+	}
+	if !slices.Equal(userSecret.SelectedRepositoryIDs, github.SelectedRepoIDs{1, 2}) {
+		t.Errorf("unexpected selected repositories: %v", userSecret.SelectedRepositoryIDs)
+	}
+}
+
+func TestPlan_InvalidScope(t *testing.T) {
+	b := New(&Options{})
+	tests := []map[string]any{
+		// no scope
+		{
+			"name":   "VERY_SECRET_TOKEN",
+			"source": "op://vault/item/VERY_SECRET_TOKEN",
+		},
+		{
+			"user":   false,
+			"name":   "VERY_SECRET_TOKEN",
+			"source": "op://vault/item/VERY_SECRET_TOKEN",
+		},
+
+		// environment secrets are available only for actions.
+		{
+			"repository":  "shogo82148/op-sync",
+			"environment": "production",
+			"application": "dependabot",
+			"name":        "VERY_SECRET_TOKEN",
+			"source":      "op://vault/item/VERY_SECRET_TOKEN",
+		},
+		{
+			"repository":   "shogo82148/op-sync",
+			"environments": []any{"production"},
+			"application":  "codespaces",
+			"name":         "VERY_SECRET_TOKEN",
+			"source":       "op://vault/item/VERY_SECRET_TOKEN",
+		},
+
+		// user secrets are available only for codespaces.
+		{
+			"user":        true,
+			"application": "actions",
+			"name":        "VERY_SECRET_TOKEN",
+			"source":      "op://vault/item/VERY_SECRET_TOKEN",
+		},
+		{
+			"user":        true,
+			"application": "dependabot",
+			"name":        "VERY_SECRET_TOKEN",
+			"source":      "op://vault/item/VERY_SECRET_TOKEN",
+		},
+
+		// user secrets have no other scopes.
+		{
+			"user":         true,
+			"organization": "my-org",
+			"name":         "VERY_SECRET_TOKEN",
+			"source":       "op://vault/item/VERY_SECRET_TOKEN",
+		},
+		{
+			"user":       true,
+			"repository": "shogo82148/op-sync",
+			"name":       "VERY_SECRET_TOKEN",
+			"source":     "op://vault/item/VERY_SECRET_TOKEN",
+		},
+		{
+			"user":        true,
+			"environment": "production",
+			"name":        "VERY_SECRET_TOKEN",
+			"source":      "op://vault/item/VERY_SECRET_TOKEN",
+		},
+		{
+			"user":         true,
+			"repositories": []any{"op-sync"},
+			"name":         "VERY_SECRET_TOKEN",
+			"source":       "op://vault/item/VERY_SECRET_TOKEN",
+		},
+	}
+	for _, params := range tests {
+		if _, err := b.Plan(context.Background(), params); err == nil {
+			t.Errorf("want error, got nil: %v", params)
+		}
+	}
+}
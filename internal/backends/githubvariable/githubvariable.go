@@ -0,0 +1,290 @@
+package githubvariable
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/shogo82148/op-sync/internal/backends"
+	"github.com/shogo82148/op-sync/internal/maputils"
+	"github.com/shogo82148/op-sync/internal/services"
+)
+
+var _ backends.Backend = (*Backend)(nil)
+
+type Backend struct {
+	opts *Options
+}
+
+type Options struct {
+	services.OnePasswordReader
+	services.GitHubRepoGetter
+	services.GitHubRepoVariableGetter
+	services.GitHubRepoVariableCreator
+	services.GitHubRepoVariableUpdater
+	services.GitHubEnvVariableGetter
+	services.GitHubEnvVariableCreator
+	services.GitHubEnvVariableUpdater
+	services.GitHubOrgVariableGetter
+	services.GitHubOrgVariableCreator
+	services.GitHubOrgVariableUpdater
+}
+
+func New(opts *Options) *Backend {
+	return &Backend{opts: opts}
+}
+
+func (b *Backend) Plan(ctx context.Context, params map[string]any) ([]backends.Plan, error) {
+	c := new(maputils.Context)
+	organization, hasOrganization := maputils.Get[string](c, params, "organization")
+	repository, hasRepository := maputils.Get[string](c, params, "repository")
+	environment, hasEnvironment := maputils.Get[string](c, params, "environment")
+	name := maputils.Must[string](c, params, "name")
+	source := maputils.Must[string](c, params, "source")
+	host, _ := maputils.Get[string](c, params, "github_host")
+	if err := c.Err(); err != nil {
+		return nil, fmt.Errorf("githubvariable: validation failed: %w", err)
+	}
+
+	if hasOrganization && hasRepository {
+		return nil, errors.New("githubvariable: both organization and repository are specified")
+	}
+	if hasOrganization && hasEnvironment {
+		return nil, errors.New("githubvariable: environment requires repository")
+	}
+
+	id := services.GitHubIdentityFromContext(ctx).WithHost(host)
+	ctx = services.WithGitHubIdentity(ctx, id)
+
+	var p *Plan
+	switch {
+	case hasOrganization:
+		p = &Plan{backend: b, id: id, org: organization, name: name}
+	case hasRepository:
+		owner, repo, ok := strings.Cut(repository, "/")
+		if !ok {
+			return nil, fmt.Errorf("githubvariable: invalid repository name %q", repository)
+		}
+		p = &Plan{backend: b, id: id, owner: owner, repo: repo, env: environment, name: name}
+		if hasEnvironment {
+			ghRepo, err := b.opts.GetGitHubRepo(ctx, owner, repo)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get GitHub repo: %w", err)
+			}
+			p.repoID = ghRepo.GetID()
+		}
+	default:
+		return nil, errors.New("githubvariable: one of organization or repository is required")
+	}
+
+	value, err := b.opts.ReadOnePassword(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret from 1password: %w", err)
+	}
+	p.value = string(value)
+
+	current, err := p.get(ctx)
+	if isNotFound(err) {
+		// the variable is not found.
+		// we should create it.
+		return []backends.Plan{p}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub variable: %w", err)
+	}
+
+	if current.Value == p.value {
+		// the variable is up-to-date.
+		return []backends.Plan{backends.NewNoopPlan(p.Target())}, nil
+	}
+	p.updatedAt = updatedAt(current)
+	p.overwrite = true
+	return []backends.Plan{p}, nil
+}
+
+func isNotFound(err error) bool {
+	var ghErr *github.ErrorResponse
+	return errors.As(err, &ghErr) && ghErr.Response.StatusCode == http.StatusNotFound
+}
+
+// updatedAt returns the time when the variable was updated.
+func updatedAt(variable *github.ActionsVariable) time.Time {
+	if variable.UpdatedAt == nil {
+		return time.Time{}
+	}
+	return variable.UpdatedAt.Time
+}
+
+// planJSON is the serialized form of the plans.
+type planJSON struct {
+	Identity  services.GitHubIdentity `json:"identity,omitzero"`
+	Owner     string                  `json:"owner,omitempty"`
+	Repo      string                  `json:"repo,omitempty"`
+	RepoID    int64                   `json:"repo_id,omitempty"`
+	Env       string                  `json:"env,omitempty"`
+	Org       string                  `json:"org,omitempty"`
+	Name      string                  `json:"name"`
+	Value     string                  `json:"value"`
+	UpdatedAt time.Time               `json:"updated_at"`
+	Overwrite bool                    `json:"overwrite"`
+}
+
+func (b *Backend) UnmarshalPlan(data []byte) (backends.Plan, error) {
+	var v planJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("githubvariable: failed to unmarshal the plan: %w", err)
+	}
+	return &Plan{
+		backend:   b,
+		id:        v.Identity,
+		owner:     v.Owner,
+		repo:      v.Repo,
+		repoID:    v.RepoID,
+		env:       v.Env,
+		org:       v.Org,
+		name:      v.Name,
+		value:     v.Value,
+		updatedAt: v.UpdatedAt,
+		overwrite: v.Overwrite,
+	}, nil
+}
+
+var _ backends.Plan = (*Plan)(nil)
+
+// Plan is a plan to create or update the variable.
+// The variable is an organization variable if org is set,
+// an environment variable if env is set, and a repository variable otherwise.
+type Plan struct {
+	backend *Backend
+	id      services.GitHubIdentity
+	owner   string
+	repo    string
+	repoID  int64
+	env     string
+	org     string
+	name    string
+	value   string
+
+	// updatedAt is the time when the variable was updated when the plan was made.
+	updatedAt time.Time
+	overwrite bool
+}
+
+func (p *Plan) get(ctx context.Context) (*github.ActionsVariable, error) {
+	switch {
+	case p.org != "":
+		return p.backend.opts.GetGitHubOrgVariable(ctx, p.org, p.name)
+	case p.env != "":
+		return p.backend.opts.GetGitHubEnvVariable(ctx, int(p.repoID), p.env, p.name)
+	default:
+		return p.backend.opts.GetGitHubRepoVariable(ctx, p.owner, p.repo, p.name)
+	}
+}
+
+func (p *Plan) Preview() string {
+	var where string
+	switch {
+	case p.org != "":
+		where = fmt.Sprintf("organization %s%s", p.id.TargetPrefix(), p.org)
+	case p.env != "":
+		where = fmt.Sprintf("%s%s/%s environment %s", p.id.TargetPrefix(), p.owner, p.repo, p.env)
+	default:
+		where = fmt.Sprintf("%s%s/%s", p.id.TargetPrefix(), p.owner, p.repo)
+	}
+	if p.overwrite {
+		return fmt.Sprintf("variable %q in %s will be updated", p.name, where)
+	}
+	return fmt.Sprintf("variable %q in %s will be created", p.name, where)
+}
+
+func (p *Plan) Action() backends.Action {
+	if p.overwrite {
+		return backends.ActionUpdate
+	}
+	return backends.ActionCreate
+}
+
+func (p *Plan) Target() string {
+	switch {
+	case p.org != "":
+		return fmt.Sprintf("%sorgs/%s/actions/variables/%s", p.id.TargetPrefix(), p.org, p.name)
+	case p.env != "":
+		return fmt.Sprintf("%srepos/%s/%s/environments/%s/variables/%s", p.id.TargetPrefix(), p.owner, p.repo, p.env, p.name)
+	default:
+		return fmt.Sprintf("%srepos/%s/%s/actions/variables/%s", p.id.TargetPrefix(), p.owner, p.repo, p.name)
+	}
+}
+
+func (p *Plan) Reason() string {
+	if p.overwrite {
+		return "the value of the variable differs"
+	}
+	return "the variable does not exist"
+}
+
+func (p *Plan) Verify(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
+	variable, err := p.get(ctx)
+	if isNotFound(err) {
+		if p.overwrite {
+			return fmt.Errorf("the variable was removed: %w", backends.ErrStalePlan)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !p.overwrite || !updatedAt(variable).Equal(p.updatedAt) {
+		return fmt.Errorf("the variable was updated at %s: %w", updatedAt(variable), backends.ErrStalePlan)
+	}
+	return nil
+}
+
+func (p *Plan) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Identity:  p.id,
+		Owner:     p.owner,
+		Repo:      p.repo,
+		RepoID:    p.repoID,
+		Env:       p.env,
+		Org:       p.org,
+		Name:      p.name,
+		Value:     p.value,
+		UpdatedAt: p.updatedAt,
+		Overwrite: p.overwrite,
+	})
+}
+
+func (p *Plan) Apply(ctx context.Context) error {
+	ctx = services.WithGitHubIdentity(ctx, p.id)
+	variable := &github.ActionsVariable{
+		Name:  p.name,
+		Value: p.value,
+	}
+	opts := p.backend.opts
+	switch {
+	case p.org != "":
+		if p.overwrite {
+			return opts.UpdateGitHubOrgVariable(ctx, p.org, variable)
+		}
+		// the same default as organization secrets.
+		variable.Visibility = github.String("selected")
+		variable.SelectedRepositoryIDs = &github.SelectedRepoIDs{}
+		return opts.CreateGitHubOrgVariable(ctx, p.org, variable)
+	case p.env != "":
+		if p.overwrite {
+			return opts.UpdateGitHubEnvVariable(ctx, int(p.repoID), p.env, variable)
+		}
+		return opts.CreateGitHubEnvVariable(ctx, int(p.repoID), p.env, variable)
+	default:
+		if p.overwrite {
+			return opts.UpdateGitHubRepoVariable(ctx, p.owner, p.repo, variable)
+		}
+		return opts.CreateGitHubRepoVariable(ctx, p.owner, p.repo, variable)
+	}
+}
@@ -0,0 +1,273 @@
+package githubvariable
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/shogo82148/op-sync/internal/backends"
+	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/mock"
+)
+
+func notFound() error {
+	return &github.ErrorResponse{
+		Response: &http.Response{
+			StatusCode: http.StatusNotFound,
+		},
+	}
+}
+
+func TestPlan_RepoVariable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var created *github.ActionsVariable
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("ap-northeast-1"), nil
+		}),
+		GitHubRepoVariableGetter: mock.GitHubRepoVariableGetter(func(ctx context.Context, owner, repo, name string) (*github.ActionsVariable, error) {
+			return nil, notFound()
+		}),
+		GitHubRepoVariableCreator: mock.GitHubRepoVariableCreator(func(ctx context.Context, owner, repo string, variable *github.ActionsVariable) error {
+			if owner != "shogo82148" || repo != "op-sync" {
+				t.Errorf("unexpected repository: want shogo82148/op-sync, got %s/%s", owner, repo)
+			}
+			created = variable
+			return nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"repository": "shogo82148/op-sync",
+		"name":       "AWS_REGION",
+		"source":     "op://vault/item/region",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plan
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Action(), backends.ActionCreate; got != want {
+		t.Errorf("unexpected action: want %q, got %q", want, got)
+	}
+	if got, want := plans[0].Target(), "repos/shogo82148/op-sync/actions/variables/AWS_REGION"; got != want {
+		t.Errorf("unexpected target: want %q, got %q", want, got)
+	}
+
+	// apply the plan
+	if err := plans[0].Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if created == nil {
+		t.Fatal("the variable is not created")
+	}
+	if created.Name != "AWS_REGION" || created.Value != "ap-northeast-1" {
+		t.Errorf("unexpected variable: %s=%s", created.Name, created.Value)
+	}
+}
+
+func TestPlan_RepoVariable_Host(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tests := []struct {
+		host   string
+		id     services.GitHubIdentity
+		target string
+	}{
+		// the explicit github.com is the same as the default host.
+		{"github.com", services.GitHubIdentity{}, "repos/shogo82148/op-sync/actions/variables/AWS_REGION"},
+		{"github.example.com", services.GitHubIdentity{Host: "github.example.com"}, "github.example.com/repos/shogo82148/op-sync/actions/variables/AWS_REGION"},
+	}
+	for _, tt := range tests {
+		var ids []services.GitHubIdentity
+		b := New(&Options{
+			OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+				return []byte("ap-northeast-1"), nil
+			}),
+			GitHubRepoVariableGetter: mock.GitHubRepoVariableGetter(func(ctx context.Context, owner, repo, name string) (*github.ActionsVariable, error) {
+				ids = append(ids, services.GitHubIdentityFromContext(ctx))
+				return nil, notFound()
+			}),
+		})
+
+		plans, err := b.Plan(ctx, map[string]any{
+			"repository":  "shogo82148/op-sync",
+			"name":        "AWS_REGION",
+			"source":      "op://vault/item/region",
+			"github_host": tt.host,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := plans[0].Target(); got != tt.target {
+			t.Errorf("%s: unexpected target: want %q, got %q", tt.host, tt.target, got)
+		}
+		if len(ids) != 1 || ids[0] != tt.id {
+			t.Errorf("%s: unexpected identities: %#v", tt.host, ids)
+		}
+	}
+}
+
+func TestPlan_RepoVariable_UpToDate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("ap-northeast-1"), nil
+		}),
+		GitHubRepoVariableGetter: mock.GitHubRepoVariableGetter(func(ctx context.Context, owner, repo, name string) (*github.ActionsVariable, error) {
+			return &github.ActionsVariable{
+				Name:  name,
+				Value: "ap-northeast-1",
+			}, nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"repository": "shogo82148/op-sync",
+		"name":       "AWS_REGION",
+		"source":     "op://vault/item/region",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plan
+	if len(plans) != 1 || plans[0].Action() != backends.ActionNoop {
+		t.Fatalf("unexpected plans: want one noop plan, got %v", plans)
+	}
+}
+
+func TestPlan_EnvVariable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updatedAt := &github.Timestamp{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var updated *github.ActionsVariable
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("ap-northeast-1"), nil
+		}),
+		GitHubRepoGetter: mock.GitHubRepoGetter(func(ctx context.Context, owner, repo string) (*github.Repository, error) {
+			return &github.Repository{
+				ID: github.Int64(123),
+			}, nil
+		}),
+		GitHubEnvVariableGetter: mock.GitHubEnvVariableGetter(func(ctx context.Context, repoID int, env, name string) (*github.ActionsVariable, error) {
+			if repoID != 123 || env != "production" {
+				t.Errorf("unexpected environment: %d %s", repoID, env)
+			}
+			return &github.ActionsVariable{
+				Name:      name,
+				Value:     "us-east-1",
+				UpdatedAt: updatedAt,
+			}, nil
+		}),
+		GitHubEnvVariableUpdater: mock.GitHubEnvVariableUpdater(func(ctx context.Context, repoID int, env string, variable *github.ActionsVariable) error {
+			updated = variable
+			return nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"repository":  "shogo82148/op-sync",
+		"environment": "production",
+		"name":        "AWS_REGION",
+		"source":      "op://vault/item/region",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plan
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Action(), backends.ActionUpdate; got != want {
+		t.Errorf("unexpected action: want %q, got %q", want, got)
+	}
+	if got, want := plans[0].Target(), "repos/shogo82148/op-sync/environments/production/variables/AWS_REGION"; got != want {
+		t.Errorf("unexpected target: want %q, got %q", want, got)
+	}
+
+	// the plan survives serialization.
+	data, err := plans[0].MarshalPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := b.UnmarshalPlan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Verify(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// apply the plan
+	if err := plan.Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if updated == nil || updated.Value != "ap-northeast-1" {
+		t.Errorf("unexpected variable: %v", updated)
+	}
+}
+
+func TestPlan_OrgVariable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var created *github.ActionsVariable
+	b := New(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			return []byte("ap-northeast-1"), nil
+		}),
+		GitHubOrgVariableGetter: mock.GitHubOrgVariableGetter(func(ctx context.Context, org, name string) (*github.ActionsVariable, error) {
+			return nil, notFound()
+		}),
+		GitHubOrgVariableCreator: mock.GitHubOrgVariableCreator(func(ctx context.Context, org string, variable *github.ActionsVariable) error {
+			if org != "my-org" {
+				t.Errorf("unexpected organization: want my-org, got %s", org)
+			}
+			created = variable
+			return nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"organization": "my-org",
+		"name":         "AWS_REGION",
+		"source":       "op://vault/item/region",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verify the plan
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if got, want := plans[0].Target(), "orgs/my-org/actions/variables/AWS_REGION"; got != want {
+		t.Errorf("unexpected target: want %q, got %q", want, got)
+	}
+
+	// apply the plan
+	if err := plans[0].Apply(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if created == nil || created.Value != "ap-northeast-1" || created.GetVisibility() != "selected" {
+		t.Errorf("unexpected variable: %v", created)
+	}
+}
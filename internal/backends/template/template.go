@@ -4,6 +4,9 @@ package template
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -43,6 +46,7 @@ func (b *Backend) Plan(ctx context.Context, params map[string]any) ([]backends.P
 	}
 
 	var overwrite bool
+	var oldHash string
 	oldData, err := os.ReadFile(output)
 	if err != nil {
 		if !errors.Is(err, fs.ErrNotExist) {
@@ -51,28 +55,57 @@ func (b *Backend) Plan(ctx context.Context, params map[string]any) ([]backends.P
 	} else {
 		overwrite = true
 		if bytes.Equal(oldData, newData) {
-			return []backends.Plan{}, nil
+			return []backends.Plan{backends.NewNoopPlan(output)}, nil
 		}
+		oldHash = hash(oldData)
 	}
 	return []backends.Plan{
 		&Plan{
 			backend:   b,
 			output:    output,
 			newData:   newData,
+			oldHash:   oldHash,
 			overwrite: overwrite,
 		},
 	}, nil
 }
 
+func (b *Backend) UnmarshalPlan(data []byte) (backends.Plan, error) {
+	var v planJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("template: failed to unmarshal the plan: %w", err)
+	}
+	return &Plan{
+		backend:   b,
+		output:    v.Output,
+		newData:   v.NewData,
+		oldHash:   v.OldHash,
+		overwrite: v.Overwrite,
+	}, nil
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 var _ backends.Plan = (*Plan)(nil)
 
 type Plan struct {
 	backend   *Backend
 	output    string
 	newData   []byte
+	oldHash   string
 	overwrite bool
 }
 
+type planJSON struct {
+	Output    string `json:"output"`
+	NewData   []byte `json:"new_data"`
+	OldHash   string `json:"old_hash,omitempty"`
+	Overwrite bool   `json:"overwrite"`
+}
+
 func (p *Plan) Preview() string {
 	if p.overwrite {
 		return fmt.Sprintf("file %q will be updated", p.output)
@@ -80,6 +113,50 @@ func (p *Plan) Preview() string {
 	return fmt.Sprintf("file %q will be created", p.output)
 }
 
+func (p *Plan) Action() backends.Action {
+	if p.overwrite {
+		return backends.ActionUpdate
+	}
+	return backends.ActionCreate
+}
+
+func (p *Plan) Target() string {
+	return p.output
+}
+
+func (p *Plan) Reason() string {
+	if p.overwrite {
+		return "the content of the file differs"
+	}
+	return "the file does not exist"
+}
+
+func (p *Plan) Verify(ctx context.Context) error {
+	data, err := os.ReadFile(p.output)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if p.overwrite {
+			return fmt.Errorf("file %q was removed: %w", p.output, backends.ErrStalePlan)
+		}
+		return nil
+	}
+	if !p.overwrite || hash(data) != p.oldHash {
+		return fmt.Errorf("file %q was changed: %w", p.output, backends.ErrStalePlan)
+	}
+	return nil
+}
+
+func (p *Plan) MarshalPlan() ([]byte, error) {
+	return json.Marshal(planJSON{
+		Output:    p.output,
+		NewData:   p.newData,
+		OldHash:   p.oldHash,
+		Overwrite: p.overwrite,
+	})
+}
+
 func (p *Plan) Apply(ctx context.Context) error {
 	tmp := fmt.Sprintf("%s.%d.tmp", p.output, os.Getpid())
 	defer os.Remove(tmp)
@@ -2,10 +2,12 @@ package template
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/shogo82148/op-sync/internal/backends"
 	"github.com/shogo82148/op-sync/internal/services/mock"
 )
 
@@ -88,8 +90,8 @@ func TestPlan_UpToDate(t *testing.T) {
 	}
 
 	// verify the plan
-	if len(plans) != 0 {
-		t.Fatalf("unexpected length: want 0, got %d", len(plans))
+	if len(plans) != 1 || plans[0].Action() != backends.ActionNoop {
+		t.Fatalf("unexpected plans: want one noop plan, got %v", plans)
 	}
 }
 
@@ -149,3 +151,54 @@ func TestPlan_Updated(t *testing.T) {
 	}
 
 }
+
+func TestPlan_Stale(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	tmp := filepath.Join(dir, "output.txt")
+
+	if err := os.WriteFile(tmp, []byte("old template"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := New(&Options{
+		Injector: mock.Injector(func(ctx context.Context, template string) ([]byte, error) {
+			return []byte("template"), nil
+		}),
+	})
+
+	// do planning
+	plans, err := b.Plan(ctx, map[string]any{
+		"output":   tmp,
+		"template": "template",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+
+	// serialize the plan
+	data, err := plans[0].MarshalPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := b.UnmarshalPlan(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Verify(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// the file is changed after planning
+	if err := os.WriteFile(tmp, []byte("changed by someone"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Verify(ctx); !errors.Is(err, backends.ErrStalePlan) {
+		t.Errorf("want ErrStalePlan, got %v", err)
+	}
+}
@@ -5,10 +5,109 @@ import (
 	"os"
 
 	"github.com/goccy/go-yaml"
+	"github.com/shogo82148/op-sync/internal/services"
 )
 
 type Config struct {
-	Secrets map[string]map[string]any `yaml:"secrets"`
+	OnePassword OnePasswordConfig         `yaml:"onepassword"`
+	GitHub      GitHubConfig              `yaml:"github"`
+	AWS         AWSConfig                 `yaml:"aws"`
+	State       StateConfig               `yaml:"state"`
+	Secrets     map[string]map[string]any `yaml:"secrets"`
+}
+
+// OnePasswordConfig is the configuration of 1Password.
+type OnePasswordConfig struct {
+	// ConnectHost is the URL of 1Password Connect server.
+	// If both ConnectHost and OP_CONNECT_HOST are empty, 1Password CLI is used.
+	ConnectHost string `yaml:"connect_host"`
+
+	// ConnectTokenEnv is the name of the environment variable
+	// that has the token of 1Password Connect server.
+	// The default is OP_CONNECT_TOKEN.
+	ConnectTokenEnv string `yaml:"connect_token_env"`
+
+	// Account is the account of 1Password CLI, e.g. my.1password.com.
+	// It can be overridden by the onepassword.account parameter of each secret.
+	// op-sync fails if the signed-in account differs from it.
+	Account string `yaml:"account"`
+
+	// ServiceAccountTokenEnv is the name of the environment variable
+	// that has the token of the 1Password service account.
+	ServiceAccountTokenEnv string `yaml:"service_account_token_env"`
+}
+
+// GitHubConfig is the configuration of GitHub.
+type GitHubConfig struct {
+	// Host is the host of GitHub Enterprise Server, e.g. github.example.com.
+	// It can be overridden by the github_host parameter of each secret.
+	// The default is github.com.
+	Host string `yaml:"host"`
+
+	// TokenEnv is the name of the environment variable that has the token, e.g. GITHUB_TOKEN.
+	// If both TokenEnv and App are empty, the token of GitHub CLI is used.
+	TokenEnv string `yaml:"token_env"`
+
+	// App is the GitHub App that generates installation tokens.
+	App *GitHubAppConfig `yaml:"app"`
+
+	// MaxConcurrentRequests is the maximum number of concurrent requests to GitHub.
+	// The default is 8.
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests"`
+
+	// Prune is the scopes where -prune deletes the secrets that are not in the configuration,
+	// e.g. {repository: owner/repo}. GitHub secrets have no description,
+	// so the secrets created by hand in these scopes are deleted, too.
+	Prune []map[string]any `yaml:"prune"`
+}
+
+// GitHubAppConfig is the configuration of the GitHub App.
+type GitHubAppConfig struct {
+	AppID          int64 `yaml:"app_id"`
+	InstallationID int64 `yaml:"installation_id"`
+
+	// PrivateKey is the secret reference to the private key, e.g. op://vault/item/private-key.
+	PrivateKey string `yaml:"private_key"`
+}
+
+// identity returns the GitHub identity of the configuration.
+func (cfg *GitHubConfig) identity() services.GitHubIdentity {
+	id := services.GitHubIdentity{
+		Host:     cfg.Host,
+		TokenEnv: cfg.TokenEnv,
+	}
+	if cfg.App != nil {
+		id.AppID = cfg.App.AppID
+		id.AppInstallationID = cfg.App.InstallationID
+		id.AppPrivateKey = cfg.App.PrivateKey
+	}
+	return id
+}
+
+// AWSConfig is the configuration of AWS.
+type AWSConfig struct {
+	// EndpointURL is the URL of the endpoint of the AWS services, e.g. http://localhost:4566 of LocalStack.
+	// It can be overridden by the endpoint_url parameter of each secret.
+	// If it is empty, AWS_ENDPOINT_URL and AWS_ENDPOINT_URL_<SERVICE> are used.
+	EndpointURL string `yaml:"endpoint_url"`
+
+	// Regions are the allowed regions that region: "*" of the secrets expands to.
+	Regions []string `yaml:"regions"`
+}
+
+// DefaultStatePath is the default path of the state file.
+const DefaultStatePath = ".op-sync.state.json"
+
+// StateConfig is the configuration of the state that op-sync records.
+// The state keeps the keyed hashes of the values of GitHub secrets to detect changes accurately.
+type StateConfig struct {
+	// Path is the path of the state file.
+	// The default is DefaultStatePath.
+	Path string `yaml:"path"`
+
+	// HMACKey is the secret reference to the key of the hashes, e.g. op://vault/item/field.
+	// The state is disabled if it is empty.
+	HMACKey string `yaml:"hmac_key"`
 }
 
 func ParseConfig(filename string) (*Config, error) {
@@ -0,0 +1,104 @@
+package opsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/shogo82148/op-sync/internal/backends"
+)
+
+const (
+	// FormatText is the human-readable output format.
+	FormatText = "text"
+
+	// FormatJSON is the machine-readable output format.
+	FormatJSON = "json"
+)
+
+// PlanDocument is the machine-readable representation of plans.
+// It never contains any secret.
+type PlanDocument struct {
+	Plans []*PlanEntry `json:"plans"`
+}
+
+// PlanEntry is the machine-readable representation of a plan.
+type PlanEntry struct {
+	// Key is the key of the secret in the configuration file.
+	// It is empty if the plan deletes the target that is no longer in the configuration.
+	Key string `json:"key,omitempty"`
+
+	// Type is the type of the backend.
+	Type string `json:"type"`
+
+	// Target is the identifier of the target.
+	Target string `json:"target,omitempty"`
+
+	// Action is the kind of the change.
+	Action backends.Action `json:"action"`
+
+	// Reason is why the change is needed.
+	Reason string `json:"reason,omitempty"`
+}
+
+// NewPlanDocument converts the plans into the machine-readable representation.
+func NewPlanDocument(plans []*SecretPlan) *PlanDocument {
+	entries := make([]*PlanEntry, 0, len(plans))
+	for _, plan := range plans {
+		entries = append(entries, &PlanEntry{
+			Key:    plan.Key,
+			Type:   plan.Type,
+			Target: plan.Target(),
+			Action: plan.Action(),
+			Reason: plan.Reason(),
+		})
+	}
+	return &PlanDocument{
+		Plans: entries,
+	}
+}
+
+// writeText writes the previews of the changes,
+// and the summary of the skipped secrets at the end.
+func writeText(w io.Writer, plans []*SecretPlan) error {
+	if err := writeChanges(w, filterChanges(plans)); err != nil {
+		return err
+	}
+
+	skips := filterSkips(plans)
+	if len(skips) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\nThe following %d secret(s) were skipped:\n", len(skips)); err != nil {
+		return err
+	}
+	for _, plan := range skips {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", plan.name(), plan.Preview()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeChanges(w io.Writer, plans []*SecretPlan) error {
+	if len(plans) == 0 {
+		_, err := fmt.Fprintln(w, "No changes will be applied.")
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, "The following changes will be applied:"); err != nil {
+		return err
+	}
+	for _, plan := range plans {
+		if _, err := fmt.Fprintln(w, plan.Preview()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, plans []*SecretPlan) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewPlanDocument(plans))
+}
@@ -0,0 +1,114 @@
+package opsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/op-sync/internal/backends"
+)
+
+type testPlan struct {
+	action backends.Action
+	target string
+	reason string
+}
+
+func (p *testPlan) Preview() string                  { return "preview of " + p.target }
+func (p *testPlan) Action() backends.Action          { return p.action }
+func (p *testPlan) Target() string                   { return p.target }
+func (p *testPlan) Reason() string                   { return p.reason }
+func (p *testPlan) Verify(ctx context.Context) error { return nil }
+func (p *testPlan) MarshalPlan() ([]byte, error)     { return []byte("{}"), nil }
+func (p *testPlan) Apply(ctx context.Context) error  { return nil }
+
+func TestWriteJSON(t *testing.T) {
+	plans := []*SecretPlan{
+		{
+			Plan: &testPlan{
+				action: backends.ActionCreate,
+				target: "shogo82148/op-sync",
+				reason: "the secret does not exist",
+			},
+			Key:  "MyPassword",
+			Type: "github",
+		},
+		{
+			Plan: backends.NewNoopPlan(".envrc"),
+			Key:  "MyFile",
+			Type: "template",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, plans); err != nil {
+		t.Fatal(err)
+	}
+
+	var got PlanDocument
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := PlanDocument{
+		Plans: []*PlanEntry{
+			{
+				Key:    "MyPassword",
+				Type:   "github",
+				Target: "shogo82148/op-sync",
+				Action: backends.ActionCreate,
+				Reason: "the secret does not exist",
+			},
+			{
+				Key:    "MyFile",
+				Type:   "template",
+				Target: ".envrc",
+				Action: backends.ActionNoop,
+				Reason: "the secret is up-to-date",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected result (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	plans := []*SecretPlan{
+		{
+			Plan: &testPlan{
+				action: backends.ActionCreate,
+				target: "shogo82148/op-sync",
+			},
+			Key:  "MyPassword",
+			Type: "github",
+		},
+		{
+			Plan: backends.NewNoopPlan(".envrc"),
+			Key:  "MyFile",
+			Type: "template",
+		},
+		{
+			Plan: backends.NewSkipPlan(
+				"arn:aws:ssm:ap-northeast-1:123456789012:parameter/foo",
+				"credentials are for account 210987654321",
+			),
+			Key:  "MyParameter",
+			Type: "aws-ssm",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeText(&buf, plans); err != nil {
+		t.Fatal(err)
+	}
+	want := "The following changes will be applied:\n" +
+		"preview of shogo82148/op-sync\n" +
+		"\n" +
+		"The following 1 secret(s) were skipped:\n" +
+		"MyParameter: arn:aws:ssm:ap-northeast-1:123456789012:parameter/foo is skipped: credentials are for account 210987654321\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("unexpected result (-want +got):\n%s", diff)
+	}
+}
@@ -2,6 +2,7 @@ package opsync
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -9,15 +10,30 @@ import (
 
 	"github.com/Songmu/prompter"
 	"github.com/shogo82148/op-sync/internal/backends"
+	"github.com/shogo82148/op-sync/internal/services"
 	"github.com/shogo82148/op-sync/internal/services/awssecretsmanager"
 	"github.com/shogo82148/op-sync/internal/services/awsssm"
 	"github.com/shogo82148/op-sync/internal/services/awssts"
 	"github.com/shogo82148/op-sync/internal/services/gh"
 	"github.com/shogo82148/op-sync/internal/services/op"
+	"github.com/shogo82148/op-sync/internal/services/opconnect"
+	"github.com/shogo82148/op-sync/internal/services/secrethash"
+	"github.com/shogo82148/op-sync/internal/services/statefile"
 )
 
 var app = New()
 
+// ErrDrift is returned by [App.Run] with the detailed exit code mode
+// when the targets drift from 1Password.
+var ErrDrift = errors.New("opsync: the targets drift from 1Password")
+
+// exit codes
+const (
+	exitCodeOK    = 0
+	exitCodeError = 1
+	exitCodeDrift = 2
+)
+
 type App struct {
 	// Config is file path.
 	Config string
@@ -34,11 +50,30 @@ type App struct {
 	// Type is the type of the secret to sync.
 	Type string
 
+	// Format is the output format of plans. "text" or "json".
+	Format string
+
+	// Parallelism is the maximum number of secrets planned concurrently.
+	Parallelism int
+
+	// Prune enables deleting the targets that are no longer in the configuration.
+	Prune bool
+
+	// DetailedExitCode enables the detailed exit code mode.
+	// It plans without prompting and applying, and reports drift by [ErrDrift].
+	DetailedExitCode bool
+
+	// Strict makes the skipped secrets errors.
+	Strict bool
+
+	// Rotate enables staging the new values of AWS Secrets Manager secrets before promoting them.
+	Rotate bool
+
 	fset *flag.FlagSet
 }
 
 func New() *App {
-	fset := flag.NewFlagSet("op-sync", flag.ExitOnError)
+	fset := flag.NewFlagSet("op-sync", flag.ContinueOnError)
 	app := &App{
 		fset: fset,
 	}
@@ -47,18 +82,36 @@ func New() *App {
 	fset.BoolVar(&app.Debug, "debug", false, "enable debug log")
 	fset.BoolVar(&app.Force, "force", false, "enable force mode")
 	fset.StringVar(&app.Type, "type", "", "the type of the secret to sync")
+	fset.StringVar(&app.Format, "format", FormatText, "output format of plans: text or json. json never prompts, and applies plans only with -force")
+	fset.IntVar(&app.Parallelism, "parallelism", 4, "the maximum number of secrets planned concurrently")
+	fset.BoolVar(&app.Prune, "prune", false, "delete the targets that op-sync created but are no longer in the config file")
+	fset.BoolVar(&app.DetailedExitCode, "detailed-exitcode", false, "plan only, and exit with 0 if no changes, 2 if there are changes, 1 on errors")
+	fset.BoolVar(&app.Strict, "strict", false, "fail if some secrets are skipped, e.g. the AWS credentials are for another account")
+	fset.BoolVar(&app.Rotate, "rotate", false, "stage the new values of AWS Secrets Manager secrets as AWSPENDING, and promote them to AWSCURRENT in the next run")
 	return app
 }
 
 func Run(ctx context.Context, args []string) int {
+	// the flag sets continue on errors, because flag.ExitOnError exits with 2,
+	// which means drift in the detailed exit code mode.
 	if err := app.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitCodeOK
+		}
 		slog.ErrorContext(ctx, "op-sync error", slog.String("error", err.Error()))
+		return exitCodeError
 	}
 	if err := app.Run(ctx); err != nil {
+		if errors.Is(err, ErrDrift) {
+			return exitCodeDrift
+		}
+		if errors.Is(err, flag.ErrHelp) {
+			return exitCodeOK
+		}
 		slog.ErrorContext(ctx, "op-sync error", slog.String("error", err.Error()))
-		return 1
+		return exitCodeError
 	}
-	return 0
+	return exitCodeOK
 }
 
 // Parse parses command line arguments.
@@ -82,51 +135,308 @@ func (app *App) Run(ctx context.Context) error {
 		slog.SetDefault(slog.New(h))
 	}
 
+	if app.Format != FormatText && app.Format != FormatJSON {
+		return fmt.Errorf("unknown format %q", app.Format)
+	}
+	if app.Parallelism < 1 {
+		return fmt.Errorf("invalid parallelism %d", app.Parallelism)
+	}
+
+	args := app.fset.Args()
+	if len(args) > 0 {
+		switch args[0] {
+		case "plan":
+			return app.runPlan(ctx, args[1:])
+		case "apply":
+			return app.runApply(ctx, args[1:])
+		case "show":
+			return app.runShow(ctx, args[1:])
+		}
+	}
+	return app.runSync(ctx, args)
+}
+
+func (app *App) newPlanner(ctx context.Context) (*Planner, error) {
 	// parse configure file
 	slog.DebugContext(ctx, "parse config", slog.String("path", app.Config))
 	cfg, err := ParseConfig(app.Config)
 	if err != nil {
-		return fmt.Errorf("failed to parse %q: %w", app.Config, err)
+		return nil, fmt.Errorf("failed to parse %q: %w", app.Config, err)
 	}
 
-	planner := NewPlanner(&PlannerOptions{
+	onePassword, err := newOnePassword(&cfg.OnePassword)
+	if err != nil {
+		return nil, err
+	}
+
+	gitHub := gh.NewService(&gh.Options{
+		OnePasswordReader:     onePassword,
+		Account:               cfg.OnePassword.Account,
+		MaxConcurrentRequests: cfg.GitHub.MaxConcurrentRequests,
+	})
+
+	opts := &PlannerOptions{
 		Config:            cfg,
-		OnePassword:       op.NewService(),
-		GitHub:            gh.NewService(),
+		OnePassword:       onePassword,
+		GitHub:            gitHub,
 		AWSSTS:            awssts.New(),
 		AWSSSM:            awsssm.New(),
 		AWSSecretsManager: awssecretsmanager.New(),
-	})
+		Parallelism:       app.Parallelism,
+		Prune:             app.Prune,
+		Rotate:            app.Rotate,
+	}
+	if cfg.State.HMACKey != "" {
+		path := cfg.State.Path
+		if path == "" {
+			path = DefaultStatePath
+		}
+		opts.State = statefile.New(path)
+		opts.SecretHasher = secrethash.New(onePassword, cfg.State.HMACKey, cfg.OnePassword.Account)
+	}
+	return NewPlanner(opts), nil
+}
+
+// newOnePassword returns the 1Password service.
+// It uses 1Password Connect server if it is configured, otherwise 1Password CLI.
+func newOnePassword(cfg *OnePasswordConfig) (services.OnePassword, error) {
+	host := cfg.ConnectHost
+	if host == "" {
+		host = os.Getenv(opconnect.HostEnv)
+	}
+	if host == "" {
+		var token string
+		if cfg.ServiceAccountTokenEnv != "" {
+			token = os.Getenv(cfg.ServiceAccountTokenEnv)
+			if token == "" {
+				return nil, fmt.Errorf("environment variable %s is required to use the 1Password service account", cfg.ServiceAccountTokenEnv)
+			}
+		}
+		return op.NewService(&op.Options{
+			ServiceAccountToken: token,
+		}), nil
+	}
+
+	tokenEnv := cfg.ConnectTokenEnv
+	if tokenEnv == "" {
+		tokenEnv = opconnect.TokenEnv
+	}
+	token := os.Getenv(tokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("environment variable %s is required to use 1Password Connect server", tokenEnv)
+	}
+	return opconnect.NewService(host, token), nil
+}
 
-	var plans []backends.Plan
-	if app.Type != "" {
+func (app *App) plan(ctx context.Context, planner *Planner, secrets []string) ([]*SecretPlan, error) {
+	var plans []*SecretPlan
+	var err error
+	switch {
+	case app.Type != "":
 		plans, err = planner.PlanWithType(ctx, app.Type)
-	} else if app.fset.NArg() == 0 {
+	case len(secrets) == 0:
 		plans, err = planner.Plan(ctx)
-	} else {
-		plans, err = planner.PlanWithSecrets(ctx, app.fset.Args())
+	default:
+		plans, err = planner.PlanWithSecrets(ctx, secrets)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if app.Strict {
+		if err := checkSkips(plans); err != nil {
+			return nil, err
+		}
+	}
+	return plans, nil
+}
+
+// runSync plans and applies the changes.
+func (app *App) runSync(ctx context.Context, secrets []string) error {
+	planner, err := app.newPlanner(ctx)
+	if err != nil {
+		return err
 	}
+	plans, err := app.plan(ctx, planner, secrets)
 	if err != nil {
 		return err
 	}
+	if app.DetailedExitCode {
+		if err := app.writePlans(plans); err != nil {
+			return err
+		}
+		return detectDrift(plans)
+	}
+	return app.confirmAndApply(ctx, plans)
+}
 
-	if len(plans) == 0 {
-		fmt.Println("No changes will be applied.")
-		return nil
+// runPlan plans the changes, and saves them into the plan file.
+func (app *App) runPlan(ctx context.Context, args []string) error {
+	var out string
+	fset := flag.NewFlagSet("op-sync plan", flag.ContinueOnError)
+	fset.StringVar(&out, "out", "", "the path to save the plan file")
+	fset.StringVar(&app.Config, "config", app.Config, "config file path")
+	fset.StringVar(&app.Type, "type", app.Type, "the type of the secret to sync")
+	fset.IntVar(&app.Parallelism, "parallelism", app.Parallelism, "the maximum number of secrets planned concurrently")
+	fset.BoolVar(&app.Prune, "prune", app.Prune, "delete the targets that op-sync created but are no longer in the config file")
+	fset.BoolVar(&app.DetailedExitCode, "detailed-exitcode", app.DetailedExitCode, "exit with 0 if no changes, 2 if there are changes, 1 on errors")
+	fset.BoolVar(&app.Strict, "strict", app.Strict, "fail if some secrets are skipped, e.g. the AWS credentials are for another account")
+	fset.BoolVar(&app.Rotate, "rotate", app.Rotate, "stage the new values of AWS Secrets Manager secrets as AWSPENDING, and promote them to AWSCURRENT in the next run")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if out == "" {
+		return errors.New("-out is required")
 	}
 
-	fmt.Println("The following changes will be applied:")
+	planner, err := app.newPlanner(ctx)
+	if err != nil {
+		return err
+	}
+	plans, err := app.plan(ctx, planner, fset.Args())
+	if err != nil {
+		return err
+	}
+	if err := app.writePlans(plans); err != nil {
+		return err
+	}
+	if err := writePlanFile(out, plans); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "the plan is saved", slog.String("path", out))
+	if app.DetailedExitCode {
+		return detectDrift(plans)
+	}
+	return nil
+}
+
+// runApply applies the changes saved in the plan file.
+func (app *App) runApply(ctx context.Context, args []string) error {
+	fset := flag.NewFlagSet("op-sync apply", flag.ContinueOnError)
+	fset.StringVar(&app.Config, "config", app.Config, "config file path")
+	fset.BoolVar(&app.Force, "force", app.Force, "enable force mode")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() != 1 {
+		return errors.New("usage: op-sync apply [options] <planfile>")
+	}
+
+	planner, err := app.newPlanner(ctx)
+	if err != nil {
+		return err
+	}
+	plans, err := readPlanFile(fset.Arg(0), planner)
+	if err != nil {
+		return err
+	}
+
+	// refuse stale plans
+	errs := []error{}
 	for _, plan := range plans {
-		fmt.Println(plan.Preview())
+		if err := plan.Verify(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", plan.name(), err))
+		}
+	}
+	if len(errs) != 0 {
+		return errors.Join(errs...)
 	}
 
-	if !app.Force {
-		if !prompter.YN("Do you want to continue?", false) {
-			return nil
+	return app.confirmAndApply(ctx, plans)
+}
+
+// runShow shows the changes saved in the plan file.
+func (app *App) runShow(ctx context.Context, args []string) error {
+	fset := flag.NewFlagSet("op-sync show", flag.ContinueOnError)
+	fset.StringVar(&app.Format, "format", app.Format, "output format of plans: text or json")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() != 1 {
+		return errors.New("usage: op-sync show [options] <planfile>")
+	}
+	if app.Format != FormatText && app.Format != FormatJSON {
+		return fmt.Errorf("unknown format %q", app.Format)
+	}
+
+	planner := NewPlanner(&PlannerOptions{})
+	plans, err := readPlanFile(fset.Arg(0), planner)
+	if err != nil {
+		return err
+	}
+	return app.writePlans(plans)
+}
+
+// writePlans writes the plans in the format.
+func (app *App) writePlans(plans []*SecretPlan) error {
+	if app.Format == FormatJSON {
+		return writeJSON(os.Stdout, plans)
+	}
+	return writeText(os.Stdout, plans)
+}
+
+// filterChanges returns the plans that make changes.
+func filterChanges(plans []*SecretPlan) []*SecretPlan {
+	ret := make([]*SecretPlan, 0, len(plans))
+	for _, plan := range plans {
+		if action := plan.Action(); action != backends.ActionNoop && action != backends.ActionSkip {
+			ret = append(ret, plan)
 		}
 	}
+	return ret
+}
 
+// filterSkips returns the plans whose targets were skipped.
+func filterSkips(plans []*SecretPlan) []*SecretPlan {
+	ret := make([]*SecretPlan, 0)
 	for _, plan := range plans {
+		if plan.Action() == backends.ActionSkip {
+			ret = append(ret, plan)
+		}
+	}
+	return ret
+}
+
+// checkSkips returns an error if some targets were skipped.
+func checkSkips(plans []*SecretPlan) error {
+	errs := []error{}
+	for _, plan := range filterSkips(plans) {
+		errs = append(errs, fmt.Errorf("%s: %s is skipped: %s", plan.name(), plan.Target(), plan.Reason()))
+	}
+	return errors.Join(errs...)
+}
+
+// detectDrift returns ErrDrift if some plans make changes.
+func detectDrift(plans []*SecretPlan) error {
+	if len(filterChanges(plans)) > 0 {
+		return ErrDrift
+	}
+	return nil
+}
+
+// confirmAndApply shows the plans, and applies them after the confirmation.
+func (app *App) confirmAndApply(ctx context.Context, plans []*SecretPlan) error {
+	if err := app.writePlans(plans); err != nil {
+		return err
+	}
+
+	changes := filterChanges(plans)
+	switch app.Format {
+	case FormatJSON:
+		if !app.Force {
+			return nil
+		}
+	default:
+		if len(changes) == 0 {
+			return nil
+		}
+		if !app.Force {
+			if !prompter.YN("Do you want to continue?", false) {
+				return nil
+			}
+		}
+	}
+
+	for _, plan := range changes {
 		if err := plan.Apply(ctx); err != nil {
 			return err
 		}
@@ -0,0 +1,54 @@
+package opsync
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shogo82148/op-sync/internal/backends"
+)
+
+func TestDetectDrift(t *testing.T) {
+	noop := &SecretPlan{Plan: backends.NewNoopPlan(".envrc"), Key: "MyFile", Type: "template"}
+	create := &SecretPlan{Plan: &testPlan{action: backends.ActionCreate}, Key: "MyPassword", Type: "github"}
+	skip := &SecretPlan{Plan: backends.NewSkipPlan("arn:aws:ssm:ap-northeast-1:123456789012:parameter/foo", "credentials are for account 210987654321"), Key: "MyParameter", Type: "aws-ssm"}
+
+	if err := detectDrift([]*SecretPlan{noop}); err != nil {
+		t.Errorf("want nil, got %v", err)
+	}
+	if err := detectDrift([]*SecretPlan{noop, skip}); err != nil {
+		t.Errorf("want nil, got %v", err)
+	}
+	if err := detectDrift([]*SecretPlan{noop, create}); !errors.Is(err, ErrDrift) {
+		t.Errorf("want ErrDrift, got %v", err)
+	}
+}
+
+func TestCheckSkips(t *testing.T) {
+	create := &SecretPlan{Plan: &testPlan{action: backends.ActionCreate}, Key: "MyPassword", Type: "github"}
+	skip := &SecretPlan{Plan: backends.NewSkipPlan("arn:aws:ssm:ap-northeast-1:123456789012:parameter/foo", "credentials are for account 210987654321"), Key: "MyParameter", Type: "aws-ssm"}
+
+	if err := checkSkips([]*SecretPlan{create}); err != nil {
+		t.Errorf("want nil, got %v", err)
+	}
+	err := checkSkips([]*SecretPlan{create, skip})
+	want := "MyParameter: arn:aws:ssm:ap-northeast-1:123456789012:parameter/foo is skipped: credentials are for account 210987654321"
+	if err == nil || err.Error() != want {
+		t.Errorf("want %q, got %v", want, err)
+	}
+}
+
+func TestRun_InvalidFlag(t *testing.T) {
+	tests := [][]string{
+		{"-detailed-exitcode", "-unknown"},
+		{"plan", "-unknown"},
+		{"apply", "-unknown"},
+		{"show", "-unknown"},
+	}
+	for _, args := range tests {
+		// the usage errors must not be reported as drift.
+		if got := Run(context.Background(), args); got != exitCodeError {
+			t.Errorf("Run(%q) = %d, want %d", args, got, exitCodeError)
+		}
+	}
+}
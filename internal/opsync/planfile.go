@@ -0,0 +1,156 @@
+package opsync
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// PlanPassphraseEnv is the name of the environment variable
+// that holds the passphrase to encrypt plan files.
+const PlanPassphraseEnv = "OP_SYNC_PLAN_PASSPHRASE"
+
+// planFileMagic is the header of plan files.
+var planFileMagic = []byte("op-sync plan v1\n")
+
+const (
+	planFileSaltSize  = 32
+	planFileNonceSize = 24
+)
+
+// planFile is the serialized form of the plans.
+type planFile struct {
+	Plans []*planFileEntry `json:"plans"`
+}
+
+type planFileEntry struct {
+	Key  string          `json:"key"`
+	Type string          `json:"type"`
+	Plan json.RawMessage `json:"plan"`
+}
+
+func planPassphrase() ([]byte, error) {
+	passphrase := os.Getenv(PlanPassphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("opsync: environment variable %s is required to encrypt plan files", PlanPassphraseEnv)
+	}
+	return []byte(passphrase), nil
+}
+
+func planFileKey(passphrase, salt []byte) (*[32]byte, error) {
+	derived, err := scrypt.Key(passphrase, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	var key [32]byte
+	copy(key[:], derived)
+	return &key, nil
+}
+
+// writePlanFile serializes the plans, and writes them into the file encrypted.
+// The plans that make no changes are omitted.
+func writePlanFile(filename string, plans []*SecretPlan) error {
+	passphrase, err := planPassphrase()
+	if err != nil {
+		return err
+	}
+
+	var v planFile
+	for _, plan := range filterChanges(plans) {
+		data, err := plan.MarshalPlan()
+		if err != nil {
+			return fmt.Errorf("opsync: failed to marshal the plan of %q: %w", plan.name(), err)
+		}
+		v.Plans = append(v.Plans, &planFileEntry{
+			Key:  plan.Key,
+			Type: plan.Type,
+			Plan: data,
+		})
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("opsync: failed to marshal the plans: %w", err)
+	}
+
+	var salt [planFileSaltSize]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return err
+	}
+	var nonce [planFileNonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	key, err := planFileKey(passphrase, salt[:])
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, len(planFileMagic)+len(salt)+len(nonce)+len(data)+secretbox.Overhead)
+	buf = append(buf, planFileMagic...)
+	buf = append(buf, salt[:]...)
+	buf = append(buf, nonce[:]...)
+	buf = secretbox.Seal(buf, data, &nonce, key)
+
+	tmp := fmt.Sprintf("%s.%d.tmp", filename, os.Getpid())
+	defer os.Remove(tmp)
+	if err := os.WriteFile(tmp, buf, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// readPlanFile reads the encrypted plans from the file.
+func readPlanFile(filename string, planner *Planner) ([]*SecretPlan, error) {
+	passphrase, err := planPassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("opsync: failed to read %q: %w", filename, err)
+	}
+	if !bytes.HasPrefix(buf, planFileMagic) {
+		return nil, fmt.Errorf("opsync: %q is not a plan file", filename)
+	}
+	buf = buf[len(planFileMagic):]
+	if len(buf) < planFileSaltSize+planFileNonceSize+secretbox.Overhead {
+		return nil, fmt.Errorf("opsync: %q is truncated", filename)
+	}
+	salt := buf[:planFileSaltSize]
+	var nonce [planFileNonceSize]byte
+	copy(nonce[:], buf[planFileSaltSize:])
+	key, err := planFileKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := secretbox.Open(nil, buf[planFileSaltSize+planFileNonceSize:], &nonce, key)
+	if !ok {
+		return nil, fmt.Errorf("opsync: failed to decrypt %q: wrong passphrase or broken file", filename)
+	}
+
+	var v planFile
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("opsync: failed to unmarshal the plans: %w", err)
+	}
+	plans := make([]*SecretPlan, 0, len(v.Plans))
+	errs := []error{}
+	for _, entry := range v.Plans {
+		plan, err := planner.UnmarshalPlan(entry.Key, entry.Type, entry.Plan)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		plans = append(plans, plan)
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	return plans, nil
+}
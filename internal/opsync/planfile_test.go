@@ -0,0 +1,44 @@
+package opsync
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/shogo82148/op-sync/internal/backends"
+)
+
+func TestPlanFile(t *testing.T) {
+	t.Setenv(PlanPassphraseEnv, "very-secret-passphrase")
+
+	dir := t.TempDir()
+	planner := NewPlanner(&PlannerOptions{})
+	plan, err := planner.UnmarshalPlan("MyFile", "template", []byte(`{"output":"output.txt","new_data":"dGVtcGxhdGU=","overwrite":false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(dir, "plan.bin")
+	if err := writePlanFile(filename, []*SecretPlan{plan, {Plan: backends.NewNoopPlan("repos/shogo82148/op-sync/actions/secrets/MY_PASSWORD"), Key: "MyPassword", Type: "github"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	plans, err := readPlanFile(filename, planner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("unexpected length: want 1, got %d", len(plans))
+	}
+	if plans[0].Key != "MyFile" || plans[0].Type != "template" {
+		t.Errorf("unexpected plan: want MyFile/template, got %s/%s", plans[0].Key, plans[0].Type)
+	}
+	if got, want := plans[0].Preview(), `file "output.txt" will be created`; got != want {
+		t.Errorf("unexpected preview: want %q, got %q", want, got)
+	}
+
+	// wrong passphrase
+	t.Setenv(PlanPassphraseEnv, "wrong-passphrase")
+	if _, err := readPlanFile(filename, planner); err == nil {
+		t.Error("want error, got nil")
+	}
+}
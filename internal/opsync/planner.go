@@ -5,91 +5,271 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net/url"
+	"regexp"
 	"slices"
+	"strings"
+	"sync"
 
 	"github.com/shogo82148/op-sync/internal/backends"
 	"github.com/shogo82148/op-sync/internal/backends/awssecretsmanager"
 	"github.com/shogo82148/op-sync/internal/backends/awsssm"
 	"github.com/shogo82148/op-sync/internal/backends/github"
+	"github.com/shogo82148/op-sync/internal/backends/githubvariable"
 	"github.com/shogo82148/op-sync/internal/backends/template"
 	"github.com/shogo82148/op-sync/internal/maputils"
+	"github.com/shogo82148/op-sync/internal/services"
 	svcsecretsmanager "github.com/shogo82148/op-sync/internal/services/awssecretsmanager"
 	svcssm "github.com/shogo82148/op-sync/internal/services/awsssm"
 	"github.com/shogo82148/op-sync/internal/services/awssts"
 	"github.com/shogo82148/op-sync/internal/services/gh"
 	"github.com/shogo82148/op-sync/internal/services/op"
+	"github.com/shogo82148/op-sync/internal/services/opcache"
+	"github.com/shogo82148/op-sync/internal/services/secrethash"
+	"github.com/shogo82148/op-sync/internal/services/statefile"
 )
 
 type Planner struct {
 	cfg      *PlannerOptions
+	op       *opcache.Cache
 	backends map[string]backends.Backend
 }
 
 type PlannerOptions struct {
 	Config            *Config
-	OnePassword       *op.Service
+	OnePassword       services.OnePassword
 	GitHub            *gh.Service
 	AWSSTS            *awssts.Service
 	AWSSSM            *svcssm.Service
 	AWSSecretsManager *svcsecretsmanager.Service
+
+	// State and SecretHasher are optional.
+	// If both are set, the changes of GitHub secrets are detected by the state.
+	State        *statefile.Service
+	SecretHasher *secrethash.Service
+
+	// Parallelism is the maximum number of secrets planned concurrently.
+	Parallelism int
+
+	// Prune enables planning to delete the targets that are no longer in the configuration.
+	Prune bool
+
+	// Rotate enables staging the new values of AWS Secrets Manager secrets before promoting them.
+	Rotate bool
 }
 
 func NewPlanner(cfg *PlannerOptions) *Planner {
+	// dedupe the reads from 1password during the run.
+	op := opcache.New(cfg.OnePassword)
+
+	githubOpts := &github.Options{
+		OnePasswordItemGetter: op,
+		OnePasswordReader:     op,
+
+		GitHubRepoGetter:                    cfg.GitHub,
+		GitHubReposLister:                   cfg.GitHub,
+		GitHubRepoSecretGetter:              cfg.GitHub,
+		GitHubRepoSecretCreator:             cfg.GitHub,
+		GitHubRepoPublicKeyGetter:           cfg.GitHub,
+		GitHubUserByLoginGetter:             cfg.GitHub,
+		GitHubTeamGetter:                    cfg.GitHub,
+		GitHubEnvGetter:                     cfg.GitHub,
+		GitHubEnvCreator:                    cfg.GitHub,
+		GitHubDeploymentBranchPolicyCreator: cfg.GitHub,
+		GitHubEnvSecretGetter:               cfg.GitHub,
+		GitHubEnvSecretCreator:              cfg.GitHub,
+		GitHubEnvPublicKeyGetter:            cfg.GitHub,
+		GitHubOrgSecretGetter:               cfg.GitHub,
+		GitHubOrgSecretCreator:              cfg.GitHub,
+		GitHubOrgPublicKeyGetter:            cfg.GitHub,
+		GitHubReposIDForOrgSecretLister:     cfg.GitHub,
+		GitHubRepoSecretsLister:             cfg.GitHub,
+		GitHubRepoSecretDeleter:             cfg.GitHub,
+		GitHubEnvSecretsLister:              cfg.GitHub,
+		GitHubEnvSecretDeleter:              cfg.GitHub,
+		GitHubOrgSecretsLister:              cfg.GitHub,
+		GitHubOrgSecretDeleter:              cfg.GitHub,
+		GitHubUserSecretGetter:              cfg.GitHub,
+		GitHubUserSecretCreator:             cfg.GitHub,
+		GitHubUserPublicKeyGetter:           cfg.GitHub,
+		GitHubReposIDForUserSecretLister:    cfg.GitHub,
+		GitHubUserSecretsLister:             cfg.GitHub,
+		GitHubUserSecretDeleter:             cfg.GitHub,
+	}
+	if cfg.Config != nil {
+		githubOpts.PruneScopes = cfg.Config.GitHub.Prune
+	}
+	if cfg.State != nil && cfg.SecretHasher != nil {
+		githubOpts.SecretHasher = cfg.SecretHasher
+		githubOpts.StateGetter = cfg.State
+		githubOpts.StatePutter = cfg.State
+		githubOpts.StateDeleter = cfg.State
+	}
+
+	// the saved plans are applied without the configuration file.
+	// they have the endpoints and the regions in themselves.
+	var awsConfig AWSConfig
+	if cfg.Config != nil {
+		awsConfig = cfg.Config.AWS
+	}
+
 	return &Planner{
 		cfg: cfg,
+		op:  op,
 		backends: map[string]backends.Backend{
 			"template": template.New(&template.Options{
-				Injector: cfg.OnePassword,
+				Injector: op,
 			}),
-			"github": github.New(&github.Options{
-				OnePasswordItemGetter: cfg.OnePassword,
-				OnePasswordReader:     cfg.OnePassword,
-
-				GitHubRepoGetter:                cfg.GitHub,
-				GitHubRepoSecretGetter:          cfg.GitHub,
-				GitHubRepoSecretCreator:         cfg.GitHub,
-				GitHubRepoPublicKeyGetter:       cfg.GitHub,
-				GitHubEnvSecretGetter:           cfg.GitHub,
-				GitHubEnvSecretCreator:          cfg.GitHub,
-				GitHubEnvPublicKeyGetter:        cfg.GitHub,
-				GitHubOrgSecretGetter:           cfg.GitHub,
-				GitHubOrgSecretCreator:          cfg.GitHub,
-				GitHubOrgPublicKeyGetter:        cfg.GitHub,
-				GitHubReposIDForOrgSecretLister: cfg.GitHub,
+			"github": github.New(githubOpts),
+			"github-variable": githubvariable.New(&githubvariable.Options{
+				OnePasswordReader: op,
+
+				GitHubRepoGetter:          cfg.GitHub,
+				GitHubRepoVariableGetter:  cfg.GitHub,
+				GitHubRepoVariableCreator: cfg.GitHub,
+				GitHubRepoVariableUpdater: cfg.GitHub,
+				GitHubEnvVariableGetter:   cfg.GitHub,
+				GitHubEnvVariableCreator:  cfg.GitHub,
+				GitHubEnvVariableUpdater:  cfg.GitHub,
+				GitHubOrgVariableGetter:   cfg.GitHub,
+				GitHubOrgVariableCreator:  cfg.GitHub,
+				GitHubOrgVariableUpdater:  cfg.GitHub,
 			}),
 			"aws-ssm": awsssm.New(&awsssm.Options{
-				OnePasswordReader: cfg.OnePassword,
+				OnePasswordReader:     op,
+				OnePasswordItemGetter: op,
 
 				STSCallerIdentityGetter: cfg.AWSSTS,
 
 				SSMParameterGetter: cfg.AWSSSM,
 				SSMParameterPutter: cfg.AWSSSM,
+				SSMTagsLister:      cfg.AWSSSM,
+				SSMTagsAdder:       cfg.AWSSSM,
+
+				SSMParametersDescriber: cfg.AWSSSM,
+				SSMParameterDeleter:    cfg.AWSSSM,
+
+				EndpointURL: awsConfig.EndpointURL,
+				Regions:     awsConfig.Regions,
 			}),
 			"aws-secrets-manager": awssecretsmanager.New(&awssecretsmanager.Options{
-				OnePasswordReader: cfg.OnePassword,
+				OnePasswordReader: op,
 
 				STSCallerIdentityGetter: cfg.AWSSTS,
 
-				SecretsManagerSecretCreator: cfg.AWSSecretsManager,
-				SecretsManagerSecretGetter:  cfg.AWSSecretsManager,
-				SecretsManagerSecretUpdater: cfg.AWSSecretsManager,
+				SecretsManagerSecretCreator:        cfg.AWSSecretsManager,
+				SecretsManagerSecretGetter:         cfg.AWSSecretsManager,
+				SecretsManagerSecretUpdater:        cfg.AWSSecretsManager,
+				SecretsManagerSecretDescriber:      cfg.AWSSecretsManager,
+				SecretsManagerResourcePolicyGetter: cfg.AWSSecretsManager,
+				SecretsManagerResourcePolicyPutter: cfg.AWSSecretsManager,
+				SecretsManagerResourceTagger:       cfg.AWSSecretsManager,
+				SecretsManagerSecretReplicator:     cfg.AWSSecretsManager,
+				SecretsManagerSecretValuePutter:    cfg.AWSSecretsManager,
+				SecretsManagerVersionStageUpdater:  cfg.AWSSecretsManager,
+				SecretsManagerSecretRotator:        cfg.AWSSecretsManager,
+				SecretsManagerRotationCanceler:     cfg.AWSSecretsManager,
+				SecretsManagerSecretsLister:        cfg.AWSSecretsManager,
+				SecretsManagerSecretDeleter:        cfg.AWSSecretsManager,
+
+				Rotate:      cfg.Rotate,
+				EndpointURL: awsConfig.EndpointURL,
+				Regions:     awsConfig.Regions,
 			}),
 		},
 	}
 }
 
-// check 1password cli is available.
-func (p *Planner) checkIsOPAvailable(ctx context.Context) error {
-	userInfo, err := p.cfg.OnePassword.WhoAmI(ctx)
-	if err != nil {
-		return err
+// check 1password cli is available, and the signed-in accounts are expected ones.
+func (p *Planner) checkIsOPAvailable(ctx context.Context, keys []string) error {
+	accounts := map[string]struct{}{}
+	for _, key := range keys {
+		account, err := p.onePasswordAccount(key)
+		if err != nil {
+			return err
+		}
+		accounts[account] = struct{}{}
+	}
+
+	for _, account := range slices.Sorted(maps.Keys(accounts)) {
+		userInfo, err := p.op.WhoAmI(services.WithOnePasswordAccount(ctx, account))
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "1password user information", slog.String("url", userInfo.URL), slog.String("email", userInfo.Email))
+		if account != "" && !matchOnePasswordAccount(userInfo, account) {
+			return fmt.Errorf("opsync: 1password account mismatch: want %q, but signed in to %s (%s)", account, userInfo.URL, userInfo.AccountUUID)
+		}
 	}
-	slog.InfoContext(ctx, "1password user information", slog.String("url", userInfo.URL), slog.String("email", userInfo.Email))
 	return nil
 }
 
+// onePasswordAccount returns the 1password account for the secret.
+func (p *Planner) onePasswordAccount(key string) (string, error) {
+	account := p.cfg.Config.OnePassword.Account
+	c := new(maputils.Context)
+	if cfg, ok := maputils.Get[map[string]any](c, p.cfg.Config.Secrets[key], "onepassword"); ok {
+		if override, ok := maputils.Get[string](c, cfg, "account"); ok {
+			account = override
+		}
+	}
+	if err := c.Err(); err != nil {
+		return "", fmt.Errorf("opsync: validation of %q failed: %w", key, err)
+	}
+	return account, nil
+}
+
+// matchOnePasswordAccount reports whether the user is signed in to the account.
+// The account is the shorthand, the sign-in address, the account ID, or the user ID.
+func matchOnePasswordAccount(user *services.OnePasswordUser, account string) bool {
+	host := user.URL
+	if u, err := url.Parse(user.URL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	for _, v := range []string{user.URL, host, user.Shorthand, user.AccountUUID, user.UserUUID} {
+		if v != "" && strings.EqualFold(v, account) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecretPlan is a plan for a secret in the configuration file.
+type SecretPlan struct {
+	backends.Plan
+
+	// Key is the key of the secret in the configuration file.
+	// It is empty if the plan deletes the target that is no longer in the configuration.
+	Key string
+
+	// Type is the type of the backend.
+	Type string
+}
+
+// name returns the human-readable name of the plan for error messages.
+func (p *SecretPlan) name() string {
+	if p.Key != "" {
+		return p.Key
+	}
+	return p.Target()
+}
+
+// UnmarshalPlan restores the plan of the secret serialized by [SecretPlan.MarshalPlan].
+func (p *Planner) UnmarshalPlan(key, typ string, data []byte) (*SecretPlan, error) {
+	backend, ok := p.backends[typ]
+	if !ok {
+		return nil, fmt.Errorf("opsync: backend for type %q not found", typ)
+	}
+	plan, err := backend.UnmarshalPlan(data)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretPlan{Plan: plan, Key: key, Type: typ}, nil
+}
+
 // Plan plans all secrets.
-func (p *Planner) Plan(ctx context.Context) ([]backends.Plan, error) {
+func (p *Planner) Plan(ctx context.Context) ([]*SecretPlan, error) {
 	s := p.cfg.Config.Secrets
 	keys := make([]string, 0, len(s))
 	for key := range s {
@@ -101,12 +281,12 @@ func (p *Planner) Plan(ctx context.Context) ([]backends.Plan, error) {
 }
 
 // PlanWithSecrets plans the specified secrets.
-func (p *Planner) PlanWithSecrets(ctx context.Context, secrets []string) ([]backends.Plan, error) {
+func (p *Planner) PlanWithSecrets(ctx context.Context, secrets []string) ([]*SecretPlan, error) {
 	return p.plan(ctx, secrets)
 }
 
 // PlanWithType plans the specified type secrets.
-func (p Planner) PlanWithType(ctx context.Context, type_ string) ([]backends.Plan, error) {
+func (p Planner) PlanWithType(ctx context.Context, type_ string) ([]*SecretPlan, error) {
 	if _, ok := p.backends[type_]; !ok {
 		return nil, fmt.Errorf("opsync: backend for type %q not found", type_)
 	}
@@ -128,12 +308,7 @@ func (p Planner) PlanWithType(ctx context.Context, type_ string) ([]backends.Pla
 	return p.plan(ctx, keys)
 }
 
-func (p *Planner) plan(ctx context.Context, secrets []string) ([]backends.Plan, error) {
-	// check 1password cli is available.
-	if err := p.checkIsOPAvailable(ctx); err != nil {
-		return nil, err
-	}
-
+func (p *Planner) plan(ctx context.Context, secrets []string) ([]*SecretPlan, error) {
 	// list the secrets
 	s := p.cfg.Config.Secrets
 	keys := make([]string, 0, len(secrets))
@@ -151,36 +326,201 @@ func (p *Planner) plan(ctx context.Context, secrets []string) ([]backends.Plan,
 		return nil, fmt.Errorf("opsync: unknown secrets %q", unknown)
 	}
 
-	// do planning
-	errs := []error{}
-	plans := make([]backends.Plan, 0, len(keys))
-	for _, key := range keys {
+	// the default GitHub identity. the secrets may override the host.
+	ctx = services.WithGitHubIdentity(ctx, p.cfg.Config.GitHub.identity())
+
+	// check 1password cli is available.
+	if err := p.checkIsOPAvailable(ctx, keys); err != nil {
+		return nil, err
+	}
+
+	// read the secrets from 1password at once.
+	p.prefetch(ctx, keys)
+
+	// do planning concurrently
+	parallelism := max(p.cfg.Parallelism, 1)
+	sem := make(chan struct{}, parallelism)
+	results := make([][]*SecretPlan, len(keys))
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		// select picks a free slot randomly even if ctx is canceled.
 		if err := ctx.Err(); err != nil {
+			wg.Wait()
 			return nil, err
 		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			results[i], errs[i] = p.planSecret(ctx, key)
+		})
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	plans := make([]*SecretPlan, 0, len(keys))
+	for _, result := range results {
+		plans = append(plans, result...)
+	}
+
+	if p.cfg.Prune {
+		pruned, err := p.prune(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, pruned...)
+	}
+	return plans, nil
+}
+
+// prune plans to delete the targets that are no longer in the configuration.
+// Only the backends of the planned secrets are pruned,
+// and the targets of all secrets in the configuration are kept.
+// If all secrets are planned, the scopes listed in github.prune are pruned
+// even if no GitHub secrets remain in the configuration.
+func (p *Planner) prune(ctx context.Context, keys []string) ([]*SecretPlan, error) {
+	types := map[string]struct{}{}
+	if len(keys) == len(p.cfg.Config.Secrets) && len(p.cfg.Config.GitHub.Prune) > 0 {
+		types["github"] = struct{}{}
+	}
+	for _, key := range keys {
+		c := new(maputils.Context)
+		typ := maputils.Must[string](c, p.cfg.Config.Secrets[key], "type")
+		if err := c.Err(); err != nil {
+			return nil, err
+		}
+		types[typ] = struct{}{}
+	}
 
-		slog.InfoContext(ctx, "planning", slog.String("key", key))
-		cfg := s[key]
+	cfgs := map[string][]map[string]any{}
+	for _, key := range slices.Sorted(maps.Keys(p.cfg.Config.Secrets)) {
+		cfg := p.cfg.Config.Secrets[key]
 		c := new(maputils.Context)
 		typ := maputils.Must[string](c, cfg, "type")
 		if err := c.Err(); err != nil {
 			return nil, err
 		}
+		cfgs[typ] = append(cfgs[typ], cfg)
+	}
 
-		backend, ok := p.backends[typ]
+	var plans []*SecretPlan
+	for _, typ := range slices.Sorted(maps.Keys(types)) {
+		pruner, ok := p.backends[typ].(backends.Pruner)
 		if !ok {
-			errs = append(errs, fmt.Errorf("opsync: backend for type %q not found", typ))
 			continue
 		}
-		plan, err := backend.Plan(ctx, cfg)
+		slog.InfoContext(ctx, "pruning", slog.String("type", typ))
+		pruned, err := pruner.Prune(ctx, cfgs[typ])
 		if err != nil {
-			errs = append(errs, err)
+			return nil, err
+		}
+		for _, pp := range pruned {
+			plans = append(plans, &SecretPlan{Plan: pp, Type: typ})
+		}
+	}
+	return plans, nil
+}
+
+// reference matches the secret reference in templates, e.g. {{ op://vault/item/field }}.
+var reference = regexp.MustCompile(`\{\{\s*(op://[^}]*?)\s*\}\}`)
+
+// prefetch reads the secrets that the secrets refer to with a single call per account.
+func (p *Planner) prefetch(ctx context.Context, keys []string) {
+	refs := map[string]map[string]struct{}{}
+	for _, key := range keys {
+		cfg := p.cfg.Config.Secrets[key]
+		if typ, _ := cfg["type"].(string); typ == "template" {
+			// templates are rendered by op inject as a whole, and don't read the references one by one.
+			continue
+		}
+
+		// the account is already validated by checkIsOPAvailable.
+		account, _ := p.onePasswordAccount(key)
+		if _, ok := refs[account]; !ok {
+			refs[account] = map[string]struct{}{}
+		}
+		collectReferences(cfg, refs[account])
+	}
+
+	for _, account := range slices.Sorted(maps.Keys(refs)) {
+		if len(refs[account]) == 0 {
 			continue
 		}
-		plans = append(plans, plan...)
+		uris := slices.Sorted(maps.Keys(refs[account]))
+
+		// failures are not fatal here. the secrets are read one by one later.
+		_ = p.op.Prefetch(services.WithOnePasswordAccount(ctx, account), uris)
+	}
+}
+
+// collectReferences collects the secret references in v.
+func collectReferences(v any, refs map[string]struct{}) {
+	switch v := v.(type) {
+	case string:
+		if strings.HasPrefix(v, "op://") {
+			// skip the references to the whole items, e.g. op://vault/item.
+			if _, err := op.ParseURI(v); err == nil {
+				refs[v] = struct{}{}
+			}
+			return
+		}
+		for _, m := range reference.FindAllStringSubmatch(v, -1) {
+			refs[m[1]] = struct{}{}
+		}
+	case []any:
+		for _, vv := range v {
+			collectReferences(vv, refs)
+		}
+	case map[string]any:
+		for k, vv := range v {
+			if k == "binary_source" {
+				// op inject can't embed binary files.
+				continue
+			}
+			collectReferences(vv, refs)
+		}
+	}
+}
+
+// planSecret plans the secret.
+func (p *Planner) planSecret(ctx context.Context, key string) ([]*SecretPlan, error) {
+	slog.InfoContext(ctx, "planning", slog.String("key", key))
+	cfg := p.cfg.Config.Secrets[key]
+	c := new(maputils.Context)
+	typ := maputils.Must[string](c, cfg, "type")
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+
+	backend, ok := p.backends[typ]
+	if !ok {
+		return nil, fmt.Errorf("opsync: backend for type %q not found", typ)
+	}
+	account, err := p.onePasswordAccount(key)
+	if err != nil {
+		return nil, err
+	}
+	ctx = services.WithOnePasswordAccount(ctx, account)
+	plan, err := backend.Plan(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan) == 0 {
+		// the backends report the up-to-date targets with noop plans,
+		// so the secret has no targets, e.g. the hierarchy of an item without fields.
+		return []*SecretPlan{{Plan: backends.NewNoopPlan(""), Key: key, Type: typ}}, nil
 	}
-	if len(errs) != 0 {
-		return nil, errors.Join(errs...)
+	plans := make([]*SecretPlan, 0, len(plan))
+	for _, pp := range plan {
+		plans = append(plans, &SecretPlan{Plan: pp, Key: key, Type: typ})
 	}
 	return plans, nil
 }
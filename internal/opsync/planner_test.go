@@ -0,0 +1,470 @@
+package opsync
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shogo82148/op-sync/internal/backends"
+	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/mock"
+)
+
+type onePassword struct {
+	mock.WhoAmIer
+	mock.Injector
+	mock.OnePasswordItemGetter
+	mock.OnePasswordReader
+}
+
+func TestPlan_OnePasswordAccount(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var accounts []string
+	planner := NewPlanner(&PlannerOptions{
+		Config: &Config{
+			OnePassword: OnePasswordConfig{
+				Account: "my.1password.com",
+			},
+			Secrets: map[string]map[string]any{
+				"MyFile": {
+					"type":     "template",
+					"output":   "output.txt",
+					"template": "{{ op://vault/item/field }}",
+					"onepassword": map[string]any{
+						"account": "other.1password.com",
+					},
+				},
+			},
+		},
+		OnePassword: &onePassword{
+			WhoAmIer: mock.WhoAmIer(func(ctx context.Context) (*services.OnePasswordUser, error) {
+				account := services.OnePasswordAccount(ctx)
+				accounts = append(accounts, account)
+				return &services.OnePasswordUser{
+					URL: "https://" + account,
+				}, nil
+			}),
+			Injector: mock.Injector(func(ctx context.Context, template string) ([]byte, error) {
+				if account := services.OnePasswordAccount(ctx); account != "other.1password.com" {
+					t.Errorf("unexpected account: want other.1password.com, got %q", account)
+				}
+				return []byte(template), nil
+			}),
+		},
+	})
+
+	if _, err := planner.Plan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0] != "other.1password.com" {
+		t.Errorf("unexpected accounts: %q", accounts)
+	}
+}
+
+func TestPlan_OnePasswordAccountMismatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	planner := NewPlanner(&PlannerOptions{
+		Config: &Config{
+			OnePassword: OnePasswordConfig{
+				Account: "my.1password.com",
+			},
+			Secrets: map[string]map[string]any{
+				"MyFile": {
+					"type":     "template",
+					"output":   "output.txt",
+					"template": "{{ op://vault/item/field }}",
+				},
+			},
+		},
+		OnePassword: &onePassword{
+			WhoAmIer: mock.WhoAmIer(func(ctx context.Context) (*services.OnePasswordUser, error) {
+				return &services.OnePasswordUser{
+					URL: "https://other.1password.com",
+				}, nil
+			}),
+			Injector: mock.Injector(func(ctx context.Context, template string) ([]byte, error) {
+				t.Error("unexpected inject")
+				return nil, nil
+			}),
+		},
+	})
+
+	if _, err := planner.Plan(ctx); err == nil {
+		t.Fatal("want error, got nil")
+	}
+}
+
+// pruner is a backend that plans nothing, and prunes the targets not in the configuration.
+type pruner struct {
+	targets []string
+}
+
+func (b *pruner) Plan(ctx context.Context, cfg map[string]any) ([]backends.Plan, error) {
+	return []backends.Plan{}, nil
+}
+
+func (b *pruner) UnmarshalPlan(data []byte) (backends.Plan, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (b *pruner) Prune(ctx context.Context, cfgs []map[string]any) ([]backends.Plan, error) {
+	names := map[string]bool{}
+	for _, cfg := range cfgs {
+		names[cfg["name"].(string)] = true
+	}
+	var plans []backends.Plan
+	for _, target := range b.targets {
+		if !names[target] {
+			plans = append(plans, &testPlan{action: backends.ActionDelete, target: target})
+		}
+	}
+	return plans, nil
+}
+
+func TestPlan_Prune(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	planner := NewPlanner(&PlannerOptions{
+		Config: &Config{
+			Secrets: map[string]map[string]any{
+				"Foo": {"type": "fake", "name": "foo"},
+				"Bar": {"type": "fake", "name": "bar"},
+			},
+		},
+		OnePassword: &onePassword{
+			WhoAmIer: mock.WhoAmIer(func(ctx context.Context) (*services.OnePasswordUser, error) {
+				return &services.OnePasswordUser{}, nil
+			}),
+			Injector: mock.Injector(func(ctx context.Context, template string) ([]byte, error) {
+				return []byte(template), nil
+			}),
+		},
+		Prune: true,
+	})
+	planner.backends["fake"] = &pruner{targets: []string{"foo", "bar", "baz"}}
+
+	// the secrets that are not planned are kept, too.
+	plans, err := planner.PlanWithSecrets(ctx, []string{"Foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("unexpected length: want 2, got %d", len(plans))
+	}
+	if plans[0].Key != "Foo" || plans[0].Action() != backends.ActionNoop {
+		t.Errorf("unexpected plan: %s %s", plans[0].Key, plans[0].Action())
+	}
+	if plans[1].Key != "" || plans[1].Type != "fake" || plans[1].Action() != backends.ActionDelete || plans[1].Target() != "baz" {
+		t.Errorf("unexpected plan: %q %s %s %s", plans[1].Key, plans[1].Type, plans[1].Action(), plans[1].Target())
+	}
+}
+
+func TestPlan_PruneGitHubScopes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	planner := NewPlanner(&PlannerOptions{
+		Config: &Config{
+			GitHub: GitHubConfig{
+				Prune: []map[string]any{{"repository": "shogo82148/op-sync"}},
+			},
+			Secrets: map[string]map[string]any{
+				"Foo": {"type": "fake", "name": "foo"},
+			},
+		},
+		OnePassword: &onePassword{
+			WhoAmIer: mock.WhoAmIer(func(ctx context.Context) (*services.OnePasswordUser, error) {
+				return &services.OnePasswordUser{}, nil
+			}),
+		},
+		Prune: true,
+	})
+	planner.backends["fake"] = &pruner{targets: []string{"foo"}}
+	planner.backends["github"] = &pruner{targets: []string{"removed"}}
+
+	// the last GitHub secret was removed from the configuration.
+	plans, err := planner.Plan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("unexpected length: want 2, got %d", len(plans))
+	}
+	if plans[1].Type != "github" || plans[1].Action() != backends.ActionDelete || plans[1].Target() != "removed" {
+		t.Errorf("unexpected plan: %s %s %s", plans[1].Type, plans[1].Action(), plans[1].Target())
+	}
+
+	// the other backends are not pruned when some of the secrets are planned.
+	plans, err = planner.PlanWithSecrets(ctx, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 0 {
+		t.Errorf("unexpected length: want 0, got %d", len(plans))
+	}
+}
+
+// reader is a backend that reads the source from 1password, and plans to write it to the target.
+type reader struct {
+	op services.OnePasswordReader
+}
+
+func (b *reader) Plan(ctx context.Context, cfg map[string]any) ([]backends.Plan, error) {
+	value, err := b.op.ReadOnePassword(ctx, cfg["source"].(string))
+	if err != nil {
+		return nil, err
+	}
+	return []backends.Plan{
+		&testPlan{action: backends.ActionCreate, target: cfg["name"].(string), reason: string(value)},
+	}, nil
+}
+
+func (b *reader) UnmarshalPlan(data []byte) (backends.Plan, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestPlan_Prefetch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var injects []string
+	planner := NewPlanner(&PlannerOptions{
+		Config: &Config{
+			Secrets: map[string]map[string]any{
+				"Foo": {"type": "fake", "name": "foo", "source": "op://vault/item/field"},
+				"Bar": {"type": "fake", "name": "bar", "source": "op://vault/item/field"},
+				"Baz": {"type": "fake", "name": "baz", "source": "op://vault/item/field"},
+				"Qux": {"type": "fake", "name": "qux", "source": "op://vault/item/field"},
+			},
+		},
+		OnePassword: &onePassword{
+			WhoAmIer: mock.WhoAmIer(func(ctx context.Context) (*services.OnePasswordUser, error) {
+				return &services.OnePasswordUser{}, nil
+			}),
+			Injector: mock.Injector(func(ctx context.Context, template string) ([]byte, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				injects = append(injects, template)
+				return []byte(reference.ReplaceAllString(template, "secret")), nil
+			}),
+			OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+				t.Errorf("unexpected read: %s", uri)
+				return nil, errors.New("unexpected read")
+			}),
+		},
+		Parallelism: 4,
+	})
+	planner.backends["fake"] = &reader{op: planner.op}
+
+	plans, err := planner.Plan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 4 {
+		t.Fatalf("unexpected length: want 4, got %d", len(plans))
+	}
+	for _, plan := range plans {
+		if plan.Reason() != "secret" {
+			t.Errorf("unexpected value of %s: %q", plan.Key, plan.Reason())
+		}
+	}
+
+	// the targets share a single op call.
+	if len(injects) != 1 {
+		t.Fatalf("unexpected op calls: want 1, got %d", len(injects))
+	}
+	if got := strings.Count(injects[0], "op://vault/item/field"); got != 1 {
+		t.Errorf("unexpected references: want 1, got %d", got)
+	}
+}
+
+func TestPlan_PrefetchSkipsTemplates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var injects []string
+	planner := NewPlanner(&PlannerOptions{
+		Config: &Config{
+			Secrets: map[string]map[string]any{
+				"MyFile": {
+					"type":     "template",
+					"output":   filepath.Join(t.TempDir(), "output.txt"),
+					"template": "{{ op://vault/item/field }}",
+				},
+			},
+		},
+		OnePassword: &onePassword{
+			WhoAmIer: mock.WhoAmIer(func(ctx context.Context) (*services.OnePasswordUser, error) {
+				return &services.OnePasswordUser{}, nil
+			}),
+			Injector: mock.Injector(func(ctx context.Context, template string) ([]byte, error) {
+				injects = append(injects, template)
+				return []byte(reference.ReplaceAllString(template, "secret")), nil
+			}),
+		},
+	})
+
+	if _, err := planner.Plan(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the template is rendered as a whole, without prefetching.
+	if len(injects) != 1 || injects[0] != "{{ op://vault/item/field }}" {
+		t.Errorf("unexpected op calls: %q", injects)
+	}
+}
+
+// sleeper is a backend that plans after the delay, or fails if the configuration says so.
+type sleeper struct {
+	mu      sync.Mutex
+	started []string
+}
+
+func (b *sleeper) Plan(ctx context.Context, cfg map[string]any) ([]backends.Plan, error) {
+	name := cfg["name"].(string)
+	b.mu.Lock()
+	b.started = append(b.started, name)
+	b.mu.Unlock()
+
+	if delay, ok := cfg["delay"].(time.Duration); ok {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if msg, ok := cfg["error"].(string); ok {
+		return nil, errors.New(msg)
+	}
+	return []backends.Plan{
+		&testPlan{action: backends.ActionCreate, target: name},
+	}, nil
+}
+
+func (b *sleeper) UnmarshalPlan(data []byte) (backends.Plan, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newSleeperPlanner(secrets map[string]map[string]any, parallelism int) (*Planner, *sleeper) {
+	planner := NewPlanner(&PlannerOptions{
+		Config: &Config{
+			Secrets: secrets,
+		},
+		OnePassword: &onePassword{
+			WhoAmIer: mock.WhoAmIer(func(ctx context.Context) (*services.OnePasswordUser, error) {
+				return &services.OnePasswordUser{}, nil
+			}),
+		},
+		Parallelism: parallelism,
+	})
+	backend := &sleeper{}
+	planner.backends["fake"] = backend
+	return planner, backend
+}
+
+func TestPlan_ParallelismOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the earlier secrets finish later.
+	planner, _ := newSleeperPlanner(map[string]map[string]any{
+		"A": {"type": "fake", "name": "a", "delay": 40 * time.Millisecond},
+		"B": {"type": "fake", "name": "b", "delay": 30 * time.Millisecond},
+		"C": {"type": "fake", "name": "c", "delay": 20 * time.Millisecond},
+		"D": {"type": "fake", "name": "d", "delay": 10 * time.Millisecond},
+		"E": {"type": "fake", "name": "e"},
+	}, 4)
+
+	plans, err := planner.Plan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys, targets []string
+	for _, plan := range plans {
+		keys = append(keys, plan.Key)
+		targets = append(targets, plan.Target())
+	}
+	if want := []string{"A", "B", "C", "D", "E"}; !slices.Equal(keys, want) {
+		t.Errorf("unexpected keys: want %q, got %q", want, keys)
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !slices.Equal(targets, want) {
+		t.Errorf("unexpected targets: want %q, got %q", want, targets)
+	}
+}
+
+func TestPlan_ParallelismErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	planner, backend := newSleeperPlanner(map[string]map[string]any{
+		"A": {"type": "fake", "name": "a", "error": "error of a"},
+		"B": {"type": "fake", "name": "b"},
+		"C": {"type": "fake", "name": "c", "error": "error of c"},
+		"D": {"type": "fake", "name": "d"},
+	}, 2)
+
+	_, err := planner.Plan(ctx)
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+
+	// all failures are reported, and the other secrets are planned.
+	for _, msg := range []string{"error of a", "error of c"} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("error %q doesn't contain %q", err, msg)
+		}
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf("unexpected error: %#v", err)
+	}
+	if len(backend.started) != 4 {
+		t.Errorf("unexpected started secrets: %q", backend.started)
+	}
+}
+
+func TestPlan_ParallelismCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	secrets := map[string]map[string]any{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		secrets[strings.ToUpper(name)] = map[string]any{"type": "fake", "name": name, "delay": time.Hour}
+	}
+	planner, backend := newSleeperPlanner(secrets, 2)
+
+	// cancel after the slots are filled.
+	go func() {
+		for {
+			backend.mu.Lock()
+			n := len(backend.started)
+			backend.mu.Unlock()
+			if n == 2 {
+				cancel()
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	_, err := planner.Plan(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: want %v, got %v", context.Canceled, err)
+	}
+
+	// the pending secrets are not planned.
+	slices.Sort(backend.started)
+	if want := []string{"a", "b"}; !slices.Equal(backend.started, want) {
+		t.Errorf("unexpected started secrets: want %q, got %q", want, backend.started)
+	}
+}
@@ -0,0 +1,34 @@
+package services
+
+import "context"
+
+// AWSConfig is the AWS credentials to access the AWS services.
+// The zero value means the default credentials.
+type AWSConfig struct {
+	// Profile is the name of the profile in the shared configuration files.
+	Profile string `json:"profile,omitempty"`
+
+	// RoleARN is the ARN of the IAM role to assume.
+	// ExternalID and RoleSessionName are used to assume the role.
+	RoleARN         string `json:"role_arn,omitempty"`
+	ExternalID      string `json:"external_id,omitempty"`
+	RoleSessionName string `json:"role_session_name,omitempty"`
+
+	// EndpointURL is the URL of the endpoint of the AWS services, e.g. http://localhost:4566 of LocalStack.
+	// It takes precedence over AWS_ENDPOINT_URL and AWS_ENDPOINT_URL_<SERVICE>.
+	EndpointURL string `json:"endpoint_url,omitempty"`
+}
+
+type awsConfigKey struct{}
+
+// WithAWSConfig returns a copy of ctx that specifies the AWS credentials to use.
+func WithAWSConfig(ctx context.Context, cfg AWSConfig) context.Context {
+	return context.WithValue(ctx, awsConfigKey{}, cfg)
+}
+
+// AWSConfigFromContext returns the AWS credentials specified by [WithAWSConfig].
+// It returns the zero value if no credentials are specified.
+func AWSConfigFromContext(ctx context.Context) AWSConfig {
+	cfg, _ := ctx.Value(awsConfigKey{}).(AWSConfig)
+	return cfg
+}
@@ -0,0 +1,71 @@
+// Package awsconfig loads the configurations of AWS SDK for [services.AWSConfig].
+package awsconfig
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/shogo82148/op-sync/internal/services"
+)
+
+// DefaultRoleSessionName is the session name of the assumed roles if it is not specified.
+const DefaultRoleSessionName = "op-sync"
+
+// Key is the key of the clients cached by the services.
+type Key struct {
+	Config services.AWSConfig
+	Region string
+}
+
+// Load loads the configuration of AWS SDK for c in the region.
+// The region of the profile or the environment is used if region is empty.
+//
+// The endpoint of c is set to BaseEndpoint of the configuration.
+// AWS SDK prefers AWS_ENDPOINT_URL_<SERVICE> to BaseEndpoint,
+// so the clients must apply [BaseEndpoint] to their options to give c precedence.
+func Load(ctx context.Context, c services.AWSConfig, region string) (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	if c.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(c.Profile))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	if c.EndpointURL != "" {
+		cfg.BaseEndpoint = aws.String(c.EndpointURL)
+	}
+	if c.RoleARN == "" {
+		return cfg, nil
+	}
+
+	client := sts.NewFromConfig(cfg, func(o *sts.Options) {
+		o.BaseEndpoint = BaseEndpoint(c, o.BaseEndpoint)
+	})
+	provider := stscreds.NewAssumeRoleProvider(client, c.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = cmp.Or(c.RoleSessionName, DefaultRoleSessionName)
+		if c.ExternalID != "" {
+			o.ExternalID = aws.String(c.ExternalID)
+		}
+	})
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+	return cfg, nil
+}
+
+// BaseEndpoint returns the endpoint of c if it is specified.
+// Otherwise, it returns resolved, the endpoint that AWS SDK resolved from
+// AWS_ENDPOINT_URL, AWS_ENDPOINT_URL_<SERVICE>, or the shared configuration files.
+func BaseEndpoint(c services.AWSConfig, resolved *string) *string {
+	if c.EndpointURL != "" {
+		return aws.String(c.EndpointURL)
+	}
+	return resolved
+}
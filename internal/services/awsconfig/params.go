@@ -0,0 +1,133 @@
+package awsconfig
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/shogo82148/op-sync/internal/maputils"
+	"github.com/shogo82148/op-sync/internal/services"
+)
+
+// ParseConfig parses the credentials and the endpoint of the entry of the AWS backends.
+// defaultEndpointURL is used if the entry has no endpoint_url.
+func ParseConfig(c *maputils.Context, params map[string]any, defaultEndpointURL string) services.AWSConfig {
+	profile, _ := maputils.Get[string](c, params, "profile")
+	roleARN, _ := maputils.Get[string](c, params, "role_arn")
+	externalID, _ := maputils.Get[string](c, params, "external_id")
+	roleSessionName, _ := maputils.Get[string](c, params, "role_session_name")
+	endpointURL, ok := maputils.Get[string](c, params, "endpoint_url")
+	if !ok {
+		endpointURL = defaultEndpointURL
+	}
+	return services.AWSConfig{
+		Profile:         profile,
+		RoleARN:         roleARN,
+		ExternalID:      externalID,
+		RoleSessionName: roleSessionName,
+		EndpointURL:     endpointURL,
+	}
+}
+
+// Validate validates the configuration parsed from the entry.
+func Validate(c services.AWSConfig) error {
+	if c.RoleARN == "" && (c.ExternalID != "" || c.RoleSessionName != "") {
+		return errors.New("external_id and role_session_name require role_arn")
+	}
+	if !validEndpointURL(c.EndpointURL) {
+		return fmt.Errorf("invalid endpoint_url %q", c.EndpointURL)
+	}
+	return nil
+}
+
+// validEndpointURL reports whether u is empty or an absolute URL.
+func validEndpointURL(u string) bool {
+	if u == "" {
+		return true
+	}
+	parsed, err := url.Parse(u)
+	return err == nil && parsed.Scheme != "" && parsed.Host != ""
+}
+
+// ParseRegions parses the regions of the entry of the AWS backends.
+// region: "*" expands to allowed, the allowed regions in aws.regions.
+func ParseRegions(c *maputils.Context, params map[string]any, allowed []string) ([]string, error) {
+	region, hasRegion := maputils.Get[string](c, params, "region")
+	list, hasRegions := maputils.Get[[]any](c, params, "regions")
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	if hasRegion == hasRegions {
+		return nil, errors.New("one of region or regions is required")
+	}
+	if hasRegion {
+		if region != "*" {
+			return []string{region}, nil
+		}
+		if len(allowed) == 0 {
+			return nil, errors.New(`region "*" requires the allowed regions in aws.regions`)
+		}
+		return slices.Clone(allowed), nil
+	}
+
+	regions := make([]string, 0, len(list))
+	for _, v := range list {
+		s, ok := v.(string)
+		if !ok || s == "" || s == "*" {
+			return nil, fmt.Errorf("invalid region %v in regions", v)
+		}
+		if !slices.Contains(regions, s) {
+			regions = append(regions, s)
+		}
+	}
+	if len(regions) == 0 {
+		return nil, errors.New("regions is empty")
+	}
+	return regions, nil
+}
+
+// Location is the account and the region where the resources are stored.
+type Location struct {
+	Account string
+	Region  string
+}
+
+func CompareLocation(a, b Location) int {
+	return cmp.Or(
+		strings.Compare(a.Account, b.Account),
+		strings.Compare(a.Region, b.Region),
+	)
+}
+
+// Accounts resolves the accounts of the credentials, and caches them.
+// It is not safe for concurrent use.
+type Accounts struct {
+	getter   services.STSCallerIdentityGetter
+	accounts map[services.AWSConfig]string
+}
+
+func NewAccounts(getter services.STSCallerIdentityGetter) *Accounts {
+	return &Accounts{
+		getter:   getter,
+		accounts: map[services.AWSConfig]string{},
+	}
+}
+
+// Get returns the account of the credentials c.
+func (a *Accounts) Get(ctx context.Context, c services.AWSConfig) (string, error) {
+	if account, ok := a.accounts[c]; ok {
+		return account, nil
+	}
+	id, err := a.getter.STSGetCallerIdentity(services.WithAWSConfig(ctx, c))
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	account := aws.ToString(id.Account)
+	a.accounts[c] = account
+	return account, nil
+}
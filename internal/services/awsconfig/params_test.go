@@ -0,0 +1,133 @@
+package awsconfig
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/shogo82148/op-sync/internal/maputils"
+	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/mock"
+)
+
+func TestParseCredentials(t *testing.T) {
+	c := new(maputils.Context)
+	got := ParseConfig(c, map[string]any{
+		"profile":           "my-profile",
+		"role_arn":          "arn:aws:iam::123456789012:role/op-sync",
+		"external_id":       "external-id",
+		"role_session_name": "session",
+	}, "http://localhost:4566")
+	if err := c.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := services.AWSConfig{
+		Profile:         "my-profile",
+		RoleARN:         "arn:aws:iam::123456789012:role/op-sync",
+		ExternalID:      "external-id",
+		RoleSessionName: "session",
+		EndpointURL:     "http://localhost:4566",
+	}
+	if got != want {
+		t.Errorf("unexpected config: want %#v, got %#v", want, got)
+	}
+	if err := Validate(got); err != nil {
+		t.Error(err)
+	}
+
+	noRole := got
+	noRole.RoleARN = ""
+	if err := Validate(noRole); err == nil {
+		t.Error("want error, got nil")
+	}
+
+	relative := got
+	relative.EndpointURL = "localhost:4566"
+	if err := Validate(relative); err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestParseConfig_EndpointURL(t *testing.T) {
+	c := new(maputils.Context)
+	got := ParseConfig(c, map[string]any{
+		"endpoint_url": "",
+	}, "http://localhost:4566")
+	if err := c.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the empty endpoint_url overrides the default, and means the endpoint of AWS.
+	if got.EndpointURL != "" {
+		t.Errorf("unexpected endpoint: %q", got.EndpointURL)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var profiles []string
+	accounts := NewAccounts(mock.STSCallerIdentityGetter(func(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
+		profile := services.AWSConfigFromContext(ctx).Profile
+		profiles = append(profiles, profile)
+		return &sts.GetCallerIdentityOutput{
+			Account: aws.String("account-of-" + profile),
+		}, nil
+	}))
+
+	for _, profile := range []string{"foo", "bar", "foo"} {
+		got, err := accounts.Get(ctx, services.AWSConfig{Profile: profile})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "account-of-" + profile; got != want {
+			t.Errorf("unexpected account: want %q, got %q", want, got)
+		}
+	}
+
+	// the accounts are cached.
+	if len(profiles) != 2 {
+		t.Errorf("unexpected calls: %q", profiles)
+	}
+}
+
+func TestParseRegions(t *testing.T) {
+	allowed := []string{"ap-northeast-1", "us-east-1"}
+	tests := []struct {
+		params map[string]any
+		want   []string
+	}{
+		{map[string]any{"region": "ap-northeast-1"}, []string{"ap-northeast-1"}},
+		{map[string]any{"region": "*"}, []string{"ap-northeast-1", "us-east-1"}},
+		{map[string]any{"regions": []any{"us-west-2", "eu-west-1", "us-west-2"}}, []string{"us-west-2", "eu-west-1"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRegions(new(maputils.Context), tt.params, allowed)
+		if err != nil {
+			t.Errorf("ParseRegions(%v) returned an error: %v", tt.params, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseRegions(%v) = %q, want %q", tt.params, got, tt.want)
+		}
+	}
+
+	invalid := []map[string]any{
+		{},
+		{"region": "ap-northeast-1", "regions": []any{"us-east-1"}},
+		{"regions": []any{}},
+		{"regions": []any{"*"}},
+		{"regions": []any{1}},
+	}
+	for _, params := range invalid {
+		if _, err := ParseRegions(new(maputils.Context), params, allowed); err == nil {
+			t.Errorf("ParseRegions(%v): want error, got nil", params)
+		}
+	}
+	if _, err := ParseRegions(new(maputils.Context), map[string]any{"region": "*"}, nil); err == nil {
+		t.Error(`region "*" without the allowed regions: want error, got nil`)
+	}
+}
@@ -17,3 +17,47 @@ type SecretsManagerSecretGetter interface {
 type SecretsManagerSecretUpdater interface {
 	SecretsManagerUpdateSecret(ctx context.Context, region string, in *secretsmanager.UpdateSecretInput) (*secretsmanager.UpdateSecretOutput, error)
 }
+
+type SecretsManagerSecretsLister interface {
+	SecretsManagerListSecrets(ctx context.Context, region string, in *secretsmanager.ListSecretsInput) (*secretsmanager.ListSecretsOutput, error)
+}
+
+type SecretsManagerSecretDeleter interface {
+	SecretsManagerDeleteSecret(ctx context.Context, region string, in *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error)
+}
+
+type SecretsManagerSecretDescriber interface {
+	SecretsManagerDescribeSecret(ctx context.Context, region string, in *secretsmanager.DescribeSecretInput) (*secretsmanager.DescribeSecretOutput, error)
+}
+
+type SecretsManagerResourcePolicyGetter interface {
+	SecretsManagerGetResourcePolicy(ctx context.Context, region string, in *secretsmanager.GetResourcePolicyInput) (*secretsmanager.GetResourcePolicyOutput, error)
+}
+
+type SecretsManagerResourcePolicyPutter interface {
+	SecretsManagerPutResourcePolicy(ctx context.Context, region string, in *secretsmanager.PutResourcePolicyInput) (*secretsmanager.PutResourcePolicyOutput, error)
+}
+
+type SecretsManagerResourceTagger interface {
+	SecretsManagerTagResource(ctx context.Context, region string, in *secretsmanager.TagResourceInput) (*secretsmanager.TagResourceOutput, error)
+}
+
+type SecretsManagerSecretReplicator interface {
+	SecretsManagerReplicateSecretToRegions(ctx context.Context, region string, in *secretsmanager.ReplicateSecretToRegionsInput) (*secretsmanager.ReplicateSecretToRegionsOutput, error)
+}
+
+type SecretsManagerSecretValuePutter interface {
+	SecretsManagerPutSecretValue(ctx context.Context, region string, in *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error)
+}
+
+type SecretsManagerVersionStageUpdater interface {
+	SecretsManagerUpdateSecretVersionStage(ctx context.Context, region string, in *secretsmanager.UpdateSecretVersionStageInput) (*secretsmanager.UpdateSecretVersionStageOutput, error)
+}
+
+type SecretsManagerSecretRotator interface {
+	SecretsManagerRotateSecret(ctx context.Context, region string, in *secretsmanager.RotateSecretInput) (*secretsmanager.RotateSecretOutput, error)
+}
+
+type SecretsManagerRotationCanceler interface {
+	SecretsManagerCancelRotateSecret(ctx context.Context, region string, in *secretsmanager.CancelRotateSecretInput) (*secretsmanager.CancelRotateSecretOutput, error)
+}
@@ -2,19 +2,21 @@ package awssecretsmanager
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/awsconfig"
 )
 
+// Service is the AWS Secrets Manager service.
+// It is safe for concurrent use.
+// The clients are cached per credentials specified by [services.WithAWSConfig] and region.
 type Service struct {
 	mu  sync.Mutex
-	svc map[string]*secretsmanager.Client
+	svc map[awsconfig.Key]*secretsmanager.Client
 }
 
 func New() *Service {
@@ -26,18 +28,21 @@ func (s *Service) getClient(ctx context.Context, region string) (*secretsmanager
 	defer s.mu.Unlock()
 
 	if s.svc == nil {
-		s.svc = make(map[string]*secretsmanager.Client)
+		s.svc = make(map[awsconfig.Key]*secretsmanager.Client)
 	}
-	if svc, ok := s.svc[region]; ok {
+	key := awsconfig.Key{Config: services.AWSConfigFromContext(ctx), Region: region}
+	if svc, ok := s.svc[key]; ok {
 		return svc, nil
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	cfg, err := awsconfig.Load(ctx, key.Config, region)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load aws config: %w", err)
+		return nil, err
 	}
-	svc := secretsmanager.NewFromConfig(cfg)
-	s.svc[region] = svc
+	svc := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		o.BaseEndpoint = awsconfig.BaseEndpoint(key.Config, o.BaseEndpoint)
+	})
+	s.svc[key] = svc
 	return svc, nil
 }
 
@@ -79,3 +84,146 @@ func (s *Service) SecretsManagerUpdateSecret(ctx context.Context, region string,
 	slog.InfoContext(ctx, "update secrets manager secret", slog.String("name", aws.ToString(in.SecretId)))
 	return svc.UpdateSecret(ctx, in)
 }
+
+var _ services.SecretsManagerSecretsLister = (*Service)(nil)
+
+// SecretsManagerListSecrets lists the secrets that are stored by Secrets Manager.
+func (s *Service) SecretsManagerListSecrets(ctx context.Context, region string, in *secretsmanager.ListSecretsInput) (*secretsmanager.ListSecretsOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "list secrets manager secrets", slog.String("region", region))
+	return svc.ListSecrets(ctx, in)
+}
+
+var _ services.SecretsManagerSecretDeleter = (*Service)(nil)
+
+// SecretsManagerDeleteSecret deletes a secret.
+func (s *Service) SecretsManagerDeleteSecret(ctx context.Context, region string, in *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "delete secrets manager secret", slog.String("name", aws.ToString(in.SecretId)))
+	return svc.DeleteSecret(ctx, in)
+}
+
+var _ services.SecretsManagerSecretDescriber = (*Service)(nil)
+
+// SecretsManagerDescribeSecret retrieves the details of a secret.
+func (s *Service) SecretsManagerDescribeSecret(ctx context.Context, region string, in *secretsmanager.DescribeSecretInput) (*secretsmanager.DescribeSecretOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "describe secrets manager secret", slog.String("name", aws.ToString(in.SecretId)))
+	return svc.DescribeSecret(ctx, in)
+}
+
+var _ services.SecretsManagerResourcePolicyGetter = (*Service)(nil)
+
+// SecretsManagerGetResourcePolicy retrieves the resource-based policy of a secret.
+func (s *Service) SecretsManagerGetResourcePolicy(ctx context.Context, region string, in *secretsmanager.GetResourcePolicyInput) (*secretsmanager.GetResourcePolicyOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "get resource policy of secrets manager secret", slog.String("name", aws.ToString(in.SecretId)))
+	return svc.GetResourcePolicy(ctx, in)
+}
+
+var _ services.SecretsManagerResourcePolicyPutter = (*Service)(nil)
+
+// SecretsManagerPutResourcePolicy attaches a resource-based policy to a secret.
+func (s *Service) SecretsManagerPutResourcePolicy(ctx context.Context, region string, in *secretsmanager.PutResourcePolicyInput) (*secretsmanager.PutResourcePolicyOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "put resource policy of secrets manager secret", slog.String("name", aws.ToString(in.SecretId)))
+	return svc.PutResourcePolicy(ctx, in)
+}
+
+var _ services.SecretsManagerResourceTagger = (*Service)(nil)
+
+// SecretsManagerTagResource attaches tags to a secret.
+func (s *Service) SecretsManagerTagResource(ctx context.Context, region string, in *secretsmanager.TagResourceInput) (*secretsmanager.TagResourceOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "tag secrets manager secret", slog.String("name", aws.ToString(in.SecretId)))
+	return svc.TagResource(ctx, in)
+}
+
+var _ services.SecretsManagerSecretReplicator = (*Service)(nil)
+
+// SecretsManagerReplicateSecretToRegions replicates a secret to the regions.
+func (s *Service) SecretsManagerReplicateSecretToRegions(ctx context.Context, region string, in *secretsmanager.ReplicateSecretToRegionsInput) (*secretsmanager.ReplicateSecretToRegionsOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "replicate secrets manager secret", slog.String("name", aws.ToString(in.SecretId)))
+	return svc.ReplicateSecretToRegions(ctx, in)
+}
+
+var _ services.SecretsManagerSecretValuePutter = (*Service)(nil)
+
+// SecretsManagerPutSecretValue creates a new version of a secret.
+func (s *Service) SecretsManagerPutSecretValue(ctx context.Context, region string, in *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "put secrets manager secret value", slog.String("name", aws.ToString(in.SecretId)))
+	return svc.PutSecretValue(ctx, in)
+}
+
+var _ services.SecretsManagerVersionStageUpdater = (*Service)(nil)
+
+// SecretsManagerUpdateSecretVersionStage moves a staging label from one version of a secret to another.
+func (s *Service) SecretsManagerUpdateSecretVersionStage(ctx context.Context, region string, in *secretsmanager.UpdateSecretVersionStageInput) (*secretsmanager.UpdateSecretVersionStageOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "update version stage of secrets manager secret", slog.String("name", aws.ToString(in.SecretId)))
+	return svc.UpdateSecretVersionStage(ctx, in)
+}
+
+var _ services.SecretsManagerSecretRotator = (*Service)(nil)
+
+// SecretsManagerRotateSecret configures the rotation of a secret.
+func (s *Service) SecretsManagerRotateSecret(ctx context.Context, region string, in *secretsmanager.RotateSecretInput) (*secretsmanager.RotateSecretOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "rotate secrets manager secret", slog.String("name", aws.ToString(in.SecretId)))
+	return svc.RotateSecret(ctx, in)
+}
+
+var _ services.SecretsManagerRotationCanceler = (*Service)(nil)
+
+// SecretsManagerCancelRotateSecret turns off the rotation of a secret.
+func (s *Service) SecretsManagerCancelRotateSecret(ctx context.Context, region string, in *secretsmanager.CancelRotateSecretInput) (*secretsmanager.CancelRotateSecretOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "cancel rotation of secrets manager secret", slog.String("name", aws.ToString(in.SecretId)))
+	return svc.CancelRotateSecret(ctx, in)
+}
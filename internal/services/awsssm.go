@@ -13,3 +13,19 @@ type SSMParameterGetter interface {
 type SSMParameterPutter interface {
 	SSMPutParameter(ctx context.Context, region string, in *ssm.PutParameterInput) (*ssm.PutParameterOutput, error)
 }
+
+type SSMParametersDescriber interface {
+	SSMDescribeParameters(ctx context.Context, region string, in *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error)
+}
+
+type SSMParameterDeleter interface {
+	SSMDeleteParameter(ctx context.Context, region string, in *ssm.DeleteParameterInput) (*ssm.DeleteParameterOutput, error)
+}
+
+type SSMTagsLister interface {
+	SSMListTagsForResource(ctx context.Context, region string, in *ssm.ListTagsForResourceInput) (*ssm.ListTagsForResourceOutput, error)
+}
+
+type SSMTagsAdder interface {
+	SSMAddTagsToResource(ctx context.Context, region string, in *ssm.AddTagsToResourceInput) (*ssm.AddTagsToResourceOutput, error)
+}
@@ -2,19 +2,21 @@ package awsssm
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/awsconfig"
 )
 
+// Service is the AWS Systems Manager service.
+// It is safe for concurrent use.
+// The clients are cached per credentials specified by [services.WithAWSConfig] and region.
 type Service struct {
 	mu  sync.Mutex
-	svc map[string]*ssm.Client
+	svc map[awsconfig.Key]*ssm.Client
 }
 
 func New() *Service {
@@ -26,18 +28,21 @@ func (s *Service) getClient(ctx context.Context, region string) (*ssm.Client, er
 	defer s.mu.Unlock()
 
 	if s.svc == nil {
-		s.svc = make(map[string]*ssm.Client)
+		s.svc = make(map[awsconfig.Key]*ssm.Client)
 	}
-	if svc, ok := s.svc[region]; ok {
+	key := awsconfig.Key{Config: services.AWSConfigFromContext(ctx), Region: region}
+	if svc, ok := s.svc[key]; ok {
 		return svc, nil
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	cfg, err := awsconfig.Load(ctx, key.Config, region)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load aws config: %w", err)
+		return nil, err
 	}
-	svc := ssm.NewFromConfig(cfg)
-	s.svc[region] = svc
+	svc := ssm.NewFromConfig(cfg, func(o *ssm.Options) {
+		o.BaseEndpoint = awsconfig.BaseEndpoint(key.Config, o.BaseEndpoint)
+	})
+	s.svc[key] = svc
 	return svc, nil
 }
 
@@ -64,3 +69,51 @@ func (s *Service) SSMPutParameter(ctx context.Context, region string, in *ssm.Pu
 	slog.InfoContext(ctx, "put ssm parameter", slog.String("name", aws.ToString(in.Name)))
 	return svc.PutParameter(ctx, in)
 }
+
+var _ services.SSMParametersDescriber = (*Service)(nil)
+
+func (s *Service) SSMDescribeParameters(ctx context.Context, region string, in *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "describe ssm parameters", slog.String("region", region))
+	return svc.DescribeParameters(ctx, in)
+}
+
+var _ services.SSMParameterDeleter = (*Service)(nil)
+
+func (s *Service) SSMDeleteParameter(ctx context.Context, region string, in *ssm.DeleteParameterInput) (*ssm.DeleteParameterOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "delete ssm parameter", slog.String("name", aws.ToString(in.Name)))
+	return svc.DeleteParameter(ctx, in)
+}
+
+var _ services.SSMTagsLister = (*Service)(nil)
+
+func (s *Service) SSMListTagsForResource(ctx context.Context, region string, in *ssm.ListTagsForResourceInput) (*ssm.ListTagsForResourceOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "list tags of ssm parameter", slog.String("name", aws.ToString(in.ResourceId)))
+	return svc.ListTagsForResource(ctx, in)
+}
+
+var _ services.SSMTagsAdder = (*Service)(nil)
+
+func (s *Service) SSMAddTagsToResource(ctx context.Context, region string, in *ssm.AddTagsToResourceInput) (*ssm.AddTagsToResourceOutput, error) {
+	svc, err := s.getClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "add tags to ssm parameter", slog.String("name", aws.ToString(in.ResourceId)))
+	return svc.AddTagsToResource(ctx, in)
+}
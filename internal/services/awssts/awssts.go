@@ -2,29 +2,57 @@ package awssts
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
+	"sync"
 
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/awsconfig"
 )
 
 var _ services.STSCallerIdentityGetter = (*Service)(nil)
 
-type Service struct{}
+// Service is the AWS Security Token Service.
+// The clients are cached per credentials specified by [services.WithAWSConfig].
+// It is safe for concurrent use.
+type Service struct {
+	mu  sync.Mutex
+	svc map[services.AWSConfig]*sts.Client
+}
 
 func New() *Service {
 	return &Service{}
 }
 
+func (s *Service) getClient(ctx context.Context) (*sts.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.svc == nil {
+		s.svc = make(map[services.AWSConfig]*sts.Client)
+	}
+	key := services.AWSConfigFromContext(ctx)
+	if svc, ok := s.svc[key]; ok {
+		return svc, nil
+	}
+
+	cfg, err := awsconfig.Load(ctx, key, "")
+	if err != nil {
+		return nil, err
+	}
+	svc := sts.NewFromConfig(cfg, func(o *sts.Options) {
+		o.BaseEndpoint = awsconfig.BaseEndpoint(key, o.BaseEndpoint)
+	})
+	s.svc[key] = svc
+	return svc, nil
+}
+
 func (s *Service) STSGetCallerIdentity(ctx context.Context) (*sts.GetCallerIdentityOutput, error) {
-	cfg, err := config.LoadDefaultConfig(ctx)
+	svc, err := s.getClient(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load aws config: %w", err)
+		return nil, err
 	}
 
-	svc := sts.NewFromConfig(cfg)
 	slog.DebugContext(ctx, "get caller identity")
 	return svc.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 }
@@ -15,16 +15,98 @@ const (
 	GitHubApplicationDependabot GitHubApplication = "dependabot"
 )
 
+// DefaultGitHubHost is the host of github.com.
+const DefaultGitHubHost = "github.com"
+
+// GitHubIdentity is the GitHub host and the credential to access it.
+// The zero value means github.com with the token of GitHub CLI.
+type GitHubIdentity struct {
+	// Host is the host of GitHub Enterprise Server, e.g. github.example.com.
+	// Empty means github.com.
+	Host string `json:"host,omitempty"`
+
+	// TokenEnv is the name of the environment variable that has the token.
+	TokenEnv string `json:"token_env,omitempty"`
+
+	// AppID, AppInstallationID, and AppPrivateKey are the GitHub App
+	// that generates installation tokens.
+	// AppPrivateKey is the secret reference to the private key, e.g. op://vault/item/private-key.
+	AppID             int64  `json:"app_id,omitempty"`
+	AppInstallationID int64  `json:"app_installation_id,omitempty"`
+	AppPrivateKey     string `json:"app_private_key,omitempty"`
+}
+
+// IsDefaultHost reports whether the identity is for github.com.
+func (id GitHubIdentity) IsDefaultHost() bool {
+	return id.Host == "" || id.Host == DefaultGitHubHost
+}
+
+// Normalize returns the identity with the empty host if it is for github.com,
+// so that the identities for the same host are equal.
+func (id GitHubIdentity) Normalize() GitHubIdentity {
+	if id.IsDefaultHost() {
+		id.Host = ""
+	}
+	return id
+}
+
+// WithHost returns the normalized identity for host, e.g. the github_host parameter of the entries.
+// The host of id is kept if host is empty.
+func (id GitHubIdentity) WithHost(host string) GitHubIdentity {
+	if host != "" {
+		id.Host = host
+	}
+	return id.Normalize()
+}
+
+// TargetPrefix returns the prefix of the targets on the host, e.g. "github.example.com/".
+// The targets on github.com have no prefix.
+func (id GitHubIdentity) TargetPrefix() string {
+	if id.IsDefaultHost() {
+		return ""
+	}
+	return id.Host + "/"
+}
+
+type gitHubIdentityKey struct{}
+
+// WithGitHubIdentity returns a copy of ctx that specifies the GitHub identity to use.
+func WithGitHubIdentity(ctx context.Context, id GitHubIdentity) context.Context {
+	return context.WithValue(ctx, gitHubIdentityKey{}, id)
+}
+
+// GitHubIdentityFromContext returns the GitHub identity specified by [WithGitHubIdentity].
+// It returns the zero value if no identity is specified.
+func GitHubIdentityFromContext(ctx context.Context) GitHubIdentity {
+	id, _ := ctx.Value(gitHubIdentityKey{}).(GitHubIdentity)
+	return id
+}
+
 // GitHubUserGetter fetches the authenticated GitHub user.
 type GitHubUserGetter interface {
 	GetGitHubUser(ctx context.Context) (*github.User, error)
 }
 
+// GitHubUserByLoginGetter fetches the GitHub user by the login name.
+type GitHubUserByLoginGetter interface {
+	GetGitHubUserByLogin(ctx context.Context, login string) (*github.User, error)
+}
+
+// GitHubTeamGetter fetches the team in the organization by the slug.
+type GitHubTeamGetter interface {
+	GetGitHubTeam(ctx context.Context, org, slug string) (*github.Team, error)
+}
+
 // GitHubRepoGetter fetches the GitHub repository.
 type GitHubRepoGetter interface {
 	GetGitHubRepo(ctx context.Context, owner, repo string) (*github.Repository, error)
 }
 
+// GitHubReposLister lists the repositories of the user or the organization.
+type GitHubReposLister interface {
+	ListGitHubRepos(ctx context.Context, owner string) ([]*github.Repository, error)
+}
+
 // GitHubRepoSecretGetter gets a single repository secret without revealing its encrypted value.
 type GitHubRepoSecretGetter interface {
 	GetGitHubRepoSecret(ctx context.Context, app GitHubApplication, owner, repo, name string) (*github.Secret, error)
@@ -37,7 +119,22 @@ type GitHubRepoSecretCreator interface {
 
 // GitHubRepoPublicKeyGetter gets a public key that should be used for secret encryption.
 type GitHubRepoPublicKeyGetter interface {
-	GetGitHubRepoPublicKey(ctx context.Context, owner, repo string) (*github.PublicKey, error)
+	GetGitHubRepoPublicKey(ctx context.Context, app GitHubApplication, owner, repo string) (*github.PublicKey, error)
+}
+
+// GitHubEnvGetter gets the environment of the repository.
+type GitHubEnvGetter interface {
+	GetGitHubEnv(ctx context.Context, owner, repo, env string) (*github.Environment, error)
+}
+
+// GitHubEnvCreator creates the environment of the repository.
+type GitHubEnvCreator interface {
+	CreateGitHubEnv(ctx context.Context, owner, repo, env string, opts *github.CreateUpdateEnvironment) error
+}
+
+// GitHubDeploymentBranchPolicyCreator creates a deployment branch policy of the environment.
+type GitHubDeploymentBranchPolicyCreator interface {
+	CreateGitHubDeploymentBranchPolicy(ctx context.Context, owner, repo, env, pattern string) error
 }
 
 // GitHubEnvSecretGetter gets a single environment secret without revealing its encrypted value.
@@ -67,10 +164,115 @@ type GitHubOrgSecretCreator interface {
 
 // GitHubOrgPublicKeyGetter gets a public key that should be used for secret encryption.
 type GitHubOrgPublicKeyGetter interface {
-	GetGitHubOrgPublicKey(ctx context.Context, org string) (*github.PublicKey, error)
+	GetGitHubOrgPublicKey(ctx context.Context, app GitHubApplication, org string) (*github.PublicKey, error)
 }
 
 // GitHubReposIDForOrgSecretLister lists all repositories that have access to a secret.
 type GitHubReposIDForOrgSecretLister interface {
 	ListGitHubReposIDForOrgSecret(ctx context.Context, app GitHubApplication, org, name string) ([]int64, error)
 }
+
+// GitHubRepoSecretsLister lists all repository secrets without revealing their encrypted values.
+type GitHubRepoSecretsLister interface {
+	ListGitHubRepoSecrets(ctx context.Context, app GitHubApplication, owner, repo string) ([]*github.Secret, error)
+}
+
+// GitHubRepoSecretDeleter deletes a repository secret.
+type GitHubRepoSecretDeleter interface {
+	DeleteGitHubRepoSecret(ctx context.Context, app GitHubApplication, owner, repo, name string) error
+}
+
+// GitHubEnvSecretsLister lists all environment secrets without revealing their encrypted values.
+type GitHubEnvSecretsLister interface {
+	ListGitHubEnvSecrets(ctx context.Context, repoID int, env string) ([]*github.Secret, error)
+}
+
+// GitHubEnvSecretDeleter deletes an environment secret.
+type GitHubEnvSecretDeleter interface {
+	DeleteGitHubEnvSecret(ctx context.Context, repoID int, env, name string) error
+}
+
+// GitHubOrgSecretsLister lists all organization secrets without revealing their encrypted values.
+type GitHubOrgSecretsLister interface {
+	ListGitHubOrgSecrets(ctx context.Context, app GitHubApplication, org string) ([]*github.Secret, error)
+}
+
+// GitHubOrgSecretDeleter deletes an organization secret.
+type GitHubOrgSecretDeleter interface {
+	DeleteGitHubOrgSecret(ctx context.Context, app GitHubApplication, org, name string) error
+}
+
+// GitHubUserSecretGetter gets a single Codespaces secret of the authenticated user without revealing its encrypted value.
+type GitHubUserSecretGetter interface {
+	GetGitHubUserSecret(ctx context.Context, name string) (*github.Secret, error)
+}
+
+// GitHubUserSecretCreator creates or updates a Codespaces secret of the authenticated user with an encrypted value.
+type GitHubUserSecretCreator interface {
+	CreateGitHubUserSecret(ctx context.Context, secret *github.EncryptedSecret) error
+}
+
+// GitHubUserPublicKeyGetter gets a public key that should be used for Codespaces secret encryption of the authenticated user.
+type GitHubUserPublicKeyGetter interface {
+	GetGitHubUserPublicKey(ctx context.Context) (*github.PublicKey, error)
+}
+
+// GitHubReposIDForUserSecretLister lists all repositories that have access to a Codespaces secret of the authenticated user.
+type GitHubReposIDForUserSecretLister interface {
+	ListGitHubReposIDForUserSecret(ctx context.Context, name string) ([]int64, error)
+}
+
+// GitHubUserSecretsLister lists all Codespaces secrets of the authenticated user without revealing their encrypted values.
+type GitHubUserSecretsLister interface {
+	ListGitHubUserSecrets(ctx context.Context) ([]*github.Secret, error)
+}
+
+// GitHubUserSecretDeleter deletes a Codespaces secret of the authenticated user.
+type GitHubUserSecretDeleter interface {
+	DeleteGitHubUserSecret(ctx context.Context, name string) error
+}
+
+// GitHubRepoVariableGetter gets a single repository variable.
+type GitHubRepoVariableGetter interface {
+	GetGitHubRepoVariable(ctx context.Context, owner, repo, name string) (*github.ActionsVariable, error)
+}
+
+// GitHubRepoVariableCreator creates a repository variable.
+type GitHubRepoVariableCreator interface {
+	CreateGitHubRepoVariable(ctx context.Context, owner, repo string, variable *github.ActionsVariable) error
+}
+
+// GitHubRepoVariableUpdater updates a repository variable.
+type GitHubRepoVariableUpdater interface {
+	UpdateGitHubRepoVariable(ctx context.Context, owner, repo string, variable *github.ActionsVariable) error
+}
+
+// GitHubEnvVariableGetter gets a single environment variable.
+type GitHubEnvVariableGetter interface {
+	GetGitHubEnvVariable(ctx context.Context, repoID int, env, name string) (*github.ActionsVariable, error)
+}
+
+// GitHubEnvVariableCreator creates an environment variable.
+type GitHubEnvVariableCreator interface {
+	CreateGitHubEnvVariable(ctx context.Context, repoID int, env string, variable *github.ActionsVariable) error
+}
+
+// GitHubEnvVariableUpdater updates an environment variable.
+type GitHubEnvVariableUpdater interface {
+	UpdateGitHubEnvVariable(ctx context.Context, repoID int, env string, variable *github.ActionsVariable) error
+}
+
+// GitHubOrgVariableGetter gets a single organization variable.
+type GitHubOrgVariableGetter interface {
+	GetGitHubOrgVariable(ctx context.Context, org, name string) (*github.ActionsVariable, error)
+}
+
+// GitHubOrgVariableCreator creates an organization variable.
+type GitHubOrgVariableCreator interface {
+	CreateGitHubOrgVariable(ctx context.Context, org string, variable *github.ActionsVariable) error
+}
+
+// GitHubOrgVariableUpdater updates an organization variable.
+type GitHubOrgVariableUpdater interface {
+	UpdateGitHubOrgVariable(ctx context.Context, org string, variable *github.ActionsVariable) error
+}
@@ -0,0 +1,109 @@
+package gh
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/shogo82148/op-sync/internal/services"
+)
+
+// appToken generates an installation token of the GitHub App.
+func (s *Service) appToken(ctx context.Context, id services.GitHubIdentity) (string, time.Time, error) {
+	if id.AppInstallationID == 0 {
+		return "", time.Time{}, errors.New("the installation ID of the GitHub App is required")
+	}
+	if id.AppPrivateKey == "" {
+		return "", time.Time{}, errors.New("the private key of the GitHub App is required")
+	}
+	if s.opts.OnePasswordReader == nil {
+		return "", time.Time{}, errors.New("1Password is not available to read the private key of the GitHub App")
+	}
+
+	data, err := s.opts.OnePasswordReader.ReadOnePassword(services.WithOnePasswordAccount(ctx, s.opts.Account), id.AppPrivateKey)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to read the private key of the GitHub App: %w", err)
+	}
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	jwt, err := appJWT(id.AppID, key, time.Now())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// the app is authenticated by the JWT instead of the token.
+	client, err := s.newClient(id, jwt)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	slog.DebugContext(ctx, "create the installation token", slog.Int64("app_id", id.AppID), slog.Int64("installation_id", id.AppInstallationID))
+	token, _, err := client.Apps.CreateInstallationToken(ctx, id.AppInstallationID, nil)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create the installation token of the GitHub App: %w", err)
+	}
+	return token.GetToken(), token.GetExpiresAt().Time, nil
+}
+
+// parsePrivateKey parses the PEM encoded RSA private key.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("the private key of the GitHub App is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the private key of the GitHub App: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key of the GitHub App is not RSA: %T", key)
+	}
+	return rsaKey, nil
+}
+
+// appJWT returns the JSON Web Token that authenticates the GitHub App.
+// See https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app
+func appJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		// allow the clock drift.
+		"iat": now.Add(-time.Minute).Unix(),
+		// the maximum is 10 minutes.
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign the JWT of the GitHub App: %w", err)
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
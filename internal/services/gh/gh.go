@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,31 +35,161 @@ func wrap(err error) error {
 	return fmt.Errorf("failed to run gh command: %w", err)
 }
 
+// Service is the GitHub service.
+// It uses the token of GitHub CLI by default,
+// and the identity specified by [services.WithGitHubIdentity] if any.
+// It waits for the rate limits of GitHub, and caps the concurrent requests.
+// It is safe for concurrent use.
 type Service struct {
-	mu sync.Mutex
-	c  *github.Client
+	opts      *Options
+	transport *rateLimitTransport
+
+	mu      sync.Mutex
+	clients map[services.GitHubIdentity]*cachedClient
 }
 
-func NewService() *Service {
-	return &Service{}
+// Options is the options of [Service].
+type Options struct {
+	// OnePasswordReader reads the private keys of GitHub Apps.
+	OnePasswordReader services.OnePasswordReader
+
+	// Account is the 1Password account to read the private keys.
+	Account string
+
+	// MaxConcurrentRequests is the maximum number of concurrent requests to GitHub.
+	// The default is 8.
+	MaxConcurrentRequests int
+
+	// MaxRetries is the maximum number of retries on the rate limits.
+	// The default is 5.
+	MaxRetries int
 }
 
-// client returns an authorized GitHub client by GitHub CLI.
+type cachedClient struct {
+	// done is closed when the token is minted.
+	// The other fields are available after done is closed.
+	done chan struct{}
+
+	client *github.Client
+	err    error
+
+	// expiresAt is the expiration time of the token.
+	// It is zero if the token never expires.
+	expiresAt time.Time
+}
+
+// usable reports whether the minted client is still usable.
+// It must be called after c.done is closed.
+func (c *cachedClient) usable() bool {
+	if c.err != nil {
+		return false
+	}
+	return c.expiresAt.IsZero() || time.Until(c.expiresAt) > tokenRefreshMargin
+}
+
+func NewService(opts *Options) *Service {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return &Service{
+		opts:      opts,
+		transport: newRateLimitTransport(nil, opts.MaxConcurrentRequests, opts.MaxRetries),
+		clients:   map[services.GitHubIdentity]*cachedClient{},
+	}
+}
+
+// tokenRefreshMargin is the margin to refresh the tokens before they expire.
+const tokenRefreshMargin = 5 * time.Minute
+
+// client returns an authorized GitHub client for the identity in ctx.
+// The clients are cached per identity, and the tokens are minted once per identity
+// without blocking the other identities. The failed tokens are minted again later.
 func (s *Service) client(ctx context.Context) (*github.Client, error) {
+	id := services.GitHubIdentityFromContext(ctx).Normalize()
+
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	if s.c != nil {
-		return s.c, nil
+	c, ok := s.clients[id]
+	if ok {
+		select {
+		case <-c.done:
+			ok = c.usable()
+		default:
+			// the token is being minted.
+		}
 	}
+	if !ok {
+		c = &cachedClient{done: make(chan struct{})}
+		s.clients[id] = c
+
+		// the other callers wait for the token even if this caller gives up.
+		go s.mint(context.WithoutCancel(ctx), id, c)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.client, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// mint mints the token of id, and makes the client of c.
+func (s *Service) mint(ctx context.Context, id services.GitHubIdentity, c *cachedClient) {
+	defer close(c.done)
 
-	cmd := command(ctx, "gh", "auth", "token")
+	token, expiresAt, err := s.token(ctx, id)
+	if err != nil {
+		c.err = err
+		return
+	}
+	c.client, c.err = s.newClient(id, token)
+	c.expiresAt = expiresAt
+}
+
+// newClient returns a GitHub client for the host of id.
+// All clients share the transport to cap the concurrent requests of the service.
+func (s *Service) newClient(id services.GitHubIdentity, token string) (*github.Client, error) {
+	client := github.NewClient(&http.Client{Transport: s.transport})
+	if token != "" {
+		client = client.WithAuthToken(token)
+	}
+	if id.IsDefaultHost() {
+		return client, nil
+	}
+	baseURL := "https://" + id.Host + "/api/v3/"
+	uploadURL := "https://" + id.Host + "/api/uploads/"
+	client, err := client.WithEnterpriseURLs(baseURL, uploadURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure GitHub Enterprise Server %s: %w", id.Host, err)
+	}
+	return client, nil
+}
+
+// token returns the token for id.
+// expiresAt is zero if the token never expires.
+func (s *Service) token(ctx context.Context, id services.GitHubIdentity) (token string, expiresAt time.Time, err error) {
+	switch {
+	case id.AppID != 0:
+		return s.appToken(ctx, id)
+	case id.TokenEnv != "":
+		token := os.Getenv(id.TokenEnv)
+		if token == "" {
+			return "", time.Time{}, fmt.Errorf("environment variable %s is empty", id.TokenEnv)
+		}
+		return token, time.Time{}, nil
+	}
+
+	args := []string{"auth", "token"}
+	if id.Host != "" {
+		args = append(args, "--hostname", id.Host)
+	}
+	cmd := command(ctx, "gh", args...)
 	out, err := cmd.Output()
 	if err != nil {
-		return nil, wrap(err)
+		return "", time.Time{}, wrap(err)
 	}
-	token := string(bytes.TrimSpace(out))
-	s.c = github.NewClient(nil).WithAuthToken(token)
-	return s.c, nil
+	return string(bytes.TrimSpace(out)), time.Time{}, nil
 }
 
 var _ services.GitHubUserGetter = (*Service)(nil)
@@ -77,6 +209,40 @@ func (s *Service) GetGitHubUser(ctx context.Context) (*github.User, error) {
 	return u, nil
 }
 
+var _ services.GitHubUserByLoginGetter = (*Service)(nil)
+
+// GetGitHubUserByLogin fetches the GitHub user by the login name.
+func (s *Service) GetGitHubUserByLogin(ctx context.Context, login string) (*github.User, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "get the user", slog.String("login", login))
+	u, _, err := client.Users.Get(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+var _ services.GitHubTeamGetter = (*Service)(nil)
+
+// GetGitHubTeam fetches the team in the organization by the slug.
+func (s *Service) GetGitHubTeam(ctx context.Context, org, slug string) (*github.Team, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "get the team", slog.String("org", org), slog.String("slug", slug))
+	team, _, err := client.Teams.GetTeamBySlug(ctx, org, slug)
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
 var _ services.GitHubRepoGetter = (*Service)(nil)
 
 // GetGitHubRepo fetches the GitHub repository.
@@ -94,6 +260,63 @@ func (s *Service) GetGitHubRepo(ctx context.Context, owner, repo string) (*githu
 	return r, nil
 }
 
+var _ services.GitHubReposLister = (*Service)(nil)
+
+// ListGitHubRepos lists the repositories of the user or the organization.
+// The private repositories of the authenticated user are listed, too.
+func (s *Service) ListGitHubRepos(ctx context.Context, owner string) ([]*github.Repository, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "get the owner of repos", slog.String("owner", owner))
+	u, _, err := client.Users.Get(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	isOrg := u.GetType() == "Organization"
+
+	// the endpoint of the other users lists only the public repositories.
+	isAuthenticatedUser := false
+	if !isOrg {
+		slog.DebugContext(ctx, "get the authenticated user")
+		me, _, err := client.Users.Get(ctx, "")
+		// the installation tokens of GitHub Apps have no authenticated user.
+		if err == nil && strings.EqualFold(me.GetLogin(), owner) {
+			isAuthenticatedUser = true
+		}
+	}
+
+	slog.DebugContext(ctx, "list the repos", slog.String("owner", owner))
+	var ret []*github.Repository
+	opt := github.ListOptions{
+		Page:    1,
+		PerPage: 100,
+	}
+	for {
+		var repos []*github.Repository
+		var resp *github.Response
+		switch {
+		case isOrg:
+			repos, resp, err = client.Repositories.ListByOrg(ctx, owner, &github.RepositoryListByOrgOptions{ListOptions: opt})
+		case isAuthenticatedUser:
+			repos, resp, err = client.Repositories.List(ctx, "", &github.RepositoryListOptions{Affiliation: "owner", ListOptions: opt})
+		default:
+			repos, resp, err = client.Repositories.List(ctx, owner, &github.RepositoryListOptions{ListOptions: opt})
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, repos...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return ret, nil
+}
+
 var _ services.GitHubRepoSecretGetter = (*Service)(nil)
 
 // GetGitHubRepoSecret gets a single repository secret without revealing its encrypted value.
@@ -158,20 +381,78 @@ func (s *Service) CreateGitHubRepoSecret(ctx context.Context, app services.GitHu
 var _ services.GitHubRepoPublicKeyGetter = (*Service)(nil)
 
 // GetGitHubRepoPublicKey gets a public key that should be used for secret encryption.
-func (s *Service) GetGitHubRepoPublicKey(ctx context.Context, owner, repo string) (*github.PublicKey, error) {
+func (s *Service) GetGitHubRepoPublicKey(ctx context.Context, app services.GitHubApplication, owner, repo string) (*github.PublicKey, error) {
 	client, err := s.client(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	slog.DebugContext(ctx, "get the repo public key", slog.String("owner", owner), slog.String("repo", repo))
-	key, _, err := client.Actions.GetRepoPublicKey(ctx, owner, repo)
+	slog.DebugContext(ctx, "get the repo public key", slog.String("application", string(app)), slog.String("owner", owner), slog.String("repo", repo))
+	var key *github.PublicKey
+	switch app {
+	case services.GitHubApplicationActions:
+		key, _, err = client.Actions.GetRepoPublicKey(ctx, owner, repo)
+	case services.GitHubApplicationDependabot:
+		key, _, err = client.Dependabot.GetRepoPublicKey(ctx, owner, repo)
+	case services.GitHubApplicationCodespaces:
+		key, _, err = client.Codespaces.GetRepoPublicKey(ctx, owner, repo)
+	default:
+		return nil, fmt.Errorf("unknown GitHub application: %s", app)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return key, nil
 }
 
+var _ services.GitHubEnvGetter = (*Service)(nil)
+
+// GetGitHubEnv gets the environment of the repository.
+func (s *Service) GetGitHubEnv(ctx context.Context, owner, repo, env string) (*github.Environment, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "get the environment", slog.String("owner", owner), slog.String("repo", repo), slog.String("env", env))
+	e, _, err := client.Repositories.GetEnvironment(ctx, owner, repo, env)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+var _ services.GitHubEnvCreator = (*Service)(nil)
+
+// CreateGitHubEnv creates the environment of the repository.
+func (s *Service) CreateGitHubEnv(ctx context.Context, owner, repo, env string, opts *github.CreateUpdateEnvironment) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "create the environment", slog.String("owner", owner), slog.String("repo", repo), slog.String("env", env))
+	_, _, err = client.Repositories.CreateUpdateEnvironment(ctx, owner, repo, env, opts)
+	return err
+}
+
+var _ services.GitHubDeploymentBranchPolicyCreator = (*Service)(nil)
+
+// CreateGitHubDeploymentBranchPolicy creates a deployment branch policy of the environment.
+func (s *Service) CreateGitHubDeploymentBranchPolicy(ctx context.Context, owner, repo, env, pattern string) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "create the deployment branch policy", slog.String("owner", owner), slog.String("repo", repo), slog.String("env", env), slog.String("pattern", pattern))
+	_, _, err = client.Repositories.CreateDeploymentBranchPolicy(ctx, owner, repo, env, &github.DeploymentBranchPolicyRequest{
+		Name: github.String(pattern),
+		Type: github.String("branch"),
+	})
+	return err
+}
+
 var _ services.GitHubEnvSecretGetter = (*Service)(nil)
 
 // GetGitHubEvSecret gets a single environment secret without revealing its encrypted value.
@@ -280,14 +561,24 @@ func (s *Service) CreateGitHubOrgSecret(ctx context.Context, app services.GitHub
 var _ services.GitHubOrgPublicKeyGetter = (*Service)(nil)
 
 // GetGitHubOrgPublicKey gets a public key that should be used for secret encryption.
-func (s *Service) GetGitHubOrgPublicKey(ctx context.Context, org string) (*github.PublicKey, error) {
+func (s *Service) GetGitHubOrgPublicKey(ctx context.Context, app services.GitHubApplication, org string) (*github.PublicKey, error) {
 	client, err := s.client(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	slog.DebugContext(ctx, "get the org public key", slog.String("org", org))
-	key, _, err := client.Actions.GetOrgPublicKey(ctx, org)
+	slog.DebugContext(ctx, "get the org public key", slog.String("application", string(app)), slog.String("org", org))
+	var key *github.PublicKey
+	switch app {
+	case services.GitHubApplicationActions:
+		key, _, err = client.Actions.GetOrgPublicKey(ctx, org)
+	case services.GitHubApplicationDependabot:
+		key, _, err = client.Dependabot.GetOrgPublicKey(ctx, org)
+	case services.GitHubApplicationCodespaces:
+		key, _, err = client.Codespaces.GetOrgPublicKey(ctx, org)
+	default:
+		return nil, fmt.Errorf("unknown GitHub application: %s", app)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -333,3 +624,396 @@ func (s *Service) ListGitHubReposIDForOrgSecret(ctx context.Context, app service
 	}
 	return ids, nil
 }
+
+var _ services.GitHubRepoSecretsLister = (*Service)(nil)
+
+// ListGitHubRepoSecrets lists all repository secrets without revealing their encrypted values.
+func (s *Service) ListGitHubRepoSecrets(ctx context.Context, app services.GitHubApplication, owner, repo string) ([]*github.Secret, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "list the repo secrets", slog.String("application", string(app)), slog.String("owner", owner), slog.String("repo", repo))
+	return listSecrets(func(opt *github.ListOptions) (*github.Secrets, error) {
+		var secrets *github.Secrets
+		switch app {
+		case services.GitHubApplicationActions:
+			secrets, _, err = client.Actions.ListRepoSecrets(ctx, owner, repo, opt)
+		case services.GitHubApplicationDependabot:
+			secrets, _, err = client.Dependabot.ListRepoSecrets(ctx, owner, repo, opt)
+		case services.GitHubApplicationCodespaces:
+			secrets, _, err = client.Codespaces.ListRepoSecrets(ctx, owner, repo, opt)
+		default:
+			return nil, fmt.Errorf("unknown GitHub application: %s", app)
+		}
+		return secrets, err
+	})
+}
+
+var _ services.GitHubRepoSecretDeleter = (*Service)(nil)
+
+// DeleteGitHubRepoSecret deletes a repository secret.
+func (s *Service) DeleteGitHubRepoSecret(ctx context.Context, app services.GitHubApplication, owner, repo, name string) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "delete the repo secret", slog.String("application", string(app)), slog.String("owner", owner), slog.String("repo", repo), slog.String("name", name))
+	switch app {
+	case services.GitHubApplicationActions:
+		_, err = client.Actions.DeleteRepoSecret(ctx, owner, repo, name)
+	case services.GitHubApplicationDependabot:
+		_, err = client.Dependabot.DeleteRepoSecret(ctx, owner, repo, name)
+	case services.GitHubApplicationCodespaces:
+		_, err = client.Codespaces.DeleteRepoSecret(ctx, owner, repo, name)
+	default:
+		return fmt.Errorf("unknown GitHub application: %s", app)
+	}
+	return err
+}
+
+var _ services.GitHubEnvSecretsLister = (*Service)(nil)
+
+// ListGitHubEnvSecrets lists all environment secrets without revealing their encrypted values.
+func (s *Service) ListGitHubEnvSecrets(ctx context.Context, repoID int, env string) ([]*github.Secret, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "list the environment secrets", slog.Int("repoID", repoID), slog.String("env", env))
+	return listSecrets(func(opt *github.ListOptions) (*github.Secrets, error) {
+		secrets, _, err := client.Actions.ListEnvSecrets(ctx, repoID, env, opt)
+		return secrets, err
+	})
+}
+
+var _ services.GitHubEnvSecretDeleter = (*Service)(nil)
+
+// DeleteGitHubEnvSecret deletes an environment secret.
+func (s *Service) DeleteGitHubEnvSecret(ctx context.Context, repoID int, env, name string) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "delete the environment secret", slog.Int("repoID", repoID), slog.String("env", env), slog.String("name", name))
+	_, err = client.Actions.DeleteEnvSecret(ctx, repoID, env, name)
+	return err
+}
+
+var _ services.GitHubOrgSecretsLister = (*Service)(nil)
+
+// ListGitHubOrgSecrets lists all organization secrets without revealing their encrypted values.
+func (s *Service) ListGitHubOrgSecrets(ctx context.Context, app services.GitHubApplication, org string) ([]*github.Secret, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "list the org secrets", slog.String("application", string(app)), slog.String("org", org))
+	return listSecrets(func(opt *github.ListOptions) (*github.Secrets, error) {
+		var secrets *github.Secrets
+		switch app {
+		case services.GitHubApplicationActions:
+			secrets, _, err = client.Actions.ListOrgSecrets(ctx, org, opt)
+		case services.GitHubApplicationDependabot:
+			secrets, _, err = client.Dependabot.ListOrgSecrets(ctx, org, opt)
+		case services.GitHubApplicationCodespaces:
+			secrets, _, err = client.Codespaces.ListOrgSecrets(ctx, org, opt)
+		default:
+			return nil, fmt.Errorf("unknown GitHub application: %s", app)
+		}
+		return secrets, err
+	})
+}
+
+var _ services.GitHubOrgSecretDeleter = (*Service)(nil)
+
+// DeleteGitHubOrgSecret deletes an organization secret.
+func (s *Service) DeleteGitHubOrgSecret(ctx context.Context, app services.GitHubApplication, org, name string) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "delete the org secret", slog.String("application", string(app)), slog.String("org", org), slog.String("name", name))
+	switch app {
+	case services.GitHubApplicationActions:
+		_, err = client.Actions.DeleteOrgSecret(ctx, org, name)
+	case services.GitHubApplicationDependabot:
+		_, err = client.Dependabot.DeleteOrgSecret(ctx, org, name)
+	case services.GitHubApplicationCodespaces:
+		_, err = client.Codespaces.DeleteOrgSecret(ctx, org, name)
+	default:
+		return fmt.Errorf("unknown GitHub application: %s", app)
+	}
+	return err
+}
+
+var _ services.GitHubUserSecretGetter = (*Service)(nil)
+
+// GetGitHubUserSecret gets a single Codespaces secret of the authenticated user without revealing its encrypted value.
+func (s *Service) GetGitHubUserSecret(ctx context.Context, name string) (*github.Secret, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "get the user secret", slog.String("name", name))
+	secret, _, err := client.Codespaces.GetUserSecret(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+var _ services.GitHubUserSecretCreator = (*Service)(nil)
+
+// CreateGitHubUserSecret creates or updates a Codespaces secret of the authenticated user with an encrypted value.
+func (s *Service) CreateGitHubUserSecret(ctx context.Context, secret *github.EncryptedSecret) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "create or update the user secret", slog.String("name", secret.Name))
+	_, err = client.Codespaces.CreateOrUpdateUserSecret(ctx, secret)
+	return err
+}
+
+var _ services.GitHubUserPublicKeyGetter = (*Service)(nil)
+
+// GetGitHubUserPublicKey gets a public key that should be used for Codespaces secret encryption of the authenticated user.
+func (s *Service) GetGitHubUserPublicKey(ctx context.Context) (*github.PublicKey, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "get the user public key")
+	key, _, err := client.Codespaces.GetUserPublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+var _ services.GitHubReposIDForUserSecretLister = (*Service)(nil)
+
+// ListGitHubReposIDForUserSecret lists all repositories that have access to a Codespaces secret of the authenticated user.
+func (s *Service) ListGitHubReposIDForUserSecret(ctx context.Context, name string) ([]int64, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "list the repos for user secret", slog.String("name", name))
+	var ids []int64
+	opt := &github.ListOptions{
+		Page:    1,
+		PerPage: 100,
+	}
+	for {
+		repos, _, err := client.Codespaces.ListSelectedReposForUserSecret(ctx, name, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos.Repositories {
+			ids = append(ids, repo.GetID())
+		}
+		if len(repos.Repositories) == 0 || len(ids) >= repos.GetTotalCount() {
+			break
+		}
+		opt.Page++
+	}
+	return ids, nil
+}
+
+var _ services.GitHubUserSecretsLister = (*Service)(nil)
+
+// ListGitHubUserSecrets lists all Codespaces secrets of the authenticated user without revealing their encrypted values.
+func (s *Service) ListGitHubUserSecrets(ctx context.Context) ([]*github.Secret, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "list the user secrets")
+	return listSecrets(func(opt *github.ListOptions) (*github.Secrets, error) {
+		secrets, _, err := client.Codespaces.ListUserSecrets(ctx, opt)
+		return secrets, err
+	})
+}
+
+var _ services.GitHubUserSecretDeleter = (*Service)(nil)
+
+// DeleteGitHubUserSecret deletes a Codespaces secret of the authenticated user.
+func (s *Service) DeleteGitHubUserSecret(ctx context.Context, name string) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "delete the user secret", slog.String("name", name))
+	_, err = client.Codespaces.DeleteUserSecret(ctx, name)
+	return err
+}
+
+// listSecrets calls list until all pages are fetched.
+func listSecrets(list func(opt *github.ListOptions) (*github.Secrets, error)) ([]*github.Secret, error) {
+	var ret []*github.Secret
+	opt := &github.ListOptions{
+		Page:    1,
+		PerPage: 100,
+	}
+	for {
+		secrets, err := list(opt)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, secrets.Secrets...)
+		if len(secrets.Secrets) == 0 || len(ret) >= secrets.TotalCount {
+			break
+		}
+		opt.Page++
+	}
+	return ret, nil
+}
+
+var _ services.GitHubRepoVariableGetter = (*Service)(nil)
+
+// GetGitHubRepoVariable gets a single repository variable.
+func (s *Service) GetGitHubRepoVariable(ctx context.Context, owner, repo, name string) (*github.ActionsVariable, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "get the repo variable", slog.String("owner", owner), slog.String("repo", repo), slog.String("name", name))
+	variable, _, err := client.Actions.GetRepoVariable(ctx, owner, repo, name)
+	if err != nil {
+		return nil, err
+	}
+	return variable, nil
+}
+
+var _ services.GitHubRepoVariableCreator = (*Service)(nil)
+
+// CreateGitHubRepoVariable creates a repository variable.
+func (s *Service) CreateGitHubRepoVariable(ctx context.Context, owner, repo string, variable *github.ActionsVariable) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "create the repo variable", slog.String("owner", owner), slog.String("repo", repo), slog.String("name", variable.Name))
+	_, err = client.Actions.CreateRepoVariable(ctx, owner, repo, variable)
+	return err
+}
+
+var _ services.GitHubRepoVariableUpdater = (*Service)(nil)
+
+// UpdateGitHubRepoVariable updates a repository variable.
+func (s *Service) UpdateGitHubRepoVariable(ctx context.Context, owner, repo string, variable *github.ActionsVariable) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "update the repo variable", slog.String("owner", owner), slog.String("repo", repo), slog.String("name", variable.Name))
+	_, err = client.Actions.UpdateRepoVariable(ctx, owner, repo, variable)
+	return err
+}
+
+var _ services.GitHubEnvVariableGetter = (*Service)(nil)
+
+// GetGitHubEnvVariable gets a single environment variable.
+func (s *Service) GetGitHubEnvVariable(ctx context.Context, repoID int, env, name string) (*github.ActionsVariable, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "get the environment variable", slog.Int("repoID", repoID), slog.String("env", env), slog.String("name", name))
+	variable, _, err := client.Actions.GetEnvVariable(ctx, repoID, env, name)
+	if err != nil {
+		return nil, err
+	}
+	return variable, nil
+}
+
+var _ services.GitHubEnvVariableCreator = (*Service)(nil)
+
+// CreateGitHubEnvVariable creates an environment variable.
+func (s *Service) CreateGitHubEnvVariable(ctx context.Context, repoID int, env string, variable *github.ActionsVariable) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "create the environment variable", slog.Int("repoID", repoID), slog.String("env", env), slog.String("name", variable.Name))
+	_, err = client.Actions.CreateEnvVariable(ctx, repoID, env, variable)
+	return err
+}
+
+var _ services.GitHubEnvVariableUpdater = (*Service)(nil)
+
+// UpdateGitHubEnvVariable updates an environment variable.
+func (s *Service) UpdateGitHubEnvVariable(ctx context.Context, repoID int, env string, variable *github.ActionsVariable) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "update the environment variable", slog.Int("repoID", repoID), slog.String("env", env), slog.String("name", variable.Name))
+	_, err = client.Actions.UpdateEnvVariable(ctx, repoID, env, variable)
+	return err
+}
+
+var _ services.GitHubOrgVariableGetter = (*Service)(nil)
+
+// GetGitHubOrgVariable gets a single organization variable.
+func (s *Service) GetGitHubOrgVariable(ctx context.Context, org, name string) (*github.ActionsVariable, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "get the org variable", slog.String("org", org), slog.String("name", name))
+	variable, _, err := client.Actions.GetOrgVariable(ctx, org, name)
+	if err != nil {
+		return nil, err
+	}
+	return variable, nil
+}
+
+var _ services.GitHubOrgVariableCreator = (*Service)(nil)
+
+// CreateGitHubOrgVariable creates an organization variable.
+func (s *Service) CreateGitHubOrgVariable(ctx context.Context, org string, variable *github.ActionsVariable) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "create the org variable", slog.String("org", org), slog.String("name", variable.Name))
+	_, err = client.Actions.CreateOrgVariable(ctx, org, variable)
+	return err
+}
+
+var _ services.GitHubOrgVariableUpdater = (*Service)(nil)
+
+// UpdateGitHubOrgVariable updates an organization variable.
+func (s *Service) UpdateGitHubOrgVariable(ctx context.Context, org string, variable *github.ActionsVariable) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "update the org variable", slog.String("org", org), slog.String("name", variable.Name))
+	_, err = client.Actions.UpdateOrgVariable(ctx, org, variable)
+	return err
+}
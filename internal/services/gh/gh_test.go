@@ -0,0 +1,248 @@
+package gh
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/mock"
+)
+
+// publicKeyHandler returns the path of the request as the ID of the public key.
+func publicKeyHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(map[string]any{
+			"key_id": r.URL.Path,
+			"key":    "X4Dk1lVSh8C21cDezdGivFERcid2L7UAfFCWeiRn1mU=",
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestGetGitHubRepoPublicKey(t *testing.T) {
+	s, ctx := newTestService(t, publicKeyHandler(t), 0, 0)
+
+	// Actions, Dependabot, and Codespaces have their own public keys.
+	tests := []struct {
+		app  services.GitHubApplication
+		want string
+	}{
+		{services.GitHubApplicationActions, "/api/v3/repos/shogo82148/op-sync/actions/secrets/public-key"},
+		{services.GitHubApplicationDependabot, "/api/v3/repos/shogo82148/op-sync/dependabot/secrets/public-key"},
+		{services.GitHubApplicationCodespaces, "/api/v3/repos/shogo82148/op-sync/codespaces/secrets/public-key"},
+	}
+	for _, tt := range tests {
+		key, err := s.GetGitHubRepoPublicKey(ctx, tt.app, "shogo82148", "op-sync")
+		if err != nil {
+			t.Errorf("%s: %v", tt.app, err)
+			continue
+		}
+		if got := key.GetKeyID(); got != tt.want {
+			t.Errorf("%s: unexpected key: want %q, got %q", tt.app, tt.want, got)
+		}
+	}
+}
+
+func TestGetGitHubOrgPublicKey(t *testing.T) {
+	s, ctx := newTestService(t, publicKeyHandler(t), 0, 0)
+
+	tests := []struct {
+		app  services.GitHubApplication
+		want string
+	}{
+		{services.GitHubApplicationActions, "/api/v3/orgs/shogo82148/actions/secrets/public-key"},
+		{services.GitHubApplicationDependabot, "/api/v3/orgs/shogo82148/dependabot/secrets/public-key"},
+		{services.GitHubApplicationCodespaces, "/api/v3/orgs/shogo82148/codespaces/secrets/public-key"},
+	}
+	for _, tt := range tests {
+		key, err := s.GetGitHubOrgPublicKey(ctx, tt.app, "shogo82148")
+		if err != nil {
+			t.Errorf("%s: %v", tt.app, err)
+			continue
+		}
+		if got := key.GetKeyID(); got != tt.want {
+			t.Errorf("%s: unexpected key: want %q, got %q", tt.app, tt.want, got)
+		}
+	}
+}
+
+func TestListGitHubRepos(t *testing.T) {
+	writeJSON := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			t.Error(err)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"login": "shogo82148", "type": "User"})
+	})
+	mux.HandleFunc("GET /api/v3/users/{user}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"login": r.PathValue("user"), "type": "User"})
+	})
+	mux.HandleFunc("GET /api/v3/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("affiliation"); got != "owner" {
+			t.Errorf("unexpected affiliation: want owner, got %q", got)
+		}
+		writeJSON(w, []any{
+			map[string]any{"name": "public", "private": false},
+			map[string]any{"name": "private", "private": true},
+		})
+	})
+	mux.HandleFunc("GET /api/v3/users/{user}/repos", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []any{
+			map[string]any{"name": "public", "private": false},
+		})
+	})
+	s, ctx := newTestService(t, mux.ServeHTTP, 0, 0)
+
+	tests := []struct {
+		owner string
+		want  []string
+	}{
+		// the private repositories of the authenticated user are listed.
+		{"Shogo82148", []string{"public", "private"}},
+		{"someone-else", []string{"public"}},
+	}
+	for _, tt := range tests {
+		repos, err := s.ListGitHubRepos(ctx, tt.owner)
+		if err != nil {
+			t.Errorf("%s: %v", tt.owner, err)
+			continue
+		}
+		var got []string
+		for _, repo := range repos {
+			got = append(got, repo.GetName())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: unexpected repos: want %q, got %q", tt.owner, tt.want, got)
+		}
+	}
+}
+
+func TestClient_Concurrent(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v3/app/installations/2/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		err := json.NewEncoder(w).Encode(map[string]any{
+			"token":      "installation-token",
+			"expires_at": time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	s, ctx := newTestService(t, mux.ServeHTTP, 0, 0)
+
+	// the private key of the GitHub App is read while minting the token.
+	var reads atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.opts.OnePasswordReader = mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+		if uri != "op://vault/item/private-key" {
+			return nil, errors.New("the private key is not found")
+		}
+		if reads.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return privateKey, nil
+	})
+	id := services.GitHubIdentityFromContext(ctx)
+	app := services.WithGitHubIdentity(ctx, services.GitHubIdentity{
+		Host:              id.Host,
+		AppID:             1,
+		AppInstallationID: 2,
+		AppPrivateKey:     "op://vault/item/private-key",
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Go(func() {
+			_, err := s.client(app)
+			errs <- err
+		})
+	}
+	<-started
+
+	// the other identities don't wait for the token of the GitHub App.
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.client(ctx)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the client of the other identity is blocked")
+	}
+
+	// the callers that give up don't wait for the token.
+	canceled, cancel := context.WithCancel(app)
+	cancel()
+	if _, err := s.client(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: want %v, got %v", context.Canceled, err)
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if got := reads.Load(); got != 1 {
+		t.Errorf("unexpected reads: want 1, got %d", got)
+	}
+}
+
+func TestClient_Retry(t *testing.T) {
+	var reads int
+	s := NewService(&Options{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			reads++
+			return nil, errors.New("the private key is not found")
+		}),
+	})
+	ctx := services.WithGitHubIdentity(context.Background(), services.GitHubIdentity{
+		AppID:             1,
+		AppInstallationID: 2,
+		AppPrivateKey:     "op://vault/item/private-key",
+	})
+
+	// the failed token is minted again.
+	for range 2 {
+		if _, err := s.client(ctx); err == nil {
+			t.Error("want error, got nil")
+		}
+	}
+	if reads != 2 {
+		t.Errorf("unexpected reads: want 2, got %d", reads)
+	}
+}
@@ -0,0 +1,223 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+)
+
+const (
+	// defaultMaxConcurrentRequests is the default limit of concurrent requests to GitHub.
+	defaultMaxConcurrentRequests = 8
+
+	// defaultMaxRetries is the default number of retries on rate limits.
+	defaultMaxRetries = 5
+
+	// defaultBaseBackoff is the first wait of the secondary rate limits without Retry-After.
+	// GitHub recommends waiting at least one minute.
+	defaultBaseBackoff = time.Minute
+)
+
+// rateLimitTransport is an [http.RoundTripper] that caps the concurrent requests,
+// and waits for the rate limits of GitHub.
+//
+// It retries the requests rejected by the rate limits:
+//   - the primary rate limit ([github.RateLimitError]): until the quota is reset.
+//   - the secondary rate limit ([github.AbuseRateLimitError]): Retry-After, or the exponential backoff.
+//
+// While a request is waiting, the other requests wait, too.
+type rateLimitTransport struct {
+	base        http.RoundTripper
+	sem         chan struct{}
+	maxRetries  int
+	baseBackoff time.Duration
+
+	mu sync.Mutex
+	// pauseUntil is the time until when no requests are sent.
+	pauseUntil time.Time
+}
+
+func newRateLimitTransport(base http.RoundTripper, maxConcurrentRequests, maxRetries int) *rateLimitTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if maxConcurrentRequests <= 0 {
+		maxConcurrentRequests = defaultMaxConcurrentRequests
+	}
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+	return &rateLimitTransport{
+		base:        base,
+		sem:         make(chan struct{}, maxConcurrentRequests),
+		maxRetries:  maxRetries,
+		baseBackoff: defaultBaseBackoff,
+	}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := t.waitPause(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := t.roundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		logRate(ctx, resp)
+
+		wait, limited := t.retryDelay(resp, attempt)
+		if !limited {
+			if wait > 0 {
+				// the quota is exhausted by this request.
+				// go-github rejects the following requests without sending them until the reset,
+				// so wait here instead.
+				slog.InfoContext(ctx, "GitHub API rate limit is exhausted, waiting for the reset", slog.Duration("wait", wait))
+				t.pause(wait)
+				if err := t.waitPause(ctx); err != nil {
+					resp.Body.Close()
+					return nil, err
+				}
+			}
+			return resp, nil
+		}
+		if attempt >= t.maxRetries || (req.Body != nil && req.GetBody == nil) {
+			// give up, and go-github reports the rate limit error.
+			return resp, nil
+		}
+
+		slog.InfoContext(
+			ctx,
+			"GitHub API rate limit exceeded, retrying",
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.Duration("wait", wait),
+			slog.Int("attempt", attempt+1),
+		)
+		resp.Body.Close()
+		t.pause(wait)
+
+		// rewind the body for the retry.
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// roundTrip sends the request while holding the semaphore.
+func (t *rateLimitTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-t.sem }()
+	return t.base.RoundTrip(req)
+}
+
+// pause stops sending requests for d.
+func (t *rateLimitTransport) pause(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until := time.Now().Add(d)
+	if until.After(t.pauseUntil) {
+		t.pauseUntil = until
+	}
+}
+
+// waitPause waits until the pause ends.
+func (t *rateLimitTransport) waitPause(ctx context.Context) error {
+	t.mu.Lock()
+	d := time.Until(t.pauseUntil)
+	t.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryDelay returns how long to wait before the next request.
+// limited reports whether the request was rejected by the rate limits.
+// If the request succeeded but it exhausted the quota, it returns the time until the reset and false.
+func (t *rateLimitTransport) retryDelay(resp *http.Response, attempt int) (wait time.Duration, limited bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			return untilReset(resp.Header), false
+		}
+		return 0, false
+	}
+
+	// CheckResponse restores the body after reading it.
+	err := github.CheckResponse(resp)
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	switch {
+	case errors.As(err, &rateErr):
+		return max(time.Until(rateErr.Rate.Reset.Time), 0), true
+	case errors.As(err, &abuseErr):
+		if abuseErr.RetryAfter != nil {
+			return max(*abuseErr.RetryAfter, 0), true
+		}
+		return t.backoff(attempt), true
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// go-github doesn't recognize 429 as the rate limit.
+		if v, err := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 64); err == nil {
+			return max(time.Duration(v)*time.Second, 0), true
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			return untilReset(resp.Header), true
+		}
+		return t.backoff(attempt), true
+	}
+	return 0, false
+}
+
+// backoff returns the exponential backoff for the attempt.
+func (t *rateLimitTransport) backoff(attempt int) time.Duration {
+	return t.baseBackoff << min(attempt, 10)
+}
+
+// untilReset returns the duration until the quota is reset.
+func untilReset(h http.Header) time.Duration {
+	reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return max(time.Until(time.Unix(reset, 0)), 0)
+}
+
+// logRate logs the remaining quota of the rate limit.
+func logRate(ctx context.Context, resp *http.Response) {
+	h := resp.Header
+	if h.Get("X-RateLimit-Remaining") == "" {
+		return
+	}
+	slog.DebugContext(
+		ctx,
+		"GitHub API rate limit",
+		slog.String("resource", h.Get("X-RateLimit-Resource")),
+		slog.String("limit", h.Get("X-RateLimit-Limit")),
+		slog.String("remaining", h.Get("X-RateLimit-Remaining")),
+		slog.String("reset", h.Get("X-RateLimit-Reset")),
+	)
+}
@@ -0,0 +1,211 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/shogo82148/op-sync/internal/services"
+)
+
+// newTestService returns a Service that sends the requests to a stand-in of GitHub Enterprise Server.
+func newTestService(t *testing.T, handler http.HandlerFunc, maxConcurrentRequests, maxRetries int) (*Service, context.Context) {
+	t.Helper()
+
+	ts := httptest.NewTLSServer(handler)
+	t.Cleanup(ts.Close)
+	t.Setenv("OP_SYNC_TEST_GITHUB_TOKEN", "token")
+
+	s := NewService(&Options{})
+	s.transport = newRateLimitTransport(ts.Client().Transport, maxConcurrentRequests, maxRetries)
+	s.transport.baseBackoff = time.Millisecond
+	ctx := services.WithGitHubIdentity(context.Background(), services.GitHubIdentity{
+		Host:     ts.Listener.Addr().String(),
+		TokenEnv: "OP_SYNC_TEST_GITHUB_TOKEN",
+	})
+	return s, ctx
+}
+
+func writeRepo(t *testing.T, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", "4999")
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	if err := json.NewEncoder(w).Encode(map[string]any{"id": 1, "name": "op-sync"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func writeSecondaryRateLimit(t *testing.T, w http.ResponseWriter, retryAfter string) {
+	w.Header().Set("Content-Type", "application/json")
+	if retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+	w.WriteHeader(http.StatusForbidden)
+	err := json.NewEncoder(w).Encode(map[string]any{
+		"message":           "You have exceeded a secondary rate limit.",
+		"documentation_url": "https://docs.github.com/rest/overview/resources-in-the-rest-api#secondary-rate-limits",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRateLimit_Secondary(t *testing.T) {
+	var count atomic.Int64
+	s, ctx := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/shogo82148/op-sync" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected authorization: %s", r.Header.Get("Authorization"))
+		}
+		switch count.Add(1) {
+		case 1:
+			writeSecondaryRateLimit(t, w, "0")
+		case 2:
+			writeSecondaryRateLimit(t, w, "")
+		default:
+			writeRepo(t, w)
+		}
+	}, 0, 0)
+
+	repo, err := s.GetGitHubRepo(ctx, "shogo82148", "op-sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.GetID() != 1 {
+		t.Errorf("unexpected ID: want 1, got %d", repo.GetID())
+	}
+	if got := count.Load(); got != 3 {
+		t.Errorf("unexpected requests: want 3, got %d", got)
+	}
+}
+
+func TestRateLimit_Primary(t *testing.T) {
+	var count atomic.Int64
+	s, ctx := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch count.Add(1) {
+		case 1:
+			// the quota has been reset already.
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			writeRepo(t, w)
+		}
+	}, 0, 0)
+
+	if _, err := s.GetGitHubRepo(ctx, "shogo82148", "op-sync"); err != nil {
+		t.Fatal(err)
+	}
+	if got := count.Load(); got != 3 {
+		t.Errorf("unexpected requests: want 3, got %d", got)
+	}
+}
+
+func TestRateLimit_RetryBody(t *testing.T) {
+	var count atomic.Int64
+	s, ctx := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		var secret github.EncryptedSecret
+		if err := json.NewDecoder(r.Body).Decode(&secret); err != nil {
+			t.Error(err)
+		}
+		if secret.EncryptedValue != "encrypted" {
+			t.Errorf("unexpected body: %v", secret)
+		}
+		if count.Add(1) == 1 {
+			writeSecondaryRateLimit(t, w, "0")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}, 0, 0)
+
+	err := s.CreateGitHubRepoSecret(ctx, services.GitHubApplicationActions, "shogo82148", "op-sync", &github.EncryptedSecret{
+		Name:           "VERY_SECRET_TOKEN",
+		KeyID:          "key_id",
+		EncryptedValue: "encrypted",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count.Load(); got != 2 {
+		t.Errorf("unexpected requests: want 2, got %d", got)
+	}
+}
+
+func TestRateLimit_GiveUp(t *testing.T) {
+	var count atomic.Int64
+	s, ctx := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		count.Add(1)
+		writeSecondaryRateLimit(t, w, "0")
+	}, 0, 2)
+
+	_, err := s.GetGitHubRepo(ctx, "shogo82148", "op-sync")
+	var abuseErr *github.AbuseRateLimitError
+	if !errors.As(err, &abuseErr) {
+		t.Fatalf("want AbuseRateLimitError, got %v", err)
+	}
+	if got := count.Load(); got != 3 {
+		t.Errorf("unexpected requests: want 3, got %d", got)
+	}
+}
+
+func TestRateLimit_Canceled(t *testing.T) {
+	s, ctx := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		writeSecondaryRateLimit(t, w, "3600")
+	}, 0, 0)
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	_, err := s.GetGitHubRepo(ctx, "shogo82148", "op-sync")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRateLimit_MaxConcurrentRequests(t *testing.T) {
+	var running, maxRunning atomic.Int64
+	s, ctx := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		writeRepo(t, w)
+	}, 2, 0)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.GetGitHubRepo(ctx, "shogo82148", "op-sync"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := maxRunning.Load(); got > 2 {
+		t.Errorf("too many concurrent requests: want at most 2, got %d", got)
+	}
+}
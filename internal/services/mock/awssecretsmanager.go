@@ -30,3 +30,91 @@ type SecretsManagerSecretUpdater func(ctx context.Context, region string, in *se
 func (f SecretsManagerSecretUpdater) SecretsManagerUpdateSecret(ctx context.Context, region string, in *secretsmanager.UpdateSecretInput) (*secretsmanager.UpdateSecretOutput, error) {
 	return f(ctx, region, in)
 }
+
+var _ services.SecretsManagerSecretsLister = SecretsManagerSecretsLister(nil)
+
+type SecretsManagerSecretsLister func(ctx context.Context, region string, in *secretsmanager.ListSecretsInput) (*secretsmanager.ListSecretsOutput, error)
+
+func (f SecretsManagerSecretsLister) SecretsManagerListSecrets(ctx context.Context, region string, in *secretsmanager.ListSecretsInput) (*secretsmanager.ListSecretsOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SecretsManagerSecretDeleter = SecretsManagerSecretDeleter(nil)
+
+type SecretsManagerSecretDeleter func(ctx context.Context, region string, in *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error)
+
+func (f SecretsManagerSecretDeleter) SecretsManagerDeleteSecret(ctx context.Context, region string, in *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SecretsManagerSecretDescriber = SecretsManagerSecretDescriber(nil)
+
+type SecretsManagerSecretDescriber func(ctx context.Context, region string, in *secretsmanager.DescribeSecretInput) (*secretsmanager.DescribeSecretOutput, error)
+
+func (f SecretsManagerSecretDescriber) SecretsManagerDescribeSecret(ctx context.Context, region string, in *secretsmanager.DescribeSecretInput) (*secretsmanager.DescribeSecretOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SecretsManagerResourcePolicyGetter = SecretsManagerResourcePolicyGetter(nil)
+
+type SecretsManagerResourcePolicyGetter func(ctx context.Context, region string, in *secretsmanager.GetResourcePolicyInput) (*secretsmanager.GetResourcePolicyOutput, error)
+
+func (f SecretsManagerResourcePolicyGetter) SecretsManagerGetResourcePolicy(ctx context.Context, region string, in *secretsmanager.GetResourcePolicyInput) (*secretsmanager.GetResourcePolicyOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SecretsManagerResourcePolicyPutter = SecretsManagerResourcePolicyPutter(nil)
+
+type SecretsManagerResourcePolicyPutter func(ctx context.Context, region string, in *secretsmanager.PutResourcePolicyInput) (*secretsmanager.PutResourcePolicyOutput, error)
+
+func (f SecretsManagerResourcePolicyPutter) SecretsManagerPutResourcePolicy(ctx context.Context, region string, in *secretsmanager.PutResourcePolicyInput) (*secretsmanager.PutResourcePolicyOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SecretsManagerResourceTagger = SecretsManagerResourceTagger(nil)
+
+type SecretsManagerResourceTagger func(ctx context.Context, region string, in *secretsmanager.TagResourceInput) (*secretsmanager.TagResourceOutput, error)
+
+func (f SecretsManagerResourceTagger) SecretsManagerTagResource(ctx context.Context, region string, in *secretsmanager.TagResourceInput) (*secretsmanager.TagResourceOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SecretsManagerSecretReplicator = SecretsManagerSecretReplicator(nil)
+
+type SecretsManagerSecretReplicator func(ctx context.Context, region string, in *secretsmanager.ReplicateSecretToRegionsInput) (*secretsmanager.ReplicateSecretToRegionsOutput, error)
+
+func (f SecretsManagerSecretReplicator) SecretsManagerReplicateSecretToRegions(ctx context.Context, region string, in *secretsmanager.ReplicateSecretToRegionsInput) (*secretsmanager.ReplicateSecretToRegionsOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SecretsManagerSecretValuePutter = SecretsManagerSecretValuePutter(nil)
+
+type SecretsManagerSecretValuePutter func(ctx context.Context, region string, in *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error)
+
+func (f SecretsManagerSecretValuePutter) SecretsManagerPutSecretValue(ctx context.Context, region string, in *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SecretsManagerVersionStageUpdater = SecretsManagerVersionStageUpdater(nil)
+
+type SecretsManagerVersionStageUpdater func(ctx context.Context, region string, in *secretsmanager.UpdateSecretVersionStageInput) (*secretsmanager.UpdateSecretVersionStageOutput, error)
+
+func (f SecretsManagerVersionStageUpdater) SecretsManagerUpdateSecretVersionStage(ctx context.Context, region string, in *secretsmanager.UpdateSecretVersionStageInput) (*secretsmanager.UpdateSecretVersionStageOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SecretsManagerSecretRotator = SecretsManagerSecretRotator(nil)
+
+type SecretsManagerSecretRotator func(ctx context.Context, region string, in *secretsmanager.RotateSecretInput) (*secretsmanager.RotateSecretOutput, error)
+
+func (f SecretsManagerSecretRotator) SecretsManagerRotateSecret(ctx context.Context, region string, in *secretsmanager.RotateSecretInput) (*secretsmanager.RotateSecretOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SecretsManagerRotationCanceler = SecretsManagerRotationCanceler(nil)
+
+type SecretsManagerRotationCanceler func(ctx context.Context, region string, in *secretsmanager.CancelRotateSecretInput) (*secretsmanager.CancelRotateSecretOutput, error)
+
+func (f SecretsManagerRotationCanceler) SecretsManagerCancelRotateSecret(ctx context.Context, region string, in *secretsmanager.CancelRotateSecretInput) (*secretsmanager.CancelRotateSecretOutput, error) {
+	return f(ctx, region, in)
+}
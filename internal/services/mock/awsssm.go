@@ -22,3 +22,35 @@ type SSMParameterPutter func(ctx context.Context, region string, in *ssm.PutPara
 func (f SSMParameterPutter) SSMPutParameter(ctx context.Context, region string, in *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
 	return f(ctx, region, in)
 }
+
+var _ services.SSMParametersDescriber = SSMParametersDescriber(nil)
+
+type SSMParametersDescriber func(ctx context.Context, region string, in *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error)
+
+func (f SSMParametersDescriber) SSMDescribeParameters(ctx context.Context, region string, in *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SSMParameterDeleter = SSMParameterDeleter(nil)
+
+type SSMParameterDeleter func(ctx context.Context, region string, in *ssm.DeleteParameterInput) (*ssm.DeleteParameterOutput, error)
+
+func (f SSMParameterDeleter) SSMDeleteParameter(ctx context.Context, region string, in *ssm.DeleteParameterInput) (*ssm.DeleteParameterOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SSMTagsLister = SSMTagsLister(nil)
+
+type SSMTagsLister func(ctx context.Context, region string, in *ssm.ListTagsForResourceInput) (*ssm.ListTagsForResourceOutput, error)
+
+func (f SSMTagsLister) SSMListTagsForResource(ctx context.Context, region string, in *ssm.ListTagsForResourceInput) (*ssm.ListTagsForResourceOutput, error) {
+	return f(ctx, region, in)
+}
+
+var _ services.SSMTagsAdder = SSMTagsAdder(nil)
+
+type SSMTagsAdder func(ctx context.Context, region string, in *ssm.AddTagsToResourceInput) (*ssm.AddTagsToResourceOutput, error)
+
+func (f SSMTagsAdder) SSMAddTagsToResource(ctx context.Context, region string, in *ssm.AddTagsToResourceInput) (*ssm.AddTagsToResourceOutput, error) {
+	return f(ctx, region, in)
+}
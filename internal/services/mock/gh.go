@@ -16,6 +16,24 @@ func (f GitHubUserGetter) GetGitHubUser(ctx context.Context) (*github.User, erro
 	return f(ctx)
 }
 
+var _ services.GitHubUserByLoginGetter = GitHubUserByLoginGetter(nil)
+
+// GitHubUserByLoginGetter fetches the GitHub user by the login name.
+type GitHubUserByLoginGetter func(ctx context.Context, login string) (*github.User, error)
+
+func (f GitHubUserByLoginGetter) GetGitHubUserByLogin(ctx context.Context, login string) (*github.User, error) {
+	return f(ctx, login)
+}
+
+var _ services.GitHubTeamGetter = GitHubTeamGetter(nil)
+
+// GitHubTeamGetter fetches the team in the organization by the slug.
+type GitHubTeamGetter func(ctx context.Context, org, slug string) (*github.Team, error)
+
+func (f GitHubTeamGetter) GetGitHubTeam(ctx context.Context, org, slug string) (*github.Team, error) {
+	return f(ctx, org, slug)
+}
+
 var _ services.GitHubRepoGetter = GitHubRepoGetter(nil)
 
 // GitHubRepoGetter fetches the GitHub repository.
@@ -25,6 +43,15 @@ func (f GitHubRepoGetter) GetGitHubRepo(ctx context.Context, owner, repo string)
 	return f(ctx, owner, repo)
 }
 
+var _ services.GitHubReposLister = GitHubReposLister(nil)
+
+// GitHubReposLister lists the repositories of the user or the organization.
+type GitHubReposLister func(ctx context.Context, owner string) ([]*github.Repository, error)
+
+func (f GitHubReposLister) ListGitHubRepos(ctx context.Context, owner string) ([]*github.Repository, error) {
+	return f(ctx, owner)
+}
+
 var _ services.GitHubRepoSecretGetter = GitHubRepoSecretGetter(nil)
 
 // GitHubRepoSecretGetter gets a single repository secret without revealing its encrypted value.
@@ -46,10 +73,37 @@ func (f GitHubRepoSecretCreator) CreateGitHubRepoSecret(ctx context.Context, app
 var _ services.GitHubRepoPublicKeyGetter = GitHubRepoPublicKeyGetter(nil)
 
 // GitHubRepoPublicKeyGetter gets a public key that should be used for secret encryption.
-type GitHubRepoPublicKeyGetter func(ctx context.Context, owner, repo string) (*github.PublicKey, error)
+type GitHubRepoPublicKeyGetter func(ctx context.Context, app services.GitHubApplication, owner, repo string) (*github.PublicKey, error)
 
-func (f GitHubRepoPublicKeyGetter) GetGitHubRepoPublicKey(ctx context.Context, owner, repo string) (*github.PublicKey, error) {
-	return f(ctx, owner, repo)
+func (f GitHubRepoPublicKeyGetter) GetGitHubRepoPublicKey(ctx context.Context, app services.GitHubApplication, owner, repo string) (*github.PublicKey, error) {
+	return f(ctx, app, owner, repo)
+}
+
+var _ services.GitHubEnvGetter = GitHubEnvGetter(nil)
+
+// GitHubEnvGetter gets the environment of the repository.
+type GitHubEnvGetter func(ctx context.Context, owner, repo, env string) (*github.Environment, error)
+
+func (f GitHubEnvGetter) GetGitHubEnv(ctx context.Context, owner, repo, env string) (*github.Environment, error) {
+	return f(ctx, owner, repo, env)
+}
+
+var _ services.GitHubEnvCreator = GitHubEnvCreator(nil)
+
+// GitHubEnvCreator creates the environment of the repository.
+type GitHubEnvCreator func(ctx context.Context, owner, repo, env string, opts *github.CreateUpdateEnvironment) error
+
+func (f GitHubEnvCreator) CreateGitHubEnv(ctx context.Context, owner, repo, env string, opts *github.CreateUpdateEnvironment) error {
+	return f(ctx, owner, repo, env, opts)
+}
+
+var _ services.GitHubDeploymentBranchPolicyCreator = GitHubDeploymentBranchPolicyCreator(nil)
+
+// GitHubDeploymentBranchPolicyCreator creates a deployment branch policy of the environment.
+type GitHubDeploymentBranchPolicyCreator func(ctx context.Context, owner, repo, env, pattern string) error
+
+func (f GitHubDeploymentBranchPolicyCreator) CreateGitHubDeploymentBranchPolicy(ctx context.Context, owner, repo, env, pattern string) error {
+	return f(ctx, owner, repo, env, pattern)
 }
 
 var _ services.GitHubEnvSecretGetter = GitHubEnvSecretGetter(nil)
@@ -100,8 +154,206 @@ func (f GitHubOrgSecretCreator) CreateGitHubOrgSecret(ctx context.Context, app s
 var _ services.GitHubOrgPublicKeyGetter = GitHubOrgPublicKeyGetter(nil)
 
 // GitHubOrgPublicKeyGetter gets a public key that should be used for secret encryption.
-type GitHubOrgPublicKeyGetter func(ctx context.Context, org string) (*github.PublicKey, error)
+type GitHubOrgPublicKeyGetter func(ctx context.Context, app services.GitHubApplication, org string) (*github.PublicKey, error)
+
+func (f GitHubOrgPublicKeyGetter) GetGitHubOrgPublicKey(ctx context.Context, app services.GitHubApplication, org string) (*github.PublicKey, error) {
+	return f(ctx, app, org)
+}
+
+var _ services.GitHubReposIDForOrgSecretLister = GitHubReposIDForOrgSecretLister(nil)
+
+// GitHubReposIDForOrgSecretLister lists all repositories that have access to a secret.
+type GitHubReposIDForOrgSecretLister func(ctx context.Context, app services.GitHubApplication, org, name string) ([]int64, error)
+
+func (f GitHubReposIDForOrgSecretLister) ListGitHubReposIDForOrgSecret(ctx context.Context, app services.GitHubApplication, org, name string) ([]int64, error) {
+	return f(ctx, app, org, name)
+}
+
+var _ services.GitHubRepoSecretsLister = GitHubRepoSecretsLister(nil)
+
+// GitHubRepoSecretsLister lists all repository secrets without revealing their encrypted values.
+type GitHubRepoSecretsLister func(ctx context.Context, app services.GitHubApplication, owner, repo string) ([]*github.Secret, error)
+
+func (f GitHubRepoSecretsLister) ListGitHubRepoSecrets(ctx context.Context, app services.GitHubApplication, owner, repo string) ([]*github.Secret, error) {
+	return f(ctx, app, owner, repo)
+}
+
+var _ services.GitHubRepoSecretDeleter = GitHubRepoSecretDeleter(nil)
+
+// GitHubRepoSecretDeleter deletes a repository secret.
+type GitHubRepoSecretDeleter func(ctx context.Context, app services.GitHubApplication, owner, repo, name string) error
+
+func (f GitHubRepoSecretDeleter) DeleteGitHubRepoSecret(ctx context.Context, app services.GitHubApplication, owner, repo, name string) error {
+	return f(ctx, app, owner, repo, name)
+}
+
+var _ services.GitHubEnvSecretsLister = GitHubEnvSecretsLister(nil)
+
+// GitHubEnvSecretsLister lists all environment secrets without revealing their encrypted values.
+type GitHubEnvSecretsLister func(ctx context.Context, repoID int, env string) ([]*github.Secret, error)
+
+func (f GitHubEnvSecretsLister) ListGitHubEnvSecrets(ctx context.Context, repoID int, env string) ([]*github.Secret, error) {
+	return f(ctx, repoID, env)
+}
+
+var _ services.GitHubEnvSecretDeleter = GitHubEnvSecretDeleter(nil)
+
+// GitHubEnvSecretDeleter deletes an environment secret.
+type GitHubEnvSecretDeleter func(ctx context.Context, repoID int, env, name string) error
+
+func (f GitHubEnvSecretDeleter) DeleteGitHubEnvSecret(ctx context.Context, repoID int, env, name string) error {
+	return f(ctx, repoID, env, name)
+}
+
+var _ services.GitHubOrgSecretsLister = GitHubOrgSecretsLister(nil)
+
+// GitHubOrgSecretsLister lists all organization secrets without revealing their encrypted values.
+type GitHubOrgSecretsLister func(ctx context.Context, app services.GitHubApplication, org string) ([]*github.Secret, error)
+
+func (f GitHubOrgSecretsLister) ListGitHubOrgSecrets(ctx context.Context, app services.GitHubApplication, org string) ([]*github.Secret, error) {
+	return f(ctx, app, org)
+}
+
+var _ services.GitHubOrgSecretDeleter = GitHubOrgSecretDeleter(nil)
+
+// GitHubOrgSecretDeleter deletes an organization secret.
+type GitHubOrgSecretDeleter func(ctx context.Context, app services.GitHubApplication, org, name string) error
+
+func (f GitHubOrgSecretDeleter) DeleteGitHubOrgSecret(ctx context.Context, app services.GitHubApplication, org, name string) error {
+	return f(ctx, app, org, name)
+}
+
+var _ services.GitHubUserSecretGetter = GitHubUserSecretGetter(nil)
+
+// GitHubUserSecretGetter gets a single Codespaces secret of the authenticated user without revealing its encrypted value.
+type GitHubUserSecretGetter func(ctx context.Context, name string) (*github.Secret, error)
+
+func (f GitHubUserSecretGetter) GetGitHubUserSecret(ctx context.Context, name string) (*github.Secret, error) {
+	return f(ctx, name)
+}
+
+var _ services.GitHubUserSecretCreator = GitHubUserSecretCreator(nil)
+
+// GitHubUserSecretCreator creates or updates a Codespaces secret of the authenticated user with an encrypted value.
+type GitHubUserSecretCreator func(ctx context.Context, secret *github.EncryptedSecret) error
+
+func (f GitHubUserSecretCreator) CreateGitHubUserSecret(ctx context.Context, secret *github.EncryptedSecret) error {
+	return f(ctx, secret)
+}
+
+var _ services.GitHubUserPublicKeyGetter = GitHubUserPublicKeyGetter(nil)
+
+// GitHubUserPublicKeyGetter gets a public key that should be used for Codespaces secret encryption of the authenticated user.
+type GitHubUserPublicKeyGetter func(ctx context.Context) (*github.PublicKey, error)
+
+func (f GitHubUserPublicKeyGetter) GetGitHubUserPublicKey(ctx context.Context) (*github.PublicKey, error) {
+	return f(ctx)
+}
+
+var _ services.GitHubReposIDForUserSecretLister = GitHubReposIDForUserSecretLister(nil)
+
+// GitHubReposIDForUserSecretLister lists all repositories that have access to a Codespaces secret of the authenticated user.
+type GitHubReposIDForUserSecretLister func(ctx context.Context, name string) ([]int64, error)
+
+func (f GitHubReposIDForUserSecretLister) ListGitHubReposIDForUserSecret(ctx context.Context, name string) ([]int64, error) {
+	return f(ctx, name)
+}
+
+var _ services.GitHubUserSecretsLister = GitHubUserSecretsLister(nil)
+
+// GitHubUserSecretsLister lists all Codespaces secrets of the authenticated user without revealing their encrypted values.
+type GitHubUserSecretsLister func(ctx context.Context) ([]*github.Secret, error)
+
+func (f GitHubUserSecretsLister) ListGitHubUserSecrets(ctx context.Context) ([]*github.Secret, error) {
+	return f(ctx)
+}
+
+var _ services.GitHubUserSecretDeleter = GitHubUserSecretDeleter(nil)
+
+// GitHubUserSecretDeleter deletes a Codespaces secret of the authenticated user.
+type GitHubUserSecretDeleter func(ctx context.Context, name string) error
+
+func (f GitHubUserSecretDeleter) DeleteGitHubUserSecret(ctx context.Context, name string) error {
+	return f(ctx, name)
+}
+
+var _ services.GitHubRepoVariableGetter = GitHubRepoVariableGetter(nil)
+
+// GitHubRepoVariableGetter gets a single repository variable.
+type GitHubRepoVariableGetter func(ctx context.Context, owner, repo, name string) (*github.ActionsVariable, error)
+
+func (f GitHubRepoVariableGetter) GetGitHubRepoVariable(ctx context.Context, owner, repo, name string) (*github.ActionsVariable, error) {
+	return f(ctx, owner, repo, name)
+}
+
+var _ services.GitHubRepoVariableCreator = GitHubRepoVariableCreator(nil)
+
+// GitHubRepoVariableCreator creates a repository variable.
+type GitHubRepoVariableCreator func(ctx context.Context, owner, repo string, variable *github.ActionsVariable) error
+
+func (f GitHubRepoVariableCreator) CreateGitHubRepoVariable(ctx context.Context, owner, repo string, variable *github.ActionsVariable) error {
+	return f(ctx, owner, repo, variable)
+}
+
+var _ services.GitHubRepoVariableUpdater = GitHubRepoVariableUpdater(nil)
+
+// GitHubRepoVariableUpdater updates a repository variable.
+type GitHubRepoVariableUpdater func(ctx context.Context, owner, repo string, variable *github.ActionsVariable) error
+
+func (f GitHubRepoVariableUpdater) UpdateGitHubRepoVariable(ctx context.Context, owner, repo string, variable *github.ActionsVariable) error {
+	return f(ctx, owner, repo, variable)
+}
+
+var _ services.GitHubEnvVariableGetter = GitHubEnvVariableGetter(nil)
+
+// GitHubEnvVariableGetter gets a single environment variable.
+type GitHubEnvVariableGetter func(ctx context.Context, repoID int, env, name string) (*github.ActionsVariable, error)
+
+func (f GitHubEnvVariableGetter) GetGitHubEnvVariable(ctx context.Context, repoID int, env, name string) (*github.ActionsVariable, error) {
+	return f(ctx, repoID, env, name)
+}
+
+var _ services.GitHubEnvVariableCreator = GitHubEnvVariableCreator(nil)
+
+// GitHubEnvVariableCreator creates an environment variable.
+type GitHubEnvVariableCreator func(ctx context.Context, repoID int, env string, variable *github.ActionsVariable) error
+
+func (f GitHubEnvVariableCreator) CreateGitHubEnvVariable(ctx context.Context, repoID int, env string, variable *github.ActionsVariable) error {
+	return f(ctx, repoID, env, variable)
+}
+
+var _ services.GitHubEnvVariableUpdater = GitHubEnvVariableUpdater(nil)
+
+// GitHubEnvVariableUpdater updates an environment variable.
+type GitHubEnvVariableUpdater func(ctx context.Context, repoID int, env string, variable *github.ActionsVariable) error
+
+func (f GitHubEnvVariableUpdater) UpdateGitHubEnvVariable(ctx context.Context, repoID int, env string, variable *github.ActionsVariable) error {
+	return f(ctx, repoID, env, variable)
+}
+
+var _ services.GitHubOrgVariableGetter = GitHubOrgVariableGetter(nil)
+
+// GitHubOrgVariableGetter gets a single organization variable.
+type GitHubOrgVariableGetter func(ctx context.Context, org, name string) (*github.ActionsVariable, error)
+
+func (f GitHubOrgVariableGetter) GetGitHubOrgVariable(ctx context.Context, org, name string) (*github.ActionsVariable, error) {
+	return f(ctx, org, name)
+}
+
+var _ services.GitHubOrgVariableCreator = GitHubOrgVariableCreator(nil)
+
+// GitHubOrgVariableCreator creates an organization variable.
+type GitHubOrgVariableCreator func(ctx context.Context, org string, variable *github.ActionsVariable) error
+
+func (f GitHubOrgVariableCreator) CreateGitHubOrgVariable(ctx context.Context, org string, variable *github.ActionsVariable) error {
+	return f(ctx, org, variable)
+}
+
+var _ services.GitHubOrgVariableUpdater = GitHubOrgVariableUpdater(nil)
+
+// GitHubOrgVariableUpdater updates an organization variable.
+type GitHubOrgVariableUpdater func(ctx context.Context, org string, variable *github.ActionsVariable) error
 
-func (f GitHubOrgPublicKeyGetter) GetGitHubOrgPublicKey(ctx context.Context, org string) (*github.PublicKey, error) {
-	return f(ctx, org)
+func (f GitHubOrgVariableUpdater) UpdateGitHubOrgVariable(ctx context.Context, org string, variable *github.ActionsVariable) error {
+	return f(ctx, org, variable)
 }
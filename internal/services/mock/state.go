@@ -0,0 +1,43 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/shogo82148/op-sync/internal/services"
+)
+
+var _ services.StateGetter = StateGetter(nil)
+
+// StateGetter gets the state of the secret.
+type StateGetter func(ctx context.Context, key string) (*services.SecretState, error)
+
+func (f StateGetter) GetState(ctx context.Context, key string) (*services.SecretState, error) {
+	return f(ctx, key)
+}
+
+var _ services.StatePutter = StatePutter(nil)
+
+// StatePutter records the state of the secret.
+type StatePutter func(ctx context.Context, key string, state *services.SecretState) error
+
+func (f StatePutter) PutState(ctx context.Context, key string, state *services.SecretState) error {
+	return f(ctx, key, state)
+}
+
+var _ services.StateDeleter = StateDeleter(nil)
+
+// StateDeleter deletes the state of the secret.
+type StateDeleter func(ctx context.Context, key string) error
+
+func (f StateDeleter) DeleteState(ctx context.Context, key string) error {
+	return f(ctx, key)
+}
+
+var _ services.SecretHasher = SecretHasher(nil)
+
+// SecretHasher computes the keyed hash of the secret.
+type SecretHasher func(ctx context.Context, secret []byte) (string, error)
+
+func (f SecretHasher) HashSecret(ctx context.Context, secret []byte) (string, error) {
+	return f(ctx, secret)
+}
@@ -2,9 +2,24 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
+type onePasswordAccountKey struct{}
+
+// WithOnePasswordAccount returns a copy of ctx that specifies the 1password account to use.
+func WithOnePasswordAccount(ctx context.Context, account string) context.Context {
+	return context.WithValue(ctx, onePasswordAccountKey{}, account)
+}
+
+// OnePasswordAccount returns the 1password account specified by [WithOnePasswordAccount].
+// It returns an empty string if no account is specified.
+func OnePasswordAccount(ctx context.Context) string {
+	account, _ := ctx.Value(onePasswordAccountKey{}).(string)
+	return account
+}
+
 type OnePasswordUser struct {
 	URL         string `json:"url"`
 	Email       string `json:"email"`
@@ -36,6 +51,31 @@ type OnePasswordItem struct {
 	CreatedAt             time.Time `json:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at"`
 	AdditionalInformation string    `json:"additional_information"`
+
+	// Fields are the fields of the item without their values.
+	// Read the values by [OnePasswordField.Reference].
+	Fields []*OnePasswordField `json:"fields"`
+}
+
+// OnePasswordSection is a section of the item.
+type OnePasswordSection struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// OnePasswordField is a field of the item.
+type OnePasswordField struct {
+	ID      string              `json:"id"`
+	Type    string              `json:"type"`
+	Purpose string              `json:"purpose"`
+	Label   string              `json:"label"`
+	Section *OnePasswordSection `json:"section"`
+}
+
+// Reference returns the secret reference of the field in the item.
+// The field is specified by its ID, because the labels may be duplicated.
+func (f *OnePasswordField) Reference(vault, item string) string {
+	return fmt.Sprintf("op://%s/%s/%s", vault, item, f.ID)
 }
 
 // OnePasswordItemGetter gets the item from 1password.
@@ -47,3 +87,11 @@ type OnePasswordItemGetter interface {
 type OnePasswordReader interface {
 	ReadOnePassword(ctx context.Context, uri string) ([]byte, error)
 }
+
+// OnePassword is the interface that groups the operations of 1password.
+type OnePassword interface {
+	WhoAmIer
+	Injector
+	OnePasswordItemGetter
+	OnePasswordReader
+}
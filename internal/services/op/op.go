@@ -78,13 +78,26 @@ func ParseURI(uri string) (*URI, error) {
 	}, nil
 }
 
-func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+// ServiceAccountTokenEnv is the name of the environment variable
+// that 1Password CLI reads the token of the service account from.
+const ServiceAccountTokenEnv = "OP_SERVICE_ACCOUNT_TOKEN"
+
+// command returns the command of 1Password CLI.
+// It specifies the account from the context, and the token of the service account.
+func (s *Service) command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	if account := services.OnePasswordAccount(ctx); account != "" {
+		args = append([]string{"--account", account}, args...)
+	}
+
 	slog.DebugContext(ctx, "run 1password cli", slog.String("name", name), slog.Any("args", args))
 	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Cancel = func() error {
 		return cmd.Process.Signal(os.Interrupt)
 	}
 	cmd.WaitDelay = 10 * time.Second
+	if s.serviceAccountToken != "" {
+		cmd.Env = append(os.Environ(), ServiceAccountTokenEnv+"="+s.serviceAccountToken)
+	}
 	return cmd
 }
 
@@ -96,18 +109,30 @@ func wrap(err error) error {
 	return fmt.Errorf("failed to run op command: %w", err)
 }
 
+// Service is the 1Password service backed by 1Password CLI.
+// It is safe for concurrent use.
 type Service struct {
+	serviceAccountToken string
 }
 
-func NewService() *Service {
-	return &Service{}
+// Options is the options of [Service].
+type Options struct {
+	// ServiceAccountToken is the token of the 1Password service account.
+	// If it is empty, the account signed in to 1Password CLI is used.
+	ServiceAccountToken string
+}
+
+func NewService(opts *Options) *Service {
+	return &Service{
+		serviceAccountToken: opts.ServiceAccountToken,
+	}
 }
 
 var _ services.WhoAmIer = (*Service)(nil)
 
 // WhoAmI returns the information about a signed-in account.
 func (s *Service) WhoAmI(ctx context.Context) (*services.OnePasswordUser, error) {
-	cmd := command(ctx, "op", "whoami", "--format=json")
+	cmd := s.command(ctx, "op", "whoami", "--format=json")
 	data, err := cmd.Output()
 	if err != nil {
 		return nil, wrap(err)
@@ -123,7 +148,7 @@ var _ services.Injector = (*Service)(nil)
 
 // Injector inject the secrets into the template.
 func (s *Service) Inject(ctx context.Context, tmpl string) ([]byte, error) {
-	cmd := command(ctx, "op", "inject")
+	cmd := s.command(ctx, "op", "inject")
 	cmd.Stdin = strings.NewReader(tmpl)
 	data, err := cmd.Output()
 	if err != nil {
@@ -134,7 +159,7 @@ func (s *Service) Inject(ctx context.Context, tmpl string) ([]byte, error) {
 
 // GetOnePasswordItem gets the item from 1password.
 func (s *Service) GetOnePasswordItem(ctx context.Context, vault, item string) (*services.OnePasswordItem, error) {
-	cmd := command(ctx, "op", "item", "get", item, "--vault", vault, "--format=json")
+	cmd := s.command(ctx, "op", "item", "get", item, "--vault", vault, "--format=json")
 	data, err := cmd.Output()
 	if err != nil {
 		return nil, wrap(err)
@@ -148,7 +173,7 @@ func (s *Service) GetOnePasswordItem(ctx context.Context, vault, item string) (*
 
 // ReadOnePassword reads the secret from 1password.
 func (s *Service) ReadOnePassword(ctx context.Context, uri string) ([]byte, error) {
-	cmd := command(ctx, "op", "read", "--no-newline", uri)
+	cmd := s.command(ctx, "op", "read", "--no-newline", uri)
 	data, err := cmd.Output()
 	if err != nil {
 		return nil, wrap(err)
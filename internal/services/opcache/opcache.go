@@ -0,0 +1,220 @@
+// Package opcache provides the per-run cache of 1Password.
+package opcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/shogo82148/op-sync/internal/services"
+)
+
+var _ services.OnePassword = (*Cache)(nil)
+
+// Cache caches the results of 1Password operations during a run.
+// The same reference is read at most once even if it is requested concurrently.
+// It is safe for concurrent use.
+type Cache struct {
+	op services.OnePassword
+
+	mu       sync.Mutex
+	whoami   map[string]*entry[*services.OnePasswordUser]
+	items    map[itemKey]*entry[*services.OnePasswordItem]
+	reads    map[refKey]*entry[[]byte]
+	injected map[refKey]*entry[[]byte]
+}
+
+// itemKey is the key of the cache of items.
+type itemKey struct {
+	account string
+	vault   string
+	item    string
+}
+
+// refKey is the key of the cache of secret references and templates.
+type refKey struct {
+	account string
+	ref     string
+}
+
+// entry is a cached result.
+// done is closed when value and err are available.
+type entry[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// New returns a new cache wrapping op.
+func New(op services.OnePassword) *Cache {
+	return &Cache{
+		op:       op,
+		whoami:   make(map[string]*entry[*services.OnePasswordUser]),
+		items:    make(map[itemKey]*entry[*services.OnePasswordItem]),
+		reads:    make(map[refKey]*entry[[]byte]),
+		injected: make(map[refKey]*entry[[]byte]),
+	}
+}
+
+// load returns the cached value of key, or calls fetch if it is not cached yet.
+// The failed results are not cached so that they are retried later.
+//
+// fetch runs with a context detached from the cancellation of ctx,
+// because the other callers wait for the result even if the first caller gives up.
+func load[K comparable, T any](ctx context.Context, mu *sync.Mutex, m map[K]*entry[T], key K, fetch func(ctx context.Context) (T, error)) (T, error) {
+	mu.Lock()
+	e, ok := m[key]
+	if !ok {
+		e = &entry[T]{done: make(chan struct{})}
+		m[key] = e
+	}
+	mu.Unlock()
+
+	if !ok {
+		go func() {
+			defer close(e.done)
+			e.value, e.err = fetch(context.WithoutCancel(ctx))
+			if e.err != nil {
+				mu.Lock()
+				delete(m, key)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	select {
+	case <-e.done:
+		return e.value, e.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// WhoAmI returns the information about a signed-in account.
+func (c *Cache) WhoAmI(ctx context.Context) (*services.OnePasswordUser, error) {
+	return load(ctx, &c.mu, c.whoami, services.OnePasswordAccount(ctx), func(ctx context.Context) (*services.OnePasswordUser, error) {
+		return c.op.WhoAmI(ctx)
+	})
+}
+
+// Inject injects the secrets into the template.
+func (c *Cache) Inject(ctx context.Context, template string) ([]byte, error) {
+	key := refKey{account: services.OnePasswordAccount(ctx), ref: template}
+	data, err := load(ctx, &c.mu, c.injected, key, func(ctx context.Context) ([]byte, error) {
+		return c.op.Inject(ctx, template)
+	})
+	return bytes.Clone(data), err
+}
+
+// GetOnePasswordItem gets the item from 1password.
+func (c *Cache) GetOnePasswordItem(ctx context.Context, vault, item string) (*services.OnePasswordItem, error) {
+	key := itemKey{account: services.OnePasswordAccount(ctx), vault: vault, item: item}
+	return load(ctx, &c.mu, c.items, key, func(ctx context.Context) (*services.OnePasswordItem, error) {
+		return c.op.GetOnePasswordItem(ctx, vault, item)
+	})
+}
+
+// ReadOnePassword reads the secret from 1password.
+func (c *Cache) ReadOnePassword(ctx context.Context, uri string) ([]byte, error) {
+	key := refKey{account: services.OnePasswordAccount(ctx), ref: uri}
+	data, err := load(ctx, &c.mu, c.reads, key, func(ctx context.Context) ([]byte, error) {
+		return c.op.ReadOnePassword(ctx, uri)
+	})
+	return bytes.Clone(data), err
+}
+
+// Prefetch reads the secrets with a single "op inject" call, and caches them.
+// The secrets are read from the account specified by the context.
+// The references that are already cached are skipped.
+// If the batch fails, the secrets are read one by one on demand later.
+func (c *Cache) Prefetch(ctx context.Context, uris []string) error {
+	account := services.OnePasswordAccount(ctx)
+
+	// claim the references that are not cached yet.
+	c.mu.Lock()
+	claimed := make([]string, 0, len(uris))
+	entries := make([]*entry[[]byte], 0, len(uris))
+	for _, uri := range uris {
+		key := refKey{account: account, ref: uri}
+		if _, ok := c.reads[key]; ok {
+			continue
+		}
+		e := &entry[[]byte]{done: make(chan struct{})}
+		c.reads[key] = e
+		claimed = append(claimed, uri)
+		entries = append(entries, e)
+	}
+	c.mu.Unlock()
+	if len(claimed) == 0 {
+		return nil
+	}
+
+	// the other callers may wait for the claimed references.
+	values, err := c.inject(context.WithoutCancel(ctx), claimed)
+	if err != nil {
+		slog.DebugContext(ctx, "failed to prefetch the secrets", slog.String("error", err.Error()))
+	}
+
+	c.mu.Lock()
+	for i, e := range entries {
+		if err != nil {
+			// forget the claimed references, and read them on demand.
+			e.err = err
+			delete(c.reads, refKey{account: account, ref: claimed[i]})
+		} else {
+			e.value = values[i]
+		}
+		close(e.done)
+	}
+	c.mu.Unlock()
+	return err
+}
+
+// inject resolves the references with a single "op inject" call.
+func (c *Cache) inject(ctx context.Context, uris []string) ([][]byte, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return nil, err
+	}
+	boundary := "--op-sync-" + hex.EncodeToString(buf[:])
+
+	// build a template like:
+	//
+	//   --op-sync-xxx 0
+	//   {{ op://vault/item/field0 }}
+	//   --op-sync-xxx 1
+	//   {{ op://vault/item/field1 }}
+	//   --op-sync-xxx end
+	var tmpl strings.Builder
+	for i, uri := range uris {
+		fmt.Fprintf(&tmpl, "%s %d\n{{ %s }}\n", boundary, i, uri)
+	}
+	fmt.Fprintf(&tmpl, "%s end\n", boundary)
+
+	slog.DebugContext(ctx, "prefetch the secrets", slog.Int("count", len(uris)))
+	data, err := c.op.Inject(ctx, tmpl.String())
+	if err != nil {
+		return nil, err
+	}
+
+	parts := bytes.Split(data, []byte(boundary))
+	if len(parts) != len(uris)+2 {
+		return nil, fmt.Errorf("opcache: unexpected output of op inject: got %d parts, want %d", len(parts), len(uris)+2)
+	}
+	values := make([][]byte, 0, len(uris))
+	for i := range uris {
+		part := parts[i+1]
+		header := fmt.Appendf(nil, " %d\n", i)
+		if !bytes.HasPrefix(part, header) || !bytes.HasSuffix(part, []byte("\n")) {
+			return nil, fmt.Errorf("opcache: unexpected output of op inject at %d", i)
+		}
+		values = append(values, part[len(header):len(part)-1])
+	}
+	return values, nil
+}
@@ -0,0 +1,178 @@
+package opcache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/mock"
+)
+
+type onePassword struct {
+	mock.WhoAmIer
+	mock.Injector
+	mock.OnePasswordItemGetter
+	mock.OnePasswordReader
+}
+
+func TestReadOnePassword(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var count atomic.Int32
+	c := New(&onePassword{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			count.Add(1)
+			return []byte("secret of " + uri), nil
+		}),
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			got, err := c.ReadOnePassword(ctx, "op://vault/item/field")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if string(got) != "secret of op://vault/item/field" {
+				t.Errorf("unexpected secret: %q", got)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := count.Load(); got != 1 {
+		t.Errorf("unexpected count of reads: want 1, got %d", got)
+	}
+}
+
+func TestReadOnePassword_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var count atomic.Int32
+	c := New(&onePassword{
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			count.Add(1)
+			close(started)
+			<-release
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return []byte("secret of " + uri), nil
+		}),
+	})
+
+	// the first caller gives up while reading.
+	first, cancelFirst := context.WithCancel(ctx)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.ReadOnePassword(first, "op://vault/item/field")
+		errc <- err
+	}()
+	<-started
+	cancelFirst()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("unexpected error: want %v, got %v", context.Canceled, err)
+	}
+
+	// the other callers still get the secret.
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			got, err := c.ReadOnePassword(ctx, "op://vault/item/field")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if string(got) != "secret of op://vault/item/field" {
+				t.Errorf("unexpected secret: %q", got)
+			}
+		})
+	}
+	close(release)
+	wg.Wait()
+
+	if got := count.Load(); got != 1 {
+		t.Errorf("unexpected count of reads: want 1, got %d", got)
+	}
+}
+
+func TestGetOnePasswordItem(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var count atomic.Int32
+	c := New(&onePassword{
+		OnePasswordItemGetter: mock.OnePasswordItemGetter(func(ctx context.Context, vault, item string) (*services.OnePasswordItem, error) {
+			count.Add(1)
+			return &services.OnePasswordItem{Title: item}, nil
+		}),
+	})
+
+	for range 3 {
+		item, err := c.GetOnePasswordItem(ctx, "vault", "item")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.Title != "item" {
+			t.Errorf("unexpected title: want item, got %q", item.Title)
+		}
+	}
+	if got := count.Load(); got != 1 {
+		t.Errorf("unexpected count of item gets: want 1, got %d", got)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var injects atomic.Int32
+	c := New(&onePassword{
+		Injector: mock.Injector(func(ctx context.Context, template string) ([]byte, error) {
+			injects.Add(1)
+			// emulate op inject.
+			template = strings.ReplaceAll(template, "{{ op://vault/item/foo }}", "foo-secret")
+			template = strings.ReplaceAll(template, "{{ op://vault/item/bar }}", "bar\nsecret")
+			return []byte(template), nil
+		}),
+		OnePasswordReader: mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+			t.Errorf("unexpected read: %s", uri)
+			return nil, nil
+		}),
+	})
+
+	if err := c.Prefetch(ctx, []string{"op://vault/item/foo", "op://vault/item/bar"}); err != nil {
+		t.Fatal(err)
+	}
+
+	foo, err := c.ReadOnePassword(ctx, "op://vault/item/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(foo) != "foo-secret" {
+		t.Errorf("unexpected secret: want %q, got %q", "foo-secret", foo)
+	}
+	bar, err := c.ReadOnePassword(ctx, "op://vault/item/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bar) != "bar\nsecret" {
+		t.Errorf("unexpected secret: want %q, got %q", "bar\nsecret", bar)
+	}
+
+	// the cached references are skipped.
+	if err := c.Prefetch(ctx, []string{"op://vault/item/foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := injects.Load(); got != 1 {
+		t.Errorf("unexpected count of injects: want 1, got %d", got)
+	}
+}
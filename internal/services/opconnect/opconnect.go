@@ -0,0 +1,356 @@
+// Package opconnect provides the 1Password service backed by 1Password Connect server.
+package opconnect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/op"
+)
+
+// HostEnv is the name of the environment variable that has the URL of 1Password Connect server.
+const HostEnv = "OP_CONNECT_HOST"
+
+// TokenEnv is the name of the environment variable that has the token of 1Password Connect server.
+const TokenEnv = "OP_CONNECT_TOKEN"
+
+// Error is the error response from 1Password Connect server.
+type Error struct {
+	StatusCode int    `json:"status"`
+	Message    string `json:"message"`
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("opconnect: %d %s", err.StatusCode, err.Message)
+}
+
+type vault struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type section struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+type field struct {
+	ID      string   `json:"id"`
+	Section *section `json:"section"`
+	Type    string   `json:"type"`
+	Purpose string   `json:"purpose"`
+	Label   string   `json:"label"`
+	Value   string   `json:"value"`
+}
+
+type file struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Section     *section `json:"section"`
+	ContentPath string   `json:"content_path"`
+}
+
+type item struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Version int    `json:"version"`
+	Vault   struct {
+		ID string `json:"id"`
+	} `json:"vault"`
+	Category     string     `json:"category"`
+	LastEditedBy string     `json:"lastEditedBy"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	Sections     []*section `json:"sections"`
+	Fields       []*field   `json:"fields"`
+	Files        []*file    `json:"files"`
+}
+
+var _ services.OnePassword = (*Service)(nil)
+
+// Service is the 1Password service backed by 1Password Connect server.
+// It is safe for concurrent use.
+type Service struct {
+	host   string
+	token  string
+	client *http.Client
+}
+
+// NewService returns a new service for the Connect server at host.
+func NewService(host, token string) *Service {
+	return &Service{
+		host:   strings.TrimSuffix(host, "/"),
+		token:  token,
+		client: http.DefaultClient,
+	}
+}
+
+func (s *Service) get(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	u := s.host + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+
+	slog.DebugContext(ctx, "request to 1password connect", slog.String("path", path))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("opconnect: failed to request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("opconnect: failed to read the response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		e := &Error{}
+		if err := json.Unmarshal(data, e); err != nil || e.Message == "" {
+			e.Message = http.StatusText(resp.StatusCode)
+		}
+		e.StatusCode = resp.StatusCode
+		return nil, e
+	}
+	return data, nil
+}
+
+func (s *Service) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	data, err := s.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("opconnect: failed to parse the response: %w", err)
+	}
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// getVault gets the vault by its name or ID.
+func (s *Service) getVault(ctx context.Context, nameOrID string) (*vault, error) {
+	var vaults []*vault
+	query := url.Values{"filter": []string{fmt.Sprintf("name eq %q", nameOrID)}}
+	if err := s.getJSON(ctx, "/v1/vaults", query, &vaults); err != nil {
+		return nil, err
+	}
+	switch len(vaults) {
+	case 0:
+	case 1:
+		return vaults[0], nil
+	default:
+		return nil, fmt.Errorf("opconnect: more than one vault matches %q", nameOrID)
+	}
+
+	// fallback to the vault ID.
+	var v vault
+	err := s.getJSON(ctx, "/v1/vaults/"+url.PathEscape(nameOrID), nil, &v)
+	if isNotFound(err) {
+		return nil, fmt.Errorf("opconnect: vault %q is not found", nameOrID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// getItem gets the item by its title or ID.
+func (s *Service) getItem(ctx context.Context, vaultNameOrID, titleOrID string) (*vault, *item, error) {
+	v, err := s.getVault(ctx, vaultNameOrID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var items []*item
+	query := url.Values{"filter": []string{fmt.Sprintf("title eq %q", titleOrID)}}
+	if err := s.getJSON(ctx, "/v1/vaults/"+url.PathEscape(v.ID)+"/items", query, &items); err != nil {
+		return nil, nil, err
+	}
+	id := titleOrID
+	switch len(items) {
+	case 0:
+	case 1:
+		id = items[0].ID
+	default:
+		return nil, nil, fmt.Errorf("opconnect: more than one item matches %q", titleOrID)
+	}
+
+	var i item
+	err = s.getJSON(ctx, "/v1/vaults/"+url.PathEscape(v.ID)+"/items/"+url.PathEscape(id), nil, &i)
+	if isNotFound(err) {
+		return nil, nil, fmt.Errorf("opconnect: item %q is not found in vault %q", titleOrID, vaultNameOrID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, &i, nil
+}
+
+// WhoAmI returns the information about the Connect server.
+// Connect server has no user, so only the URL is available.
+func (s *Service) WhoAmI(ctx context.Context) (*services.OnePasswordUser, error) {
+	// check the token is valid.
+	var vaults []*vault
+	if err := s.getJSON(ctx, "/v1/vaults", nil, &vaults); err != nil {
+		return nil, err
+	}
+	return &services.OnePasswordUser{
+		URL: s.host,
+	}, nil
+}
+
+// GetOnePasswordItem gets the item from 1password.
+func (s *Service) GetOnePasswordItem(ctx context.Context, vault, item string) (*services.OnePasswordItem, error) {
+	v, i, err := s.getItem(ctx, vault, item)
+	if err != nil {
+		return nil, err
+	}
+	ret := &services.OnePasswordItem{
+		ID:           i.ID,
+		Title:        i.Title,
+		Version:      i.Version,
+		Category:     i.Category,
+		LastEditedBy: i.LastEditedBy,
+		CreatedAt:    i.CreatedAt,
+		UpdatedAt:    i.UpdatedAt,
+	}
+	ret.Vault.ID = v.ID
+	ret.Vault.Name = v.Name
+	for _, f := range i.Fields {
+		field := &services.OnePasswordField{
+			ID:      f.ID,
+			Type:    f.Type,
+			Purpose: f.Purpose,
+			Label:   f.Label,
+		}
+		if f.Section != nil {
+			field.Section = &services.OnePasswordSection{
+				ID:    f.Section.ID,
+				Label: sectionLabel(i, f.Section.ID),
+			}
+		}
+		ret.Fields = append(ret.Fields, field)
+	}
+	return ret, nil
+}
+
+// ReadOnePassword reads the secret from 1password.
+func (s *Service) ReadOnePassword(ctx context.Context, uri string) ([]byte, error) {
+	u, err := op.ParseURI(uri)
+	if err != nil {
+		return nil, fmt.Errorf("opconnect: failed to parse %q: %w", uri, err)
+	}
+	_, i, err := s.getItem(ctx, u.Vault, u.Item)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range i.Fields {
+		if !matchSection(i, f.Section, u.Section) || (f.ID != u.Field && f.Label != u.Field) {
+			continue
+		}
+		switch u.Attribute {
+		case "", "value":
+			return []byte(f.Value), nil
+		case "type":
+			return []byte(strings.ToLower(f.Type)), nil
+		case "id":
+			return []byte(f.ID), nil
+		case "label", "title":
+			return []byte(f.Label), nil
+		case "purpose":
+			return []byte(f.Purpose), nil
+		default:
+			return nil, fmt.Errorf("opconnect: unsupported attribute %q", u.Attribute)
+		}
+	}
+
+	for _, f := range i.Files {
+		if !matchSection(i, f.Section, u.Section) || (f.ID != u.Field && f.Name != u.Field) {
+			continue
+		}
+		if u.Attribute != "" && u.Attribute != "content" {
+			return nil, fmt.Errorf("opconnect: unsupported attribute %q", u.Attribute)
+		}
+		return s.get(ctx, f.ContentPath, nil)
+	}
+
+	return nil, fmt.Errorf("opconnect: field %q is not found in item %q", u.Field, u.Item)
+}
+
+// sectionLabel returns the label of the section.
+func sectionLabel(i *item, id string) string {
+	for _, s := range i.Sections {
+		if s.ID == id {
+			return s.Label
+		}
+	}
+	return ""
+}
+
+// matchSection reports whether the section s matches the name of the section.
+func matchSection(i *item, s *section, name string) bool {
+	if name == "" {
+		return true
+	}
+	if s == nil {
+		return false
+	}
+	if s.ID == name {
+		return true
+	}
+	for _, ss := range i.Sections {
+		if ss.ID == s.ID && ss.Label == name {
+			return true
+		}
+	}
+	return false
+}
+
+// reference matches the secret reference in templates, e.g. {{ op://vault/item/field }}.
+// The reference may contain spaces, e.g. {{ op://Private/My Item/password }}.
+var reference = regexp.MustCompile(`\{\{\s*(op://[^}]*?)\s*\}\}`)
+
+// opening matches the beginning of the secret references.
+var opening = regexp.MustCompile(`\{\{\s*op://`)
+
+// Inject injects the secrets into the template.
+// Connect server has no API for injection, so it is done on the client side.
+func (s *Service) Inject(ctx context.Context, tmpl string) ([]byte, error) {
+	// the references that don't match would be left in the output as they are.
+	if got, want := len(reference.FindAllStringIndex(tmpl, -1)), len(opening.FindAllStringIndex(tmpl, -1)); got != want {
+		return nil, errors.New("opconnect: the template has malformed secret references")
+	}
+
+	var errs []error
+	ret := reference.ReplaceAllStringFunc(tmpl, func(m string) string {
+		uri := reference.FindStringSubmatch(m)[1]
+		secret, err := s.ReadOnePassword(ctx, uri)
+		if err != nil {
+			errs = append(errs, err)
+			return m
+		}
+		return string(secret)
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return []byte(ret), nil
+}
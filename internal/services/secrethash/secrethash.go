@@ -0,0 +1,67 @@
+// Package secrethash computes the keyed hashes of secrets with the key stored in 1Password.
+package secrethash
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/shogo82148/op-sync/internal/services"
+)
+
+var _ services.SecretHasher = (*Service)(nil)
+
+// Service computes HMAC-SHA256 of secrets.
+// It is safe for concurrent use.
+type Service struct {
+	op      services.OnePasswordReader
+	ref     string
+	account string
+
+	mu  sync.Mutex
+	key []byte
+}
+
+// New returns a new hasher that reads the key from the secret reference ref of the 1Password account.
+func New(op services.OnePasswordReader, ref, account string) *Service {
+	return &Service{
+		op:      op,
+		ref:     ref,
+		account: account,
+	}
+}
+
+// getKey reads the key from 1Password at the first call.
+func (s *Service) getKey(ctx context.Context) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key != nil {
+		return s.key, nil
+	}
+
+	// the key always lives in the account of the state, not the one of the secret.
+	ctx = services.WithOnePasswordAccount(ctx, s.account)
+	key, err := s.op.ReadOnePassword(ctx, s.ref)
+	if err != nil {
+		return nil, fmt.Errorf("secrethash: failed to read the key: %w", err)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("secrethash: the key %q is empty", s.ref)
+	}
+	s.key = key
+	return key, nil
+}
+
+// HashSecret computes HMAC-SHA256 of the secret, and returns it encoded in hex.
+func (s *Service) HashSecret(ctx context.Context, secret []byte) (string, error) {
+	key, err := s.getKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(secret)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,42 @@
+package secrethash
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shogo82148/op-sync/internal/services"
+	"github.com/shogo82148/op-sync/internal/services/mock"
+)
+
+func TestHashSecret(t *testing.T) {
+	ctx := context.Background()
+
+	var reads int
+	s := New(mock.OnePasswordReader(func(ctx context.Context, uri string) ([]byte, error) {
+		reads++
+		if uri != "op://vault/item/key" {
+			t.Errorf("unexpected uri: %q", uri)
+		}
+		if account := services.OnePasswordAccount(ctx); account != "my.1password.com" {
+			t.Errorf("unexpected account: %q", account)
+		}
+		return []byte("key"), nil
+	}), "op://vault/item/key", "my.1password.com")
+
+	got, err := s.HashSecret(ctx, []byte("The quick brown fox jumps over the lazy dog"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// HMAC_SHA256("key", "The quick brown fox jumps over the lazy dog")
+	want := "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got != want {
+		t.Errorf("unexpected hash: want %s, got %s", want, got)
+	}
+
+	if _, err := s.HashSecret(ctx, []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+	if reads != 1 {
+		t.Errorf("the key is read %d times, want 1", reads)
+	}
+}
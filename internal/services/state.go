@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+// SecretState is the state of a secret that op-sync synced.
+type SecretState struct {
+	// HMAC is the keyed hash of the plaintext of the secret, encoded in hex.
+	HMAC string `json:"hmac"`
+
+	// UpdatedAt is the time when the target was updated by op-sync.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StateGetter gets the state of the secret.
+// It returns nil without error if the state is not recorded.
+type StateGetter interface {
+	GetState(ctx context.Context, key string) (*SecretState, error)
+}
+
+// StatePutter records the state of the secret.
+type StatePutter interface {
+	PutState(ctx context.Context, key string, state *SecretState) error
+}
+
+// StateDeleter deletes the state of the secret.
+// The keys are compared case-insensitively, because the names on GitHub are case-insensitive.
+// It returns nil if the state is not recorded.
+type StateDeleter interface {
+	DeleteState(ctx context.Context, key string) error
+}
+
+// SecretHasher computes the keyed hash of the secret.
+type SecretHasher interface {
+	HashSecret(ctx context.Context, secret []byte) (string, error)
+}
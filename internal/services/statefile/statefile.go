@@ -0,0 +1,136 @@
+// Package statefile provides the state store backed by a local JSON file.
+package statefile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/shogo82148/op-sync/internal/services"
+)
+
+var _ services.StateGetter = (*Service)(nil)
+var _ services.StatePutter = (*Service)(nil)
+var _ services.StateDeleter = (*Service)(nil)
+
+// file is the content of the state file.
+type file struct {
+	Secrets map[string]*services.SecretState `json:"secrets"`
+}
+
+// Service is the state store backed by a local JSON file.
+// It is safe for concurrent use.
+type Service struct {
+	path string
+
+	mu      sync.Mutex
+	secrets map[string]*services.SecretState
+}
+
+// New returns a new state store that reads and writes path.
+func New(path string) *Service {
+	return &Service{path: path}
+}
+
+// load reads the state file if it is not loaded yet.
+// The caller must hold s.mu.
+func (s *Service) load() error {
+	if s.secrets != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.secrets = map[string]*services.SecretState{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("statefile: failed to read %q: %w", s.path, err)
+	}
+
+	var v file
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("statefile: failed to parse %q: %w", s.path, err)
+	}
+	if v.Secrets == nil {
+		v.Secrets = map[string]*services.SecretState{}
+	}
+	s.secrets = v.Secrets
+	return nil
+}
+
+// GetState gets the state of the secret.
+func (s *Service) GetState(ctx context.Context, key string) (*services.SecretState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	state, ok := s.secrets[key]
+	if !ok {
+		return nil, nil
+	}
+	ret := *state
+	return &ret, nil
+}
+
+// PutState records the state of the secret, and writes the state file.
+func (s *Service) PutState(ctx context.Context, key string, state *services.SecretState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	v := *state
+	s.secrets[key] = &v
+
+	slog.DebugContext(ctx, "write the state", slog.String("path", s.path), slog.String("key", key))
+	return s.save()
+}
+
+// DeleteState deletes the state of the secret, and writes the state file.
+// The keys are compared case-insensitively.
+func (s *Service) DeleteState(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	n := len(s.secrets)
+	maps.DeleteFunc(s.secrets, func(k string, _ *services.SecretState) bool {
+		return strings.EqualFold(k, key)
+	})
+	if len(s.secrets) == n {
+		return nil
+	}
+
+	slog.DebugContext(ctx, "delete the state", slog.String("path", s.path), slog.String("key", key))
+	return s.save()
+}
+
+// save writes the state file atomically.
+// The caller must hold s.mu.
+func (s *Service) save() error {
+	data, err := json.MarshalIndent(file{Secrets: s.secrets}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("statefile: failed to marshal the state: %w", err)
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", s.path, os.Getpid())
+	defer os.Remove(tmp)
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("statefile: failed to write %q: %w", s.path, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("statefile: failed to write %q: %w", s.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package statefile
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shogo82148/op-sync/internal/services"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	// the state file doesn't exist yet.
+	s := New(path)
+	state, err := s.GetState(ctx, "repos/shogo82148/op-sync/actions/secrets/FOO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != nil {
+		t.Errorf("unexpected state: %v", state)
+	}
+
+	want := &services.SecretState{
+		HMAC:      "0123456789abcdef",
+		UpdatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := s.PutState(ctx, "repos/shogo82148/op-sync/actions/secrets/FOO", want); err != nil {
+		t.Fatal(err)
+	}
+
+	// read the state from the file.
+	s = New(path)
+	got, err := s.GetState(ctx, "repos/shogo82148/op-sync/actions/secrets/FOO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected state (-want +got):\n%s", diff)
+	}
+
+	// the keys are case-insensitive in deletion.
+	if err := s.DeleteState(ctx, "repos/Shogo82148/OP-SYNC/actions/secrets/foo"); err != nil {
+		t.Fatal(err)
+	}
+	s = New(path)
+	state, err = s.GetState(ctx, "repos/shogo82148/op-sync/actions/secrets/FOO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != nil {
+		t.Errorf("unexpected state: %v", state)
+	}
+
+	// deleting the unknown state is not an error.
+	if err := s.DeleteState(ctx, "repos/shogo82148/op-sync/actions/secrets/BAR"); err != nil {
+		t.Fatal(err)
+	}
+}